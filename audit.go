@@ -0,0 +1,113 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces secret values in a redacted audit
+const redactedValue = "******"
+
+// sensitiveEnvWords mark env var names whose values are redacted
+var sensitiveEnvWords = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL"}
+
+// Placeholder describes one placeholder found in the config
+type Placeholder struct {
+	// Path is the dot-path of the value holding the placeholder
+	Path string
+	// Expr is the placeholder as written, e.g. ${PORT:8080}
+	Expr string
+	// Resolver is "env" for env placeholders, or the scheme name
+	Resolver string
+	// Name is the env var read, with its $envPrefix applied, or the key
+	// passed to the resolver
+	Name string
+	// Default is the fallback text, valid when HasDefault is set
+	Default    string
+	HasDefault bool
+	// Set reports whether the env var is set and non-empty. It is always
+	// false for other resolvers.
+	Set bool
+	// Value is the resolved value, or "******" for secrets when
+	// redaction was requested
+	Value string
+	// Redacted reports whether Value was hidden
+	Redacted bool
+	// Err is the error resolving the placeholder, if any
+	Err error
+}
+
+// Placeholders resolves every placeholder in the config and describes
+// each one, sorted by path, for printing at startup. With redact set, the
+// values of secret resolvers such as vault or file are hidden, as are
+// values whose env var or key contains a word like PASSWORD, SECRET, TOKEN
+// or KEY.
+func (p *YamlProfile) Placeholders(redact bool) []Placeholder {
+	var found []Placeholder
+	p.walkPlaceholders(func(path, expr, prefix string) {
+		found = append(found, p.describePlaceholder(path, expr, prefix, redact))
+	})
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Path < found[j].Path
+	})
+	return found
+}
+
+// describePlaceholder resolves the placeholder expr at path, relative to
+// p, and fills in a Placeholder for it
+func (p *YamlProfile) describePlaceholder(path, expr, prefix string, redact bool) Placeholder {
+	ph := Placeholder{Path: path, Expr: expr}
+	base, _ := p.splitFilters(expr)
+	name, rest, hasRest := parsePlaceholder(base)
+	if sch, ok := p.scheme(name); ok && (hasRest || sch.bare) {
+		ph.Resolver = name
+		ph.Name, ph.Default, ph.HasDefault = sch.split(rest, hasRest)
+	} else {
+		ph.Resolver = "env"
+		ph.Name, ph.Default, ph.HasDefault = prefix+name, rest, hasRest
+		val, _ := p.lookupEnv(ph.Name)
+		ph.Set = val != ""
+	}
+
+	ph.Value, ph.Err = p.resolveValue(context.Background(), joinPath(p.base, path), expr)
+	if redact && ph.Value != "" && isSecretPlaceholder(ph) {
+		ph.Value, ph.Redacted = redactedValue, true
+	}
+	return ph
+}
+
+// isSecretPlaceholder reports whether a placeholder's value should be
+// hidden in redacted output
+func isSecretPlaceholder(ph Placeholder) bool {
+	if secretSchemes[ph.Resolver] {
+		return true
+	}
+	upper := strings.ToUpper(ph.Name)
+	for _, word := range sensitiveEnvWords {
+		if strings.Contains(upper, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unresolved returns the sorted paths of placeholders that resolve to an
+// empty string because their variable is unset and they have no default,
+// so CI can fail a deployment with missing environment before it starts.
+// WithStrict turns the same placeholders into ErrUnresolved errors.
+func (p *YamlProfile) Unresolved() []string {
+	strict := *p
+	strict.strict = true
+
+	var paths []string
+	p.walkPlaceholders(func(path, expr, _ string) {
+		_, err := strict.resolveValue(context.Background(), joinPath(p.base, path), expr)
+		if errors.Is(err, ErrUnresolved) {
+			paths = append(paths, path)
+		}
+	})
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,86 @@
+package dollarYaml
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestYamlProfile_Placeholders(t *testing.T) {
+	t.Setenv("AUDIT_PORT", "9090")
+	t.Setenv("AUDIT_DB_PASSWORD", "hunter2")
+	t.Setenv("WORKER_THREADS", "8")
+
+	yamlData := []byte(`
+server:
+  port: ${AUDIT_PORT:8080}
+  host: ${AUDIT_HOST:localhost|upper}
+  plain: no placeholder here
+db:
+  password: ${AUDIT_DB_PASSWORD}
+  cert: ${file:/nonexistent/cert.pem:none}
+  token: ${vault:secret/data/app#token}
+worker:
+  $envPrefix: WORKER_
+  threads: ${THREADS}
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	got := p.Placeholders(true)
+	want := []Placeholder{
+		{Path: "db.cert", Expr: "${file:/nonexistent/cert.pem:none}", Resolver: "file", Name: "/nonexistent/cert.pem", Default: "none", HasDefault: true, Value: redactedValue, Redacted: true},
+		{Path: "db.password", Expr: "${AUDIT_DB_PASSWORD}", Resolver: "env", Name: "AUDIT_DB_PASSWORD", Set: true, Value: redactedValue, Redacted: true},
+		{Path: "db.token", Expr: "${vault:secret/data/app#token}", Resolver: "vault", Name: "secret/data/app#token"},
+		{Path: "server.host", Expr: "${AUDIT_HOST:localhost|upper}", Resolver: "env", Name: "AUDIT_HOST", Default: "localhost", HasDefault: true, Value: "LOCALHOST"},
+		{Path: "server.port", Expr: "${AUDIT_PORT:8080}", Resolver: "env", Name: "AUDIT_PORT", Default: "8080", HasDefault: true, Set: true, Value: "9090"},
+		{Path: "worker.threads", Expr: "${THREADS}", Resolver: "env", Name: "WORKER_THREADS", Set: true, Value: "8"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d placeholders, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if want[i].Resolver == "vault" {
+			if !errors.Is(got[i].Err, ErrResolverNotConfigured) {
+				t.Errorf("%s: expected ErrResolverNotConfigured but got %v", got[i].Path, got[i].Err)
+			}
+			got[i].Err = nil
+		}
+		if got[i] != want[i] {
+			t.Errorf("got %+v\nwant %+v", got[i], want[i])
+		}
+	}
+
+	unredacted := p.Placeholders(false)
+	assert(t, unredacted[1].Value, "hunter2", "unredacted password")
+	assert(t, unredacted[1].Redacted, false, "unredacted flag")
+}
+
+func TestYamlProfile_Unresolved(t *testing.T) {
+	t.Setenv("UNRES_SET", "yes")
+
+	yamlData := []byte(`
+set: ${UNRES_SET}
+defaulted: ${UNRES_MISSING:fallback}
+missing: ${UNRES_MISSING}
+nested: ${UNRES_MISSING:${UNRES_OTHER}}
+optional: ${UNRES_MISSING:+suffix}
+list:
+  - ${UNRES_ITEM}
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	got := p.Unresolved()
+	want := []string{"list.0", "missing", "nested"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		assert(t, got[i], want[i], "unresolved path")
+	}
+	assert(t, p.Get("missing"), "", "lenient lookups are unchanged")
+}
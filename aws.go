@@ -0,0 +1,542 @@
+package dollarYaml
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSCredentials are the keys used to sign requests to AWS
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expires is when temporary credentials stop working, zero if never
+	Expires time.Time
+}
+
+// AWSConfig configures the ${aws-sm:...} and ${aws-ssm:...} resolvers
+type AWSConfig struct {
+	// Region used for requests; defaults to AWS_REGION or
+	// AWS_DEFAULT_REGION. Secrets addressed by ARN use the ARN's region.
+	Region string
+	// Credentials overrides the default credential chain, e.g. to plug in
+	// credentials obtained from the AWS SDK
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+	// Endpoint overrides the service URL, e.g. for LocalStack
+	Endpoint string
+	// Timeout bounds each request to AWS; defaults to 10 seconds
+	Timeout time.Duration
+	// CacheTTL is how long a fetched value is reused; defaults to 5
+	// minutes, and a negative value disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the client used to reach AWS
+	HTTPClient *http.Client
+}
+
+// awsResolver fetches values from Secrets Manager and SSM Parameter Store
+type awsResolver struct {
+	cfg   AWSConfig
+	cache *ttlCache
+
+	mu    sync.Mutex
+	creds AWSCredentials
+}
+
+func newAWSResolver(cfg AWSConfig) *awsResolver {
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	r := &awsResolver{cfg: cfg, cache: newTTLCache(cfg.CacheTTL)}
+	if r.cfg.Credentials == nil {
+		r.cfg.Credentials = r.defaultCredentials
+	}
+	return r
+}
+
+// splitAWSSecretKey separates the default from an aws-sm key, skipping
+// the colons inside an ARN
+func splitAWSSecretKey(rest string) (key, defaultValue string, hasDefault bool) {
+	skip := 0
+	if strings.HasPrefix(rest, "arn:") {
+		// arn:partition:service:region:account:secret:name
+		skip = 6
+	}
+	idx := 0
+	for i := 0; i <= skip; i++ {
+		next := strings.Index(rest[idx:], ":")
+		if next == -1 {
+			return rest, "", false
+		}
+		idx += next + 1
+	}
+	return rest[:idx-1], rest[idx:], true
+}
+
+// resolveSecret returns a Secrets Manager secret from a key of the form
+// name[#jsonKey], where jsonKey selects a field of a JSON secret
+func (r *awsResolver) resolveSecret(ctx context.Context, key string) (string, error) {
+	name, jsonKey, _ := strings.Cut(key, "#")
+	region := r.cfg.Region
+	if parts := strings.Split(name, ":"); len(parts) >= 7 && parts[0] == "arn" {
+		region = parts[3]
+	}
+
+	secret, err := r.cache.get(ctx, "sm:"+region+":"+name, func() (interface{}, error) {
+		var out struct {
+			SecretString string
+		}
+		err := r.call(ctx, "secretsmanager", region, "secretsmanager.GetSecretValue",
+			map[string]string{"SecretId": name}, &out)
+		return out.SecretString, err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	value := secret.(string)
+	if jsonKey == "" {
+		return value, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("parsing secret %s as JSON: %w", name, err)
+	}
+	field, ok := fields[jsonKey]
+	if !ok || field == nil {
+		return "", fmt.Errorf("%w: key %s in secret %s", ErrValueNotFound, jsonKey, name)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(field)
+	return string(encoded), err
+}
+
+// resolveParameter returns an SSM parameter, decrypting SecureStrings
+func (r *awsResolver) resolveParameter(ctx context.Context, name string) (string, error) {
+	param, err := r.cache.get(ctx, "ssm:"+r.cfg.Region+":"+name, func() (interface{}, error) {
+		var out struct {
+			Parameter struct {
+				Value string
+			}
+		}
+		err := r.call(ctx, "ssm", r.cfg.Region, "AmazonSSM.GetParameter",
+			map[string]interface{}{"Name": name, "WithDecryption": true}, &out)
+		return out.Parameter.Value, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return param.(string), nil
+}
+
+// credentials returns cached credentials, refreshing them before expiry
+func (r *awsResolver) credentials(ctx context.Context) (AWSCredentials, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.creds.AccessKeyID == "" || (!r.creds.Expires.IsZero() && time.Until(r.creds.Expires) < time.Minute) {
+		creds, err := r.cfg.Credentials(ctx)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("aws credentials: %w", err)
+		}
+		r.creds = creds
+	}
+	return r.creds, nil
+}
+
+// call invokes an AWS JSON 1.1 API action
+func (r *awsResolver) call(ctx context.Context, service, region, target string, input, out interface{}) error {
+	if region == "" {
+		return errors.New("aws: region not set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	creds, err := r.credentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	endpoint := r.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", service, region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	signAWSRequest(req, payload, creds, service, region, time.Now())
+
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &apiErr)
+		errType := apiErr.Type[strings.LastIndex(apiErr.Type, "#")+1:]
+		if errType == "ResourceNotFoundException" || errType == "ParameterNotFound" {
+			return fmt.Errorf("%w: %s %s", ErrValueNotFound, service, errType)
+		}
+		return fmt.Errorf("aws %s %s: %s %s", service, resp.Status, errType, apiErr.Message)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// signAWSRequest adds a Signature Version 4 Authorization header to req
+func signAWSRequest(req *http.Request, payload []byte, creds AWSCredentials, service, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by key as SigV4 requires
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string{}, values[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// defaultCredentials follows the usual AWS chain: env vars, web identity
+// (EKS IRSA), the shared credentials file, ECS container credentials and
+// finally the EC2 instance metadata service
+func (r *awsResolver) defaultCredentials(ctx context.Context) (AWSCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return AWSCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if tokenFile, role := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"); tokenFile != "" && role != "" {
+		return r.webIdentityCredentials(ctx, tokenFile, role)
+	}
+	if creds, ok := sharedFileCredentials(); ok {
+		return creds, nil
+	}
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		return r.containerCredentials(ctx, "http://169.254.170.2"+uri)
+	}
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); uri != "" {
+		return r.containerCredentials(ctx, uri)
+	}
+	return r.instanceCredentials(ctx)
+}
+
+// sharedFileCredentials reads the active profile from ~/.aws/credentials
+func sharedFileCredentials() (AWSCredentials, bool) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return AWSCredentials{}, false
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return AWSCredentials{}, false
+	}
+	defer f.Close()
+
+	var creds AWSCredentials
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != profile {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	return creds, creds.AccessKeyID != "" && creds.SecretAccessKey != ""
+}
+
+// webIdentityCredentials exchanges a web identity token for role credentials
+func (r *awsResolver) webIdentityCredentials(ctx context.Context, tokenFile, role string) (AWSCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {role},
+		"RoleSessionName":  {"dollaryaml"},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	if name := os.Getenv("AWS_ROLE_SESSION_NAME"); name != "" {
+		query.Set("RoleSessionName", name)
+	}
+	endpoint := "https://sts.amazonaws.com/"
+	if r.cfg.Region != "" {
+		endpoint = "https://sts." + r.cfg.Region + ".amazonaws.com/"
+	}
+
+	body, err := r.fetch(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	var out struct {
+		Credentials struct {
+			AccessKeyId     string
+			SecretAccessKey string
+			SessionToken    string
+			Expiration      time.Time
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &out); err != nil {
+		return AWSCredentials{}, err
+	}
+	c := out.Credentials
+	return AWSCredentials{AccessKeyID: c.AccessKeyId, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken, Expires: c.Expiration}, nil
+}
+
+// containerCredentials reads credentials from the ECS credentials endpoint
+func (r *awsResolver) containerCredentials(ctx context.Context, uri string) (AWSCredentials, error) {
+	headers := map[string]string{}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		headers["Authorization"] = token
+	}
+	body, err := r.fetch(ctx, http.MethodGet, uri, headers)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	return decodeAWSCredentials(body)
+}
+
+// instanceCredentials reads role credentials from EC2 IMDSv2
+func (r *awsResolver) instanceCredentials(ctx context.Context) (AWSCredentials, error) {
+	const imds = "http://169.254.169.254/latest"
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	token, err := r.fetch(ctx, http.MethodPut, imds+"/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "21600"})
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("no credentials found in env, shared file, container or instance metadata: %w", err)
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": string(token)}
+	role, err := r.fetch(ctx, http.MethodGet, imds+"/meta-data/iam/security-credentials/", headers)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	body, err := r.fetch(ctx, http.MethodGet, imds+"/meta-data/iam/security-credentials/"+strings.TrimSpace(string(role)), headers)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	return decodeAWSCredentials(body)
+}
+
+// decodeAWSCredentials parses the JSON credential document served by the
+// container and instance metadata endpoints
+func decodeAWSCredentials(body []byte) (AWSCredentials, error) {
+	var out struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return AWSCredentials{}, err
+	}
+	return AWSCredentials{AccessKeyID: out.AccessKeyId, SecretAccessKey: out.SecretAccessKey, SessionToken: out.Token, Expires: out.Expiration}, nil
+}
+
+// fetch performs an unsigned request and returns the response body
+func (r *awsResolver) fetch(ctx context.Context, method, uri string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", method, uri, resp.Status)
+	}
+	return body, nil
+}
+
+// getObject downloads an S3 object with a signed request, returning its
+// content and Last-Modified time. Objects are addressed virtual-host
+// style, or path-style under cfg.Endpoint when it is set.
+func (r *awsResolver) getObject(ctx context.Context, bucket, key string) ([]byte, time.Time, error) {
+	if r.cfg.Region == "" {
+		return nil, time.Time{}, errors.New("aws: region not set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	creds, err := r.credentials(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	objectPath := (&url.URL{Path: "/" + key}).EscapedPath()
+	endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", bucket, r.cfg.Region, objectPath)
+	if r.cfg.Endpoint != "" {
+		endpoint = strings.TrimRight(r.cfg.Endpoint, "/") + "/" + bucket + objectPath
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	emptyHash := sha256.Sum256(nil)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(emptyHash[:]))
+	signAWSRequest(req, nil, creds, "s3", r.cfg.Region, time.Now())
+
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, time.Time{}, fmt.Errorf("%w: s3 object %s/%s", ErrValueNotFound, bucket, key)
+	default:
+		var apiErr struct {
+			Code    string
+			Message string
+		}
+		xml.Unmarshal(body, &apiErr)
+		return nil, time.Time{}, fmt.Errorf("aws s3 %s: %s %s", resp.Status, apiErr.Code, apiErr.Message)
+	}
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return body, modified, nil
+}
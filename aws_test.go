@@ -0,0 +1,151 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newAWSServer fakes the Secrets Manager and SSM JSON APIs
+func newAWSServer(t *testing.T, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		atomic.AddInt32(calls, 1)
+		var in map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&in)
+
+		notFound := func(errType string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": errType, "message": "not found"})
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "secretsmanager.GetSecretValue":
+			secrets := map[string]string{
+				"plain": "s3cret",
+				"db":    `{"user":"app","port":5432}`,
+				"arn:aws:secretsmanager:eu-west-1:123456789012:secret:db": `{"user":"arn-app"}`,
+			}
+			secret, ok := secrets[in["SecretId"].(string)]
+			if !ok {
+				notFound("ResourceNotFoundException")
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"SecretString": secret})
+		case "AmazonSSM.GetParameter":
+			if in["Name"] != "/app/db/password" || in["WithDecryption"] != true {
+				notFound("ParameterNotFound")
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"Parameter": map[string]string{"Value": "decrypted"},
+			})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestYamlProfile_AWSSchemes(t *testing.T) {
+	var calls int32
+	server := newAWSServer(t, &calls)
+	defer server.Close()
+
+	p := New(false, WithAWS(AWSConfig{
+		Region:   "us-east-1",
+		Endpoint: server.URL,
+		Credentials: func(ctx context.Context) (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		},
+	}))
+	yamlData := []byte(`
+plain: ${aws-sm:plain}
+user: ${aws-sm:db#user}
+port: ${aws-sm:db#port}
+arn: ${aws-sm:arn:aws:secretsmanager:eu-west-1:123456789012:secret:db#user}
+arnDefault: ${aws-sm:arn:aws:secretsmanager:eu-west-1:123456789012:secret:other#user:fallback}
+missingKey: ${aws-sm:db#password:none}
+param: ${aws-ssm:/app/db/password}
+missingParam: ${aws-ssm:/app/other:default}
+`)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"plain", "s3cret"},
+		{"user", "app"},
+		{"port", "5432"},
+		{"arn", "arn-app"},
+		{"arnDefault", "fallback"},
+		{"missingKey", "none"},
+		{"param", "decrypted"},
+		{"missingParam", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := p.GetError(tt.path)
+			if err != nil {
+				t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+			}
+			assert(t, got, tt.want, tt.path)
+		})
+	}
+
+	before := atomic.LoadInt32(&calls)
+	p.Get("user")
+	p.Get("param")
+	if after := atomic.LoadInt32(&calls); after != before {
+		t.Errorf("expected cached lookups, got %d extra calls", after-before)
+	}
+}
+
+func TestYamlProfile_AWSNotConfigured(t *testing.T) {
+	p := New(false)
+	if err := p.Read([]byte("a: ${aws-sm:db:fallback}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := p.GetError("a"); err == nil || !strings.Contains(err.Error(), "WithAWS") {
+		t.Errorf("expected resolver not configured error, got %v", err)
+	}
+}
+
+func TestSplitAWSSecretKey(t *testing.T) {
+	tests := []struct {
+		rest, key, def string
+		hasDefault     bool
+	}{
+		{"db", "db", "", false},
+		{"db#user:admin", "db#user", "admin", true},
+		{"arn:aws:secretsmanager:us-east-1:1:secret:db", "arn:aws:secretsmanager:us-east-1:1:secret:db", "", false},
+		{"arn:aws:secretsmanager:us-east-1:1:secret:db#k:a:b", "arn:aws:secretsmanager:us-east-1:1:secret:db#k", "a:b", true},
+	}
+	for _, tt := range tests {
+		key, def, hasDefault := splitAWSSecretKey(tt.rest)
+		if key != tt.key || def != tt.def || hasDefault != tt.hasDefault {
+			t.Errorf("splitAWSSecretKey(%q) = %q, %q, %v", tt.rest, key, def, hasDefault)
+		}
+	}
+}
+
+func TestSignAWSRequest(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSRequest(req, nil, creds, "service", "us-east-1", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	assert(t, req.Header.Get("Authorization"), want, "Authorization")
+}
@@ -0,0 +1,307 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AzureToken is an OAuth2 access token for Azure Key Vault
+type AzureToken struct {
+	AccessToken string
+	// Expires is when the token stops working, zero if unknown
+	Expires time.Time
+}
+
+// AzureConfig configures the ${azure-kv:vaultName/secretName} resolver
+type AzureConfig struct {
+	// Token overrides the default credential chain, e.g. to plug in an
+	// azidentity credential
+	Token func(ctx context.Context) (AzureToken, error)
+	// DNSSuffix of the Key Vault service; defaults to vault.azure.net, set
+	// it for sovereign clouds such as vault.azure.cn
+	DNSSuffix string
+	// Endpoint overrides the vault URL, with {vault} replaced by the vault
+	// name, e.g. for a local emulator
+	Endpoint string
+	// BlobEndpoint overrides the Blob Storage URL used by
+	// ReadFromAzureBlob, with {account} replaced by the storage account
+	// name; defaults to https://{account}.blob.core.windows.net. Set it for
+	// sovereign clouds or Azurite.
+	BlobEndpoint string
+	// Timeout bounds each request to Azure; defaults to 10 seconds
+	Timeout time.Duration
+	// CacheTTL is how long a fetched secret is reused; defaults to 5
+	// minutes, and a negative value disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the client used to reach Azure
+	HTTPClient *http.Client
+}
+
+// azureResolver fetches secrets from Azure Key Vault
+type azureResolver struct {
+	cfg   AzureConfig
+	cache *ttlCache
+	// resource the default credential chain requests tokens for
+	resource string
+
+	mu    sync.Mutex
+	token AzureToken
+}
+
+func newAzureResolver(cfg AzureConfig) *azureResolver {
+	if cfg.DNSSuffix == "" {
+		cfg.DNSSuffix = "vault.azure.net"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://{vault}." + cfg.DNSSuffix
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	r := &azureResolver{cfg: cfg, cache: newTTLCache(cfg.CacheTTL), resource: "https://" + cfg.DNSSuffix}
+	if r.cfg.Token == nil {
+		r.cfg.Token = r.defaultToken
+	}
+	return r
+}
+
+// resolve returns the secret named by key, vaultName/secretName with an
+// optional /version suffix
+func (r *azureResolver) resolve(ctx context.Context, key string) (string, error) {
+	parts := strings.Split(strings.Trim(key, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("azure-kv key %q is not vaultName/secretName[/version]", key)
+	}
+	value, err := r.cache.get(ctx, strings.Join(parts, "/"), func() (interface{}, error) {
+		return r.secret(ctx, parts[0], strings.Join(parts[1:], "/"))
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// secret calls the Get Secret API
+func (r *azureResolver) secret(ctx context.Context, vault, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	endpoint := strings.ReplaceAll(r.cfg.Endpoint, "{vault}", vault) + "/secrets/" + name + "?api-version=7.4"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: azure secret %s/%s", ErrValueNotFound, vault, name)
+	default:
+		return "", fmt.Errorf("azure key vault %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", err
+	}
+	return out.Value, nil
+}
+
+// accessToken returns a cached token, refreshing it before expiry
+func (r *azureResolver) accessToken(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token.AccessToken == "" || (!r.token.Expires.IsZero() && time.Until(r.token.Expires) < time.Minute) {
+		token, err := r.cfg.Token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("azure credentials: %w", err)
+		}
+		r.token = token
+	}
+	return r.token.AccessToken, nil
+}
+
+// defaultToken follows the DefaultAzureCredential chain: a client secret
+// from the environment, workload identity (AKS), then managed identity
+func (r *azureResolver) defaultToken(ctx context.Context) (AzureToken, error) {
+	resource := r.resource
+	tenant, client := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+
+	if secret := os.Getenv("AZURE_CLIENT_SECRET"); secret != "" && tenant != "" && client != "" {
+		return r.clientToken(ctx, tenant, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {client},
+			"client_secret": {secret},
+			"scope":         {resource + "/.default"},
+		})
+	}
+	if tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tokenFile != "" && tenant != "" && client != "" {
+		assertion, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return AzureToken{}, err
+		}
+		return r.clientToken(ctx, tenant, url.Values{
+			"grant_type":            {"client_credentials"},
+			"client_id":             {client},
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {strings.TrimSpace(string(assertion))},
+			"scope":                 {resource + "/.default"},
+		})
+	}
+	return r.managedIdentityToken(ctx, resource, client)
+}
+
+// clientToken requests a token from Microsoft Entra ID for tenant
+func (r *azureResolver) clientToken(ctx context.Context, tenant string, form url.Values) (AzureToken, error) {
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authority == "" {
+		authority = "https://login.microsoftonline.com"
+	}
+	endpoint := strings.TrimRight(authority, "/") + "/" + tenant + "/oauth2/v2.0/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return AzureToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r.decodeToken(req)
+}
+
+// managedIdentityToken asks the App Service identity endpoint or the VM
+// instance metadata service for a token
+func (r *azureResolver) managedIdentityToken(ctx context.Context, resource, client string) (AzureToken, error) {
+	query := url.Values{"resource": {resource}}
+	if client != "" {
+		query.Set("client_id", client)
+	}
+
+	var req *http.Request
+	var err error
+	if endpoint := os.Getenv("IDENTITY_ENDPOINT"); endpoint != "" {
+		query.Set("api-version", "2019-08-01")
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+		if err == nil {
+			req.Header.Set("X-IDENTITY-HEADER", os.Getenv("IDENTITY_HEADER"))
+		}
+	} else {
+		query.Set("api-version", "2018-02-01")
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet,
+			"http://169.254.169.254/metadata/identity/oauth2/token?"+query.Encode(), nil)
+		if err == nil {
+			req.Header.Set("Metadata", "true")
+		}
+	}
+	if err != nil {
+		return AzureToken{}, err
+	}
+	token, err := r.decodeToken(req)
+	if err != nil {
+		return AzureToken{}, fmt.Errorf("no client secret or workload identity configured and managed identity unavailable: %w", err)
+	}
+	return token, nil
+}
+
+// decodeToken performs req and parses the token response. expires_in is
+// a number from Entra ID but a string from managed identity endpoints.
+func (r *azureResolver) decodeToken(req *http.Request) (AzureToken, error) {
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return AzureToken{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return AzureToken{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return AzureToken{}, fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+	var out struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return AzureToken{}, err
+	}
+	token := AzureToken{AccessToken: out.AccessToken}
+	if seconds, err := strconv.Atoi(out.ExpiresIn.String()); err == nil && seconds > 0 {
+		token.Expires = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// azureStorageResource is the token audience for Azure Storage
+const azureStorageResource = "https://storage.azure.com"
+
+// getBlob downloads a blob with a Microsoft Entra ID token, returning its
+// content and Last-Modified time
+func (r *azureResolver) getBlob(ctx context.Context, account, container, blob string) ([]byte, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	endpoint := r.cfg.BlobEndpoint
+	if endpoint == "" {
+		endpoint = "https://{account}.blob.core.windows.net"
+	}
+	endpoint = strings.TrimRight(strings.ReplaceAll(endpoint, "{account}", account), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/"+container+(&url.URL{Path: "/" + blob}).EscapedPath(), nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("x-ms-version", "2021-08-06")
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, time.Time{}, fmt.Errorf("%w: azure blob %s/%s/%s", ErrValueNotFound, account, container, blob)
+	default:
+		return nil, time.Time{}, fmt.Errorf("azure blob storage %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return body, modified, nil
+}
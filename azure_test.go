@@ -0,0 +1,140 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// newAzureServer fakes Key Vault, the Entra ID token endpoint and the App
+// Service managed identity endpoint
+func newAzureServer(t *testing.T, reads *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tenant/oauth2/v2.0/token":
+			r.ParseForm()
+			if r.Form.Get("client_secret") != "cs" && r.Form.Get("client_assertion") != "fed-jwt" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "entra", "expires_in": 3600})
+			return
+		case "/msi":
+			if r.Header.Get("X-IDENTITY-HEADER") != "ih" || r.URL.Query().Get("resource") != "https://vault.azure.net" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "msi", "expires_in": "3600"})
+			return
+		}
+
+		if auth := r.Header.Get("Authorization"); auth != "Bearer entra" && auth != "Bearer msi" && auth != "Bearer static" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(reads, 1)
+		switch r.URL.Path {
+		case "/myvault/secrets/db-password":
+			json.NewEncoder(w).Encode(map[string]string{"value": "s3cret"})
+		case "/myvault/secrets/db-password/v1":
+			json.NewEncoder(w).Encode(map[string]string{"value": "old"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestYamlProfile_AzureScheme(t *testing.T) {
+	var reads int32
+	server := newAzureServer(t, &reads)
+	defer server.Close()
+
+	fedPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(fedPath, []byte("fed-jwt\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	yamlData := []byte(`
+password: ${azure-kv:myvault/db-password}
+pinned: ${azure-kv:myvault/db-password/v1}
+missing: ${azure-kv:myvault/other:fallback}
+`)
+
+	reset := map[string]string{
+		"AZURE_TENANT_ID": "", "AZURE_CLIENT_ID": "", "AZURE_CLIENT_SECRET": "",
+		"AZURE_FEDERATED_TOKEN_FILE": "", "IDENTITY_ENDPOINT": "", "IDENTITY_HEADER": "",
+		"AZURE_AUTHORITY_HOST": server.URL,
+	}
+	auths := map[string]struct {
+		cfg AzureConfig
+		env map[string]string
+	}{
+		"static": {cfg: AzureConfig{Token: func(ctx context.Context) (AzureToken, error) {
+			return AzureToken{AccessToken: "static"}, nil
+		}}},
+		"client secret": {env: map[string]string{
+			"AZURE_TENANT_ID": "tenant", "AZURE_CLIENT_ID": "app", "AZURE_CLIENT_SECRET": "cs",
+		}},
+		"workload identity": {env: map[string]string{
+			"AZURE_TENANT_ID": "tenant", "AZURE_CLIENT_ID": "app", "AZURE_FEDERATED_TOKEN_FILE": fedPath,
+		}},
+		"managed identity": {env: map[string]string{
+			"IDENTITY_ENDPOINT": server.URL + "/msi", "IDENTITY_HEADER": "ih",
+		}},
+	}
+	for name, auth := range auths {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range reset {
+				t.Setenv(k, v)
+			}
+			for k, v := range auth.env {
+				t.Setenv(k, v)
+			}
+			cfg := auth.cfg
+			cfg.Endpoint = server.URL + "/{vault}"
+			p := New(false, WithAzure(cfg))
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+
+			tests := []struct {
+				path string
+				want string
+			}{
+				{"password", "s3cret"},
+				{"pinned", "old"},
+				{"missing", "fallback"},
+			}
+			for _, tt := range tests {
+				got, err := p.GetError(tt.path)
+				if err != nil {
+					t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+				}
+				assert(t, got, tt.want, tt.path)
+			}
+
+			before := atomic.LoadInt32(&reads)
+			p.Get("password")
+			if after := atomic.LoadInt32(&reads); after != before {
+				t.Errorf("expected cached lookup, got %d extra reads", after-before)
+			}
+		})
+	}
+}
+
+func TestYamlProfile_AzureSchemeBadKey(t *testing.T) {
+	p := New(false, WithAzure(AzureConfig{Token: func(ctx context.Context) (AzureToken, error) {
+		return AzureToken{AccessToken: "static"}, nil
+	}}))
+	if err := p.Read([]byte("a: ${azure-kv:novault}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := p.GetError("a"); err == nil {
+		t.Error("expected error for key without secret name")
+	}
+}
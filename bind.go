@@ -0,0 +1,97 @@
+package dollarYaml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrInvalidBindTarget = errors.New("bind target must be a non-nil pointer to a struct")
+
+// Binding publishes a struct decoded from a profile and replaced with a
+// freshly decoded one whenever the config changes
+type Binding struct {
+	p        *YamlProfile
+	typ      reflect.Type
+	onRebind func(old, new interface{})
+
+	current atomic.Value // holds the published pointer
+	mu      sync.Mutex   // serializes rebinds
+	err     error
+	closed  bool
+	cancel  func()
+}
+
+// BindStruct decodes the profile into target, a pointer to a struct, and
+// keeps it up to date: after every successful reload or env change seen
+// through OnChange, the config is decoded into a new struct of the same
+// type which is then published atomically. Load returns the latest one,
+// so readers always see a complete struct and never one being decoded.
+// onRebind, if not nil, is called with the previous and the new pointer
+// after each rebind. A failed decode keeps the previous struct; its error
+// is reported by Err.
+func (p *YamlProfile) BindStruct(target interface{}, onRebind func(old, new interface{})) (*Binding, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w, got %T", ErrInvalidBindTarget, target)
+	}
+	if err := p.UnmarshalTo(target); err != nil {
+		return nil, err
+	}
+
+	b := &Binding{p: p, typ: v.Elem().Type(), onRebind: onRebind}
+	b.current.Store(target)
+	b.cancel = p.OnChange(func(ChangeEvent) {
+		b.rebind()
+	})
+	return b, nil
+}
+
+// Load returns the current struct as a pointer of the type given to
+// BindStruct. The struct must be treated as read-only, since other
+// goroutines may hold the same pointer.
+func (b *Binding) Load() interface{} {
+	return b.current.Load()
+}
+
+// Err returns the error of the last rebind, or nil if it succeeded
+func (b *Binding) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// Close stops rebinding and unregisters the binding from the profile.
+// Load keeps returning the last struct.
+func (b *Binding) Close() {
+	b.cancel()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+// rebind decodes the config into a new struct and publishes it
+func (b *Binding) rebind() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	next := reflect.New(b.typ).Interface()
+	if err := b.p.UnmarshalTo(next); err != nil {
+		b.p.debugf("Rebinding %s failed: %v\n", b.typ, err)
+		b.err = err
+		b.mu.Unlock()
+		return
+	}
+	b.err = nil
+	old := b.current.Load()
+	b.current.Store(next)
+	b.mu.Unlock()
+
+	if b.onRebind != nil {
+		b.onRebind(old, next)
+	}
+}
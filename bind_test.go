@@ -0,0 +1,73 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+type boundConfig struct {
+	Server struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	} `yaml:"server"`
+}
+
+func TestYamlProfile_BindStruct(t *testing.T) {
+	os.Setenv("BIND_HOST", "first.local")
+	defer os.Unsetenv("BIND_HOST")
+
+	p := New(false)
+	if err := p.Read([]byte("server:\n  host: ${BIND_HOST}\n  port: 80\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var rebinds []*boundConfig
+	initial := &boundConfig{}
+	b, err := p.BindStruct(initial, func(old, new interface{}) {
+		if old.(*boundConfig) == new.(*boundConfig) {
+			t.Error("rebind reused the old struct")
+		}
+		rebinds = append(rebinds, new.(*boundConfig))
+	})
+	if err != nil {
+		t.Fatalf("BindStruct failed: %v", err)
+	}
+	if b.Load() != initial || initial.Server.Host != "first.local" {
+		t.Fatalf("expected the decoded target to be published, got %+v", b.Load())
+	}
+
+	// Reading the config again rebinds into a new struct
+	if err := p.Read([]byte("server:\n  host: ${BIND_HOST}\n  port: 8080\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	cfg := b.Load().(*boundConfig)
+	assert(t, cfg.Server.Port, 8080, "port after reload")
+	assert(t, initial.Server.Port, 80, "old struct is left alone")
+	assert(t, len(rebinds), 1, "rebinds")
+
+	// An env change found by polling rebinds too
+	os.Setenv("BIND_HOST", "second.local")
+	p.notify(ChangeEvent{Env: []string{"BIND_HOST"}, Paths: []string{"server.host"}})
+	assert(t, b.Load().(*boundConfig).Server.Host, "second.local", "host after env change")
+
+	// A failed decode keeps the last good struct
+	if err := p.Read([]byte("server:\n  port: not-a-number\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if b.Err() == nil {
+		t.Error("expected a rebind error")
+	}
+	assert(t, b.Load().(*boundConfig).Server.Port, 8080, "port after failed rebind")
+
+	b.Close()
+	assert(t, len(p.hooks.hooks), 0, "hooks after Close")
+	if err := p.Read([]byte("server:\n  port: 9090\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, b.Load().(*boundConfig).Server.Port, 8080, "port after Close")
+
+	if _, err := p.BindStruct(boundConfig{}, nil); !errors.Is(err, ErrInvalidBindTarget) {
+		t.Errorf("expected ErrInvalidBindTarget but got %v", err)
+	}
+}
@@ -0,0 +1,452 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSourceTimeout bounds how long a Builder waits for each source when
+// no other timeout is set
+const DefaultSourceTimeout = 30 * time.Second
+
+// Source provides one layer of configuration to a Builder
+type Source interface {
+	// Name identifies the source in errors
+	Name() string
+	// Load fetches the source and returns its tree with placeholders
+	// still unresolved
+	Load(ctx context.Context) (map[string]interface{}, error)
+}
+
+// WatchableSource is a Source that can wait for its data to change, so
+// Builder.Watch can reload it
+type WatchableSource interface {
+	Source
+	// Watch blocks until the data may have changed since the last Load,
+	// returning nil, or until ctx is done or the watch fails
+	Watch(ctx context.Context) error
+}
+
+// ErrNotWatchable is returned by Builder.Watch when none of the sources
+// is a WatchableSource
+var ErrNotWatchable = errors.New("no watchable source")
+
+// watchRetryDelay is how long Builder.Watch waits after a failure
+var watchRetryDelay = time.Second
+
+// Builder assembles a profile from several sources. Sources are fetched
+// concurrently, then merged in the order they were added, later sources
+// overriding earlier ones key by key. Lists are merged as set with
+// WithListMergeStrategy, by default replaced.
+type Builder struct {
+	opts    []Option
+	timeout time.Duration
+	sources []builderSource
+	// layered records the tree of each source for Explain, as Layers does
+	layered bool
+}
+
+type builderSource struct {
+	src      Source
+	timeout  time.Duration
+	layer    string
+	priority int
+}
+
+// NewBuilder creates a Builder whose profile is configured with opts
+func NewBuilder(opts ...Option) *Builder {
+	return &Builder{opts: opts, timeout: DefaultSourceTimeout}
+}
+
+// Timeout sets the default time allowed for each source to load
+func (b *Builder) Timeout(timeout time.Duration) *Builder {
+	b.timeout = timeout
+	return b
+}
+
+// Add appends a source, taking precedence over those added before it
+func (b *Builder) Add(src Source) *Builder {
+	b.sources = append(b.sources, builderSource{src: src})
+	return b
+}
+
+// AddWithTimeout appends a source with its own load timeout
+func (b *Builder) AddWithTimeout(src Source, timeout time.Duration) *Builder {
+	b.sources = append(b.sources, builderSource{src: src, timeout: timeout})
+	return b
+}
+
+// Build loads every source concurrently and merges the results. The merge
+// order only depends on the order sources were added, never on which one
+// finished first. If any source fails, the error of the earliest failing
+// source is returned.
+func (b *Builder) Build(ctx context.Context) (*YamlProfile, error) {
+	p := NewProfile(b.opts...)
+	merged, trees, err := b.loadAll(ctx, p.listMerge)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.install(p, merged, trees); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// loadAll loads every source concurrently and merges their trees in the
+// order the sources were added. The trees of the sources are returned
+// too, in the same order.
+func (b *Builder) loadAll(ctx context.Context, lists ListMergeStrategy) (map[string]interface{}, []map[string]interface{}, error) {
+	trees := make([]map[string]interface{}, len(b.sources))
+	errs := make([]error, len(b.sources))
+
+	var wg sync.WaitGroup
+	for i, s := range b.sources {
+		wg.Add(1)
+		go func(i int, s builderSource) {
+			defer wg.Done()
+			timeout := s.timeout
+			if timeout <= 0 {
+				timeout = b.timeout
+			}
+			loadCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			trees[i], errs[i] = loadSource(loadCtx, s.src)
+		}(i, s)
+	}
+	wg.Wait()
+
+	m := merger{lists: lists}
+	merged := make(map[string]interface{})
+	for i, tree := range trees {
+		if errs[i] != nil {
+			return nil, nil, fmt.Errorf("source %s: %w", b.sources[i].src.Name(), errs[i])
+		}
+		normalizeTree(tree)
+		m.merge(merged, tree, "")
+	}
+	return merged, trees, nil
+}
+
+// install loads the merged tree into p, recording the source names, the
+// prefixes of its FromEnvPrefix sources and, for a layered Builder, the
+// tree of each layer
+func (b *Builder) install(p *YamlProfile, merged map[string]interface{}, trees []map[string]interface{}) error {
+	raw, err := yaml.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	var sources, envSources []string
+	var layers []layerTree
+	for i, s := range b.sources {
+		sources = append(sources, s.src.Name())
+		if env, ok := s.src.(envPrefixSource); ok {
+			envSources = append(envSources, env.prefix)
+		}
+		if b.layered {
+			layers = append(layers, layerTree{name: s.layer, priority: s.priority, tree: trees[i]})
+		}
+	}
+	p.loadWith(raw, merged, make(map[string]string), func() {
+		p.sources, p.layers, p.envSources = sources, layers, envSources
+	})
+	return nil
+}
+
+// Watch keeps p, a profile built by b, up to date. Whenever one of the
+// sources that is a WatchableSource reports a change, every source is
+// loaded again and the merged result replaces the config of p, whose
+// OnChange hooks are called with Reloaded set. Reads of p may run
+// concurrently with a reload and see either config; read through
+// BindStruct to see a whole struct decoded from one of them. Failed watches and
+// reloads are retried after a second, keeping the current config, and
+// logged when debug output is enabled. Watch blocks until ctx is done,
+// so run it in its own goroutine.
+func (b *Builder) Watch(ctx context.Context, p *YamlProfile) error {
+	changes := make(chan struct{}, 1)
+	watching := 0
+	for _, s := range b.sources {
+		w, ok := s.src.(WatchableSource)
+		if !ok {
+			continue
+		}
+		watching++
+		go func(w WatchableSource) {
+			for ctx.Err() == nil {
+				if err := w.Watch(ctx); err != nil {
+					if ctx.Err() == nil {
+						p.debugf("Watching source %s failed: %v\n", w.Name(), err)
+						sleepContext(ctx, watchRetryDelay)
+					}
+					continue
+				}
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}(w)
+	}
+	if watching == 0 {
+		return ErrNotWatchable
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changes:
+		}
+		for {
+			merged, trees, err := b.loadAll(ctx, p.listMerge)
+			if err == nil {
+				err = b.install(p, merged, trees)
+			}
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			p.debugf("Reloading after a source change failed: %v\n", err)
+			sleepContext(ctx, watchRetryDelay)
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// loadSource runs src.Load, giving up when ctx is done even if the source
+// ignores cancellation
+func loadSource(ctx context.Context, src Source) (map[string]interface{}, error) {
+	type result struct {
+		tree map[string]interface{}
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		tree, err := src.Load(ctx)
+		done <- result{tree, err}
+	}()
+	select {
+	case r := <-done:
+		return r.tree, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// copyTree returns a deep copy of a raw tree value
+func copyTree(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = copyTree(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = copyTree(item)
+		}
+		return list
+	}
+	return v
+}
+
+// fileSource reads a config file, decoding it with the codec registered
+// for its extension
+type fileSource struct {
+	path string
+}
+
+// FileSource returns a Source reading the file at path. $include keys are
+// expanded as in ReadFromPath; sources do not keep tags, so !include is not.
+func FileSource(path string) Source {
+	return fileSource{path: path}
+}
+
+func (s fileSource) Name() string { return s.path }
+
+func (s fileSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := decodeSource(data, filepath.Ext(s.path))
+	if err != nil {
+		return nil, err
+	}
+	return expandSource(tree, s.path, osIncluder(ListReplace))
+}
+
+// fsSource reads a config file from a file system
+type fsSource struct {
+	fsys fs.FS
+	name string
+}
+
+// FSSource returns a Source reading the file name in fsys, such as an
+// embed.FS. $include keys are expanded from fsys as in FileSource.
+func FSSource(fsys fs.FS, name string) Source {
+	return fsSource{fsys: fsys, name: name}
+}
+
+func (s fsSource) Name() string { return s.name }
+
+func (s fsSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	data, err := fs.ReadFile(s.fsys, s.name)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := decodeSource(data, path.Ext(s.name))
+	if err != nil {
+		return nil, err
+	}
+	return expandSource(tree, s.name, fsIncluder(s.fsys, ListReplace))
+}
+
+// bytesSource decodes in-memory data
+type bytesSource struct {
+	name   string
+	data   []byte
+	format string
+}
+
+// BytesSource returns a Source decoding data in format, an extension or
+// MIME type with a registered codec
+func BytesSource(name string, data []byte, format string) Source {
+	return bytesSource{name: name, data: data, format: format}
+}
+
+func (s bytesSource) Name() string { return s.name }
+
+func (s bytesSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	return decodeSource(s.data, s.format)
+}
+
+// envPrefixSource builds a tree from prefixed environment variables
+type envPrefixSource struct {
+	prefix string
+}
+
+// FromEnvPrefix returns a Source building a tree from the environment
+// variables starting with prefix, for deployments configured only
+// through the environment. The prefix is dropped, the rest lower-cased
+// and split at double underscores, so APP_DATABASE__HOST is read as
+// database.host and APP_DATABASE__MAX_CONNS as database.max_conns. Values
+// are typed as in YAML, and maps whose keys are the indices 0 to n-1
+// become lists, so APP_HOSTS__0 and APP_HOSTS__1 form a list. Added after
+// a FileSource, the variables override the file's defaults.
+func FromEnvPrefix(prefix string) Source {
+	return envPrefixSource{prefix: prefix}
+}
+
+func (s envPrefixSource) Name() string { return "env:" + s.prefix }
+
+func (s envPrefixSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	vars := make(map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if rest := strings.TrimPrefix(name, s.prefix); rest != name && rest != "" {
+			vars[rest] = value
+		}
+	}
+	tree := make(map[string]interface{})
+	for _, name := range sortedKeys(vars) {
+		keys := strings.Split(strings.ToLower(name), "__")
+		if !validEnvKeys(keys) {
+			continue
+		}
+		if err := setLeaf(tree, keys, typedText(vars[name])); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", s.prefix, name, err)
+		}
+	}
+	return indexedLists(tree).(map[string]interface{}), nil
+}
+
+// validEnvKeys reports whether none of the segments is empty
+func validEnvKeys(keys []string) bool {
+	for _, k := range keys {
+		if k == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// indexedLists replaces the maps in v whose keys are exactly 0 to n-1
+// with lists, keeping the root a map
+func indexedLists(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for k, item := range m {
+		m[k] = indexedLists(item)
+		if child, ok := m[k].(map[string]interface{}); ok && len(child) > 0 {
+			list := make([]interface{}, len(child))
+			for i := range list {
+				item, ok := child[strconv.Itoa(i)]
+				if !ok {
+					list = nil
+					break
+				}
+				list[i] = item
+			}
+			if list != nil {
+				m[k] = list
+			}
+		}
+	}
+	return m
+}
+
+// expandSource expands the $include keys of tree, the contents of the
+// file name. Its root must stay a map.
+func expandSource(tree map[string]interface{}, name string, inc *includer) (map[string]interface{}, error) {
+	expanded, err := inc.expand(tree, nil, name)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := expanded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrInvalidRoot)
+	}
+	return m, nil
+}
+
+// decodeSource decodes data with the codec for format, defaulting to YAML
+func decodeSource(data []byte, format string) (map[string]interface{}, error) {
+	c, ok := CodecFor(format)
+	if !ok {
+		c = yamlCodec{}
+	}
+	var tree map[string]interface{}
+	if err := c.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		tree = make(map[string]interface{})
+	}
+	return tree, nil
+}
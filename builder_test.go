@@ -0,0 +1,254 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// slowSource returns a fixed tree after a delay
+type slowSource struct {
+	name  string
+	delay time.Duration
+	tree  map[string]interface{}
+	err   error
+}
+
+func (s slowSource) Name() string { return s.name }
+
+func (s slowSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	time.Sleep(s.delay)
+	return s.tree, s.err
+}
+
+func TestBuilder_Build(t *testing.T) {
+	os.Setenv("BUILDER_HOST", "db.internal")
+	defer os.Unsetenv("BUILDER_HOST")
+
+	path := filepath.Join(t.TempDir(), "base.yaml")
+	base := "db:\n  host: ${BUILDER_HOST}\n  port: 5432\n  pool: {min: 1, max: 4}\nname: base\n"
+	if err := os.WriteFile(path, []byte(base), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	start := time.Now()
+	p, err := NewBuilder().
+		Add(FileSource(path)).
+		Add(slowSource{name: "slow", delay: 100 * time.Millisecond, tree: map[string]interface{}{
+			"db": map[string]interface{}{"port": 6432, "pool": map[string]interface{}{"max": 16}},
+		}}).
+		Add(slowSource{name: "fast", tree: map[string]interface{}{
+			"db":   map[string]interface{}{"port": 7432},
+			"name": "override",
+		}}).
+		Add(BytesSource("inline", []byte(`{"extra": true}`), "yaml")).
+		Add(FSSource(fstest.MapFS{"embedded.yaml": {Data: []byte("embedded: yes")}}, "embedded.yaml")).
+		Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Build took %v", elapsed)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"db.host", "db.internal"},
+		// The fast source finishes first but was added last, so it wins
+		{"db.port", "7432"},
+		{"db.pool.min", "1"},
+		{"db.pool.max", "16"},
+		{"name", "override"},
+		{"extra", "true"},
+		{"embedded", "yes"},
+	}
+	for _, tt := range tests {
+		assert(t, p.Get(tt.path), tt.want, tt.path)
+	}
+	if p.ConfigHash() == "" {
+		t.Error("expected a config hash for the merged tree")
+	}
+}
+
+func TestBuilder_Concurrent(t *testing.T) {
+	b := NewBuilder()
+	for i := 0; i < 5; i++ {
+		b.Add(slowSource{name: "slow", delay: 200 * time.Millisecond, tree: map[string]interface{}{}})
+	}
+	start := time.Now()
+	if _, err := b.Build(context.Background()); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("sources were not fetched concurrently, took %v", elapsed)
+	}
+}
+
+func TestBuilder_Errors(t *testing.T) {
+	_, err := NewBuilder().
+		Add(slowSource{name: "ok", tree: map[string]interface{}{}}).
+		AddWithTimeout(slowSource{name: "hung", delay: time.Second}, 50*time.Millisecond).
+		Build(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "hung") {
+		t.Errorf("expected timeout from hung source, got %v", err)
+	}
+
+	failure := errors.New("boom")
+	_, err = NewBuilder().
+		Add(slowSource{name: "first", delay: 50 * time.Millisecond, err: failure}).
+		Add(slowSource{name: "second", err: errors.New("other")}).
+		Build(context.Background())
+	if !errors.Is(err, failure) {
+		t.Errorf("expected error of the earliest source, got %v", err)
+	}
+
+	if _, err := NewBuilder().Add(FileSource("/nonexistent/config.yaml")).Build(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestFromEnvPrefix(t *testing.T) {
+	t.Setenv("ENVSRC_DATABASE__HOST", "db.internal")
+	t.Setenv("ENVSRC_DATABASE__MAX_CONNS", "20")
+	t.Setenv("ENVSRC_HOSTS__0", "a")
+	t.Setenv("ENVSRC_HOSTS__1", "b")
+	t.Setenv("ENVSRC_DEBUG", "true")
+	t.Setenv("ENVSRC_BAD____KEY", "skipped")
+	t.Setenv("OTHER_DATABASE__HOST", "ignored")
+
+	defaults := BytesSource("defaults", []byte("database:\n  host: localhost\n  port: 5432\nname: app\n"), ".yaml")
+	p, err := NewBuilder().Add(defaults).Add(FromEnvPrefix("ENVSRC_")).Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"database.host", "db.internal"},
+		{"database.port", "5432"},
+		{"database.max_conns", "20"},
+		{"hosts.1", "b"},
+		{"name", "app"},
+	}
+	for _, tt := range tests {
+		assert(t, p.Get(tt.path), tt.want, tt.path)
+	}
+
+	var cfg struct {
+		Debug    bool     `yaml:"debug"`
+		Hosts    []string `yaml:"hosts"`
+		Database struct {
+			MaxConns int `yaml:"max_conns"`
+		} `yaml:"database"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, cfg.Debug, true, "debug")
+	assert(t, strings.Join(cfg.Hosts, ","), "a,b", "hosts")
+	assert(t, cfg.Database.MaxConns, 20, "max_conns")
+
+	t.Setenv("ENVSRC_DATABASE", "conflict")
+	if _, err := NewBuilder().Add(FromEnvPrefix("ENVSRC_")).Build(context.Background()); err == nil {
+		t.Error("expected a conflict between ENVSRC_DATABASE and its nested keys")
+	}
+}
+
+// tickSource is a WatchableSource whose data changes on every tick
+type tickSource struct {
+	ticks chan struct{}
+	n     int64
+}
+
+func (s *tickSource) Name() string { return "tick" }
+
+func (s *tickSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	n := atomic.AddInt64(&s.n, 1)
+	return map[string]interface{}{
+		"server": map[string]interface{}{"port": 8000 + n%2, "host": "${TICK_HOST:localhost}"},
+		"list":   []interface{}{n, n + 1},
+	}, nil
+}
+
+func (s *tickSource) Watch(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ticks:
+		return nil
+	}
+}
+
+func TestBuilder_WatchConcurrentReads(t *testing.T) {
+	src := &tickSource{ticks: make(chan struct{})}
+	b := NewBuilder().Add(src)
+	p, err := b.Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	var reloads int64
+	p.OnChange(func(ChangeEvent) { atomic.AddInt64(&reloads, 1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- b.Watch(ctx, p) }()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var cfg struct {
+				Server struct {
+					Port int    `yaml:"port"`
+					Host string `yaml:"host"`
+				} `yaml:"server"`
+			}
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if port := p.Get("server.port"); port != "8000" && port != "8001" {
+					t.Errorf("server.port = %q", port)
+					return
+				}
+				if err := p.UnmarshalTo(&cfg); err != nil {
+					t.Errorf("UnmarshalTo failed: %v", err)
+					return
+				}
+				p.Has("list.1")
+				p.GetRaw("server")
+				p.Sub("server").Get("host")
+				p.LoadedAt()
+				p.Age()
+				p.Tags()
+			}
+		}()
+	}
+	deadline := time.After(5 * time.Second)
+	for atomic.LoadInt64(&reloads) < 5 {
+		select {
+		case src.ticks <- struct{}{}:
+		case <-deadline:
+			t.Fatalf("only %d reloads", atomic.LoadInt64(&reloads))
+		}
+	}
+	close(stop)
+	wg.Wait()
+	cancel()
+	<-done
+}
@@ -0,0 +1,98 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errFetchPanicked is returned to lookups that waited on a fetch which
+// panicked
+var errFetchPanicked = errors.New("fetch panicked")
+
+// ttlCache keeps values fetched from remote resolvers for a limited time
+type ttlCache struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]ttlCacheEntry
+	inflight map[string]*ttlCacheCall
+}
+
+type ttlCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// ttlCacheCall is a fetch in progress, shared by the lookups of its key
+type ttlCacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+	// canceled is set when the fetch failed because the context of the
+	// caller running it was done, an error not shared with other callers
+	canceled bool
+}
+
+// newTTLCache creates a cache; a ttl <= 0 disables caching
+func newTTLCache(ttl time.Duration) *ttlCache {
+	return &ttlCache{
+		ttl:      ttl,
+		entries:  make(map[string]ttlCacheEntry),
+		inflight: make(map[string]*ttlCacheCall),
+	}
+}
+
+// get returns the cached value for key, calling fetch, which should use
+// ctx, on a miss. Lookups of a key already being fetched wait for that
+// fetch and share its result, until their own ctx is done, while other
+// keys are served meanwhile. If the fetch fails because its caller's ctx
+// is done, the waiting lookups fetch again. Only successful results are
+// cached, and expired entries are dropped when found.
+func (c *ttlCache) get(ctx context.Context, key string, fetch func() (interface{}, error)) (interface{}, error) {
+	for {
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok {
+			if time.Now().Before(entry.expires) {
+				c.mu.Unlock()
+				return entry.value, nil
+			}
+			delete(c.entries, key)
+		}
+		call, ok := c.inflight[key]
+		if !ok {
+			call = &ttlCacheCall{done: make(chan struct{})}
+			c.inflight[key] = call
+			c.mu.Unlock()
+			return c.run(ctx, key, call, fetch)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !call.canceled {
+			return call.value, call.err
+		}
+	}
+}
+
+// run calls fetch for key on behalf of call and the lookups waiting on it
+func (c *ttlCache) run(ctx context.Context, key string, call *ttlCacheCall, fetch func() (interface{}, error)) (interface{}, error) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil && c.ttl > 0 {
+			c.entries[key] = ttlCacheEntry{value: call.value, expires: time.Now().Add(c.ttl)}
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	call.err = errFetchPanicked
+	call.value, call.err = fetch()
+	call.canceled = call.err != nil && ctx.Err() != nil
+	return call.value, call.err
+}
@@ -0,0 +1,129 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTTLCache_SharesInflightFetch(t *testing.T) {
+	c := newTTLCache(time.Minute)
+	var fetches int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fetch := func() (interface{}, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.get(context.Background(), "key", fetch)
+			if err != nil || value != "value" {
+				t.Errorf("get = %v, %v", value, err)
+			}
+		}()
+	}
+	<-started
+
+	// Another key is served while the first fetch is still running
+	value, err := c.get(context.Background(), "other", func() (interface{}, error) { return "other", nil })
+	if err != nil || value != "other" {
+		t.Errorf("get other = %v, %v", value, err)
+	}
+
+	close(release)
+	wg.Wait()
+	assert(t, atomic.LoadInt32(&fetches), int32(1), "fetches of a shared key")
+	value, _ = c.get(context.Background(), "key", fetch)
+	assert(t, value, "value", "cached value")
+	assert(t, atomic.LoadInt32(&fetches), int32(1), "fetches after caching")
+}
+
+func TestTTLCache_DoesNotCacheErrors(t *testing.T) {
+	c := newTTLCache(time.Minute)
+	failed := errors.New("unavailable")
+	var fetches int32
+	fetch := func() (interface{}, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			return nil, failed
+		}
+		return "value", nil
+	}
+
+	if _, err := c.get(context.Background(), "key", fetch); !errors.Is(err, failed) {
+		t.Errorf("first get: err = %v, want %v", err, failed)
+	}
+	value, err := c.get(context.Background(), "key", fetch)
+	if err != nil {
+		t.Fatalf("second get failed: %v", err)
+	}
+	assert(t, value, "value", "value after a failed fetch")
+	assert(t, atomic.LoadInt32(&fetches), int32(2), "fetches")
+}
+
+func TestTTLCache_WaitersUseTheirOwnContext(t *testing.T) {
+	c := newTTLCache(time.Minute)
+	first, cancelFirst := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := c.get(first, "key", func() (interface{}, error) {
+			close(started)
+			<-first.Done()
+			return nil, first.Err()
+		})
+		fetched <- err
+	}()
+	<-started
+
+	// A waiter gives up when its own context is done
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := c.get(short, "key", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiter: err = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A waiter whose fetching caller is cancelled fetches again itself
+	result := make(chan interface{}, 1)
+	go func() {
+		value, err := c.get(context.Background(), "key", func() (interface{}, error) {
+			return "value", nil
+		})
+		if err != nil {
+			t.Errorf("waiter after cancellation failed: %v", err)
+		}
+		result <- value
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelFirst()
+	if err := <-fetched; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled fetch: err = %v", err)
+	}
+	assert(t, <-result, "value", "value fetched by the waiter")
+}
+
+func TestTTLCache_EvictsExpired(t *testing.T) {
+	c := newTTLCache(time.Millisecond)
+	fetch := func() (interface{}, error) { return "value", nil }
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := c.get(context.Background(), key, fetch); err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	c.get(context.Background(), "a", func() (interface{}, error) { return nil, errors.New("down") })
+	c.mu.Lock()
+	_, ok := c.entries["a"]
+	c.mu.Unlock()
+	assert(t, ok, false, "expired entry kept after a failed refetch")
+}
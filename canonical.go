@@ -0,0 +1,165 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrNotCanonical = errors.New("value cannot be represented canonically")
+
+// Canonicalize returns the resolved configuration in a normalized YAML form:
+// placeholders are replaced by their values, mapping keys are sorted and
+// indentation is fixed. Reading the result into a new profile yields the
+// same values for every path, so the output is suitable for hashing and
+// signing configs. Resolved values whose automatic type conversion would
+// change their text, such as 08540 or 1.10, are kept as strings, and every
+// ${ in keys and values is written as $${ so it is not resolved again.
+func (p *YamlProfile) Canonicalize() ([]byte, error) {
+	root, err := p.canonicalNode(p.root(), p.base)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, fmt.Errorf("encoding canonical form: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalNode converts a raw tree value into a resolved yaml.Node
+func (p *YamlProfile) canonicalNode(value interface{}, path string) (*yaml.Node, error) {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		// Directives only affect resolution, which is already applied, so
+		// resolveKeys leaves them out
+		names, err := p.resolveKeys(context.Background(), path, val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys := make([]string, 0, len(names))
+		for k := range names {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return names[keys[i]] < names[keys[j]]
+		})
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			child, err := p.canonicalNode(val[k], joinKey(path, k))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: escapePlaceholders(names[k])}, child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i, item := range val {
+			child, err := p.canonicalNode(item, joinPath(path, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case string:
+		if !p.hasPlaceholders(val) {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: escapePlaceholders(val)}, nil
+		}
+		value, err := p.resolveString(context.Background(), path, val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		resolved, ok := value.(string)
+		if !ok {
+			return p.canonicalFiltered(value, path)
+		}
+		resolved = escapePlaceholders(resolved)
+		if p.isPlaceholder(resolved) {
+			return nil, fmt.Errorf("%w: %s resolves to placeholder %q", ErrNotCanonical, path, resolved)
+		}
+		// Resolved placeholders are coerced on decode, so keep them plain
+		// when the plain scalar reads back as the same text
+		if plainRoundTrips(resolved) {
+			return &yaml.Node{Kind: yaml.ScalarNode, Value: resolved}, nil
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: resolved}, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(val); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return node, nil
+	}
+}
+
+// escapePlaceholders writes every ${ in s as $${, so reading s back gives
+// its text rather than resolving placeholders in it
+func escapePlaceholders(s string) string {
+	return strings.ReplaceAll(s, "${", "$${")
+}
+
+// plainRoundTrips reports whether s, written as a plain YAML scalar, is
+// read back as a value that prints as s again
+func plainRoundTrips(s string) bool {
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(s), &decoded); err != nil {
+		return false
+	}
+	switch decoded.(type) {
+	case int, float64, bool:
+		return fmt.Sprint(decoded) == s
+	case string:
+		return decoded == s
+	}
+	return false
+}
+
+// joinPath appends key to a dot-path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// canonicalFiltered builds the node for a filtered placeholder whose result is
+// a hinted or list value rather than plain text
+func (p *YamlProfile) canonicalFiltered(value interface{}, path string) (*yaml.Node, error) {
+	switch val := value.(type) {
+	case literalText:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: escapePlaceholders(string(val))}, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				item = literalText(s)
+			}
+			child, err := p.canonicalFiltered(item, path)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return node, nil
+}
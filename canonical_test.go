@@ -0,0 +1,157 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"gopkg.in/yaml.v3"
+)
+
+// randomTree is a quick.Generator producing config trees that mix plain
+// scalars with placeholders
+type randomTree map[string]interface{}
+
+var treeScalars = []interface{}{
+	"plain", "8080", "08540", "1.10", "true", "null", "", "a: b", "multi\nline", "${X}",
+	42, 3.5, false, nil,
+	"${CANON_PORT:8080}", "${CANON_FLAG:true}",
+	"${CANON_TEXT:hello world}", "${CANON_EMPTY}", "${CANON_SET:unused}",
+	"${CANON_EMBED}", "${CANON_WHOLE}", "at ${CANON_SET} and ${CANON_EMBED}",
+	"cost $${HOME}", "$${CANON_SET}", "$$${CANON_SET}", "a ${ b",
+}
+
+func (randomTree) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(randomTree(genMap(r, 3)))
+}
+
+func genMap(r *rand.Rand, depth int) map[string]interface{} {
+	m := make(map[string]interface{})
+	for i := 0; i < 1+r.Intn(4); i++ {
+		m[fmt.Sprintf("k%d", r.Intn(10))] = genValue(r, depth-1)
+	}
+	return m
+}
+
+func genValue(r *rand.Rand, depth int) interface{} {
+	if depth <= 0 {
+		return treeScalars[r.Intn(len(treeScalars))]
+	}
+	switch r.Intn(4) {
+	case 0:
+		return genMap(r, depth)
+	case 1:
+		list := make([]interface{}, r.Intn(3))
+		for i := range list {
+			list[i] = genValue(r, depth-1)
+		}
+		return list
+	}
+	return treeScalars[r.Intn(len(treeScalars))]
+}
+
+func TestYamlProfile_CanonicalizeProperties(t *testing.T) {
+	t.Setenv("CANON_SET", "from-env")
+	t.Setenv("CANON_EMBED", "a${HOME}b")
+	t.Setenv("CANON_WHOLE", "${HOME}")
+
+	roundTrip := func(tree randomTree) bool {
+		source, err := yaml.Marshal(map[string]interface{}(tree))
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		p := New(false)
+		if err := p.Read(source); err != nil {
+			t.Fatalf("failed to read generated yaml: %v", err)
+		}
+		canonical, err := p.Canonicalize()
+		if err != nil {
+			t.Logf("Canonicalize failed: %v\n%s", err, source)
+			return false
+		}
+
+		q := New(false)
+		if err := q.Read(canonical); err != nil {
+			t.Logf("failed to read canonical form: %v\n%s", err, canonical)
+			return false
+		}
+
+		// Every leaf path resolves to the same value
+		for _, key := range allKeys(p.data) {
+			switch node, _, _ := p.lookup(key); node.(type) {
+			case map[string]interface{}, []interface{}:
+				continue
+			}
+			if got, want := q.Get(key), p.Get(key); got != want {
+				t.Logf("%s: got %q, want %q\n%s", key, got, want, canonical)
+				return false
+			}
+		}
+
+		// Decoding yields the same tree
+		var want, got interface{}
+		if err := p.UnmarshalTo(&want); err != nil {
+			t.Logf("UnmarshalTo failed: %v", err)
+			return false
+		}
+		if err := q.UnmarshalTo(&got); err != nil {
+			t.Logf("UnmarshalTo canonical failed: %v", err)
+			return false
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("decoded %#v, want %#v\n%s", got, want, canonical)
+			return false
+		}
+
+		// The canonical form is a fixed point
+		again, err := q.Canonicalize()
+		if err != nil || !bytes.Equal(again, canonical) {
+			t.Logf("canonical form is not stable:\n%s\n---\n%s", canonical, again)
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestYamlProfile_CanonicalizeOrder(t *testing.T) {
+	a := New(false)
+	b := New(false)
+	if err := a.Read([]byte("b: 1\na:\n  y: ${CANON_Y:2}\n  x: three\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if err := b.Read([]byte("a:\n    x: three\n    y: 2\nb: 1\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	ca, err := a.Canonicalize()
+	if err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	cb, err := b.Canonicalize()
+	if err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	if !bytes.Equal(ca, cb) {
+		t.Errorf("canonical forms differ:\n%s\n---\n%s", ca, cb)
+	}
+	assert(t, string(ca), "a:\n  x: three\n  y: 2\nb: 1\n", "canonical form")
+}
+
+func TestYamlProfile_CanonicalizeKeepsText(t *testing.T) {
+	p := New(false)
+	if err := p.Read([]byte("zip: ${CANON_ZIP:08540}\nversion: ${CANON_VERSION:1.10}\nport: ${CANON_PORT:8080}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	canonical, err := p.Canonicalize()
+	if err != nil {
+		t.Fatalf("Canonicalize failed: %v", err)
+	}
+	assert(t, string(canonical), "port: 8080\nversion: \"1.10\"\nzip: \"08540\"\n", "canonical form")
+}
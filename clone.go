@@ -0,0 +1,63 @@
+package dollarYaml
+
+// Clone returns an independent copy of p: its tree, options, resolvers and
+// the values already generated for placeholders such as ${uuid}, so the
+// copy can be given overrides with Set or Merge and compared with the
+// original. OnChange hooks are not copied; hooks registered on either
+// profile only see its own changes.
+func (p *YamlProfile) Clone() *YamlProfile {
+	p.mu.RLock()
+	c := *p
+	p.mu.RUnlock()
+	c.mu = &profileLock{}
+	c.data = copyTree(c.data)
+	c.baseResolvers = cloneSlice(p.baseResolvers)
+	c.pathResolvers = make(map[string][]string, len(p.pathResolvers))
+	for k, v := range p.pathResolvers {
+		c.pathResolvers[k] = cloneSlice(v)
+	}
+	c.env = cloneMap(p.env)
+	c.dotenv = cloneMap(p.dotenv)
+	c.schemes = cloneMap(p.schemes)
+	c.tags = cloneMap(c.tags)
+	c.filters = cloneMap(p.filters)
+	c.envAllow = cloneSlice(p.envAllow)
+	c.envDeny = cloneSlice(p.envDeny)
+	c.sources = cloneSlice(c.sources)
+	c.layers = cloneSlice(c.layers)
+	c.envSources = cloneSlice(c.envSources)
+	c.seal = cloneMap(c.seal)
+	c.generated = c.generated.clone()
+	c.hooks = &changeHooks{}
+	return &c
+}
+
+// clone returns a cache holding the values generated so far
+func (c *valueCache) clone() *valueCache {
+	if c == nil {
+		return newValueCache()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &valueCache{values: cloneMap(c.values)}
+}
+
+// cloneMap copies m, keeping nil maps nil
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// cloneSlice copies s, keeping nil slices nil
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
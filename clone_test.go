@@ -0,0 +1,55 @@
+package dollarYaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_Clone(t *testing.T) {
+	yamlData := []byte(`
+server:
+  host: ${CLONE_HOST:localhost}
+  tags: [a, b]
+id: ${uuid}
+secret: !vault kv/app
+`)
+	p := NewProfile(WithStrict(true), WithPathResolvers("server", "env"))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	id := p.Get("id")
+	hooked := 0
+	p.OnChange(func(ChangeEvent) { hooked++ })
+
+	c := p.Clone()
+	assert(t, c.Get("id"), id, "generated values are kept")
+	assert(t, c.Get("server.host"), "localhost", "server.host")
+	if tag, _ := c.Tag("secret"); tag != "!vault" {
+		t.Errorf("tag = %q", tag)
+	}
+
+	if err := c.Set("server.tags.0", "z"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Set("server.port", 80); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	c.pathResolvers["server"][0] = "vault"
+	c.tags["id"] = "!changed"
+
+	assert(t, p.Get("server.tags.0"), "a", "original list unchanged")
+	assert(t, p.Has("server.port"), false, "original map unchanged")
+	if !reflect.DeepEqual(p.pathResolvers["server"], []string{"env"}) {
+		t.Errorf("original resolvers changed: %v", p.pathResolvers)
+	}
+	if _, ok := p.Tag("id"); ok {
+		t.Error("original tags changed")
+	}
+	assert(t, hooked, 0, "hooks are not copied")
+	assert(t, c.strict, true, "options are kept")
+
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, c.Get("id"), id, "clone keeps its values after the original reloads")
+}
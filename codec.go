@@ -0,0 +1,168 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Codec converts between a config format and the generic tree used by
+// YamlProfile. Placeholders in string values are resolved the same way
+// whatever the source format.
+type Codec interface {
+	Unmarshal(data []byte, out *map[string]interface{}) error
+	Marshal(data map[string]interface{}) ([]byte, error)
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byKey map[string]Codec
+}{byKey: make(map[string]Codec)}
+
+func init() {
+	RegisterCodec(yamlCodec{}, ".yaml", ".yml", "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml")
+	RegisterCodec(jsonCodec{}, ".json", "application/json", "text/json")
+}
+
+// RegisterCodec makes c available for the given file extensions (".hcl")
+// and MIME types ("application/hcl"), replacing any codec already
+// registered for them. It is safe to call from init functions.
+func RegisterCodec(c Codec, keys ...string) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	for _, key := range keys {
+		codecs.byKey[codecKey(key)] = c
+	}
+}
+
+// CodecFor returns the codec registered for an extension or MIME type
+func CodecFor(format string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.byKey[codecKey(format)]
+	return c, ok
+}
+
+// codecKey normalizes extensions to ".ext" and strips MIME parameters
+func codecKey(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if strings.Contains(format, "/") {
+		if mediaType, _, err := mime.ParseMediaType(format); err == nil {
+			return mediaType
+		}
+		return format
+	}
+	if !strings.HasPrefix(format, ".") {
+		format = "." + format
+	}
+	return format
+}
+
+// ReadAs loads data encoded in format, an extension or MIME type with a
+// registered codec
+func (p *YamlProfile) ReadAs(data []byte, format string) error {
+	result, tags, err := p.parseAs(data, format)
+	if err != nil {
+		return err
+	}
+	p.load(data, result, tags)
+	return nil
+}
+
+// parseAs decodes data with the codec registered for format, as ReadAs
+// does, and returns the tree and its custom tags
+func (p *YamlProfile) parseAs(data []byte, format string) (interface{}, map[string]string, error) {
+	c, ok := CodecFor(format)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	if _, ok := c.(yamlCodec); ok {
+		return p.parseYAML(data)
+	}
+
+	var result map[string]interface{}
+	if err := c.Unmarshal(data, &result); err != nil {
+		return nil, nil, err
+	}
+	return result, make(map[string]string), nil
+}
+
+// ReadJSON loads JSON data. Placeholders in string values are resolved as
+// in YAML, and integers keep their precision.
+func (p *YamlProfile) ReadJSON(data []byte) error {
+	return p.ReadAs(data, ".json")
+}
+
+// yamlCodec is the built-in YAML codec
+type yamlCodec struct{}
+
+func (yamlCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	return yaml.Unmarshal(data, out)
+}
+
+func (yamlCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	return yaml.Marshal(data)
+}
+
+// jsonCodec is the built-in JSON codec. Numbers decode as int when they
+// are integers that fit, as YAML decodes them, and as float64 otherwise.
+type jsonCodec struct{}
+
+func (jsonCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the JSON document")
+	}
+	if m != nil {
+		*out = jsonNumbers(m).(map[string]interface{})
+	}
+	return nil
+}
+
+func (jsonCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonNumbers replaces the json.Number values in v with ints or float64s
+func jsonNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = jsonNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = jsonNumbers(item)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(val), 10, 0); err == nil {
+			return int(i)
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return string(val)
+	}
+	return v
+}
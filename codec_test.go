@@ -0,0 +1,154 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// lineCodec is a toy key=value format used to exercise the registry
+type lineCodec struct{}
+
+func (lineCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	m := make(map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return errors.New("missing =")
+		}
+		m[key] = value
+	}
+	*out = m
+	return nil
+}
+
+func (lineCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	var lines []string
+	for k, v := range data {
+		lines = append(lines, k+"="+v.(string))
+	}
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+func TestRegisterCodec(t *testing.T) {
+	RegisterCodec(lineCodec{}, "kv", "Text/X-KV")
+	os.Setenv("CODEC_HOST", "example.com")
+	defer os.Unsetenv("CODEC_HOST")
+
+	path := filepath.Join(t.TempDir(), "app.kv")
+	if err := os.WriteFile(path, []byte("host=${CODEC_HOST}\nport=${CODEC_PORT:8080}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	p := New(false)
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	assert(t, p.Get("host"), "example.com", "host from .kv file")
+	assert(t, p.Get("port"), "8080", "port from .kv file")
+
+	if err := p.ReadAs([]byte("name=mime\n"), "text/x-kv; charset=utf-8"); err != nil {
+		t.Fatalf("ReadAs failed: %v", err)
+	}
+	assert(t, p.Get("name"), "mime", "name read by MIME type")
+
+	c, ok := CodecFor(".KV")
+	if !ok {
+		t.Fatal("expected codec for .KV")
+	}
+	out, err := c.Marshal(map[string]interface{}{"b": "2", "a": "1"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	assert(t, string(out), "a=1\nb=2\n", "marshaled output")
+}
+
+func TestYamlProfile_ReadAs(t *testing.T) {
+	p := New(false)
+	if err := p.ReadAs([]byte("a:\n  b: !secret c\n"), "application/yaml"); err != nil {
+		t.Fatalf("ReadAs failed: %v", err)
+	}
+	assert(t, p.Get("a.b"), "c", "yaml by MIME type")
+	if tag, _ := p.Tag("a.b"); tag != "!secret" {
+		t.Errorf("expected tags kept for yaml, got %q", tag)
+	}
+
+	if err := p.ReadAs([]byte("x"), ".unknown"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestYamlProfile_ReadJSON(t *testing.T) {
+	t.Setenv("CODEC_DB_HOST", "db.local")
+
+	data := []byte(`{
+	"database": {
+		"host": "${CODEC_DB_HOST:localhost}",
+		"port": 5432,
+		"id": 9007199254740993,
+		"ratio": 0.25
+	},
+	"servers": [{"name": "a"}, {"name": "${CODEC_SERVER:b}"}],
+	"debug": true,
+	"note": null
+}`)
+	p := NewProfile()
+	if err := p.ReadJSON(data); err != nil {
+		t.Fatalf("ReadJSON failed: %v", err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"database.host", "db.local"},
+		{"database.port", "5432"},
+		{"database.id", "9007199254740993"},
+		{"database.ratio", "0.25"},
+		{"servers.1.name", "b"},
+		{"debug", "true"},
+	}
+	for _, tt := range tests {
+		assert(t, p.Get(tt.path), tt.want, tt.path)
+	}
+
+	var cfg struct {
+		Database struct {
+			Port int   `yaml:"port"`
+			ID   int64 `yaml:"id"`
+		} `yaml:"database"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, cfg.Database.Port, 5432, "decoded port")
+	assert(t, cfg.Database.ID, int64(9007199254740993), "decoded id")
+
+	path := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	fromFile := NewProfile()
+	if err := fromFile.ReadFromPath(path); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	assert(t, fromFile.Get("database.host"), "db.local", "host from .json file")
+
+	out, err := p.MarshalAs("application/json")
+	if err != nil {
+		t.Fatalf("MarshalAs failed: %v", err)
+	}
+	back := NewProfile()
+	if err := back.ReadJSON(out); err != nil {
+		t.Fatalf("failed to read marshaled JSON: %v", err)
+	}
+	assert(t, back.Get("servers.1.name"), "b", "round trip")
+
+	for _, bad := range []string{`{"a": 1} {"b": 2}`, `[1, 2]`, `{"a": `} {
+		if err := NewProfile().ReadJSON([]byte(bad)); err == nil {
+			t.Errorf("expected an error reading %s", bad)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsulConfig configures the ${consul:key} resolver
+type ConsulConfig struct {
+	// Address of the Consul agent; defaults to CONSUL_HTTP_ADDR or
+	// 127.0.0.1:8500. An address without a scheme uses https when
+	// CONSUL_HTTP_SSL is true.
+	Address string
+	// Token is sent as X-Consul-Token; defaults to CONSUL_HTTP_TOKEN or
+	// the contents of CONSUL_HTTP_TOKEN_FILE
+	Token string
+	// Datacenter and Namespace scope the lookups; Namespace defaults to
+	// CONSUL_NAMESPACE
+	Datacenter string
+	Namespace  string
+	// Timeout bounds each request to Consul; defaults to 10 seconds
+	Timeout time.Duration
+	// CacheTTL is how long a fetched value is reused; defaults to 5
+	// minutes, and a negative value disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the client used to reach Consul
+	HTTPClient *http.Client
+}
+
+// consulResolver reads values from Consul KV
+type consulResolver struct {
+	cfg   ConsulConfig
+	cache *ttlCache
+}
+
+func newConsulResolver(cfg ConsulConfig) *consulResolver {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if cfg.Address == "" {
+		cfg.Address = "127.0.0.1:8500"
+	}
+	if !strings.Contains(cfg.Address, "://") {
+		scheme := "http://"
+		if strings.EqualFold(os.Getenv("CONSUL_HTTP_SSL"), "true") {
+			scheme = "https://"
+		}
+		cfg.Address = scheme + cfg.Address
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	if cfg.Token == "" {
+		if file := os.Getenv("CONSUL_HTTP_TOKEN_FILE"); file != "" {
+			if data, err := os.ReadFile(file); err == nil {
+				cfg.Token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("CONSUL_NAMESPACE")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &consulResolver{cfg: cfg, cache: newTTLCache(cfg.CacheTTL)}
+}
+
+// resolve returns the raw value stored at key
+func (c *consulResolver) resolve(ctx context.Context, key string) (string, error) {
+	key = strings.Trim(key, "/")
+	value, err := c.cache.get(ctx, key, func() (interface{}, error) {
+		return c.fetch(ctx, key)
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+func (c *consulResolver) fetch(ctx context.Context, key string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	resp, body, err := c.get(ctx, key, url.Values{"raw": {""}})
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return string(body), nil
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: consul key %s", ErrValueNotFound, key)
+	}
+	return "", fmt.Errorf("consul %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// get requests the KV entry or prefix at key with query, scoped to the
+// configured datacenter and namespace, and returns the response with
+// its body read
+func (c *consulResolver) get(ctx context.Context, key string, query url.Values) (*http.Response, []byte, error) {
+	if c.cfg.Datacenter != "" {
+		query.Set("dc", c.cfg.Datacenter)
+	}
+	if c.cfg.Namespace != "" {
+		query.Set("ns", c.cfg.Namespace)
+	}
+	endpoint := c.cfg.Address + "/v1/kv/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", c.cfg.Token)
+	}
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// consulWatchWait is how long a blocking query waits for a change before
+// Consul answers with the unchanged index
+var consulWatchWait = 5 * time.Minute
+
+// consulSource loads a config, or a tree of keys, from Consul KV
+type consulSource struct {
+	c   *consulResolver
+	key string
+
+	mu    sync.Mutex
+	index uint64
+}
+
+// ConsulSource returns a Source loading key from Consul KV. A key ending
+// in / is read recursively as a prefix: every key below it becomes a
+// path, split at /, whose value is typed as in YAML, so
+// config/app/database/port under the prefix config/app/ is read as
+// database.port. Any other key holds a whole config, decoded with the
+// codec registered for its extension and as YAML by default. The source
+// is a WatchableSource whose Watch is a Consul blocking query, so
+// Builder.Watch reloads the profile as soon as the key or prefix changes.
+func ConsulSource(cfg ConsulConfig, key string) Source {
+	return &consulSource{c: newConsulResolver(cfg), key: strings.TrimPrefix(key, "/")}
+}
+
+func (s *consulSource) Name() string { return "consul:" + s.key }
+
+// query returns the query parameters selecting the source
+func (s *consulSource) query() url.Values {
+	if strings.HasSuffix(s.key, "/") {
+		return url.Values{"recurse": {""}}
+	}
+	return url.Values{"raw": {""}}
+}
+
+func (s *consulSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.c.cfg.Timeout)
+	defer cancel()
+
+	resp, body, err := s.c.get(ctx, s.key, s.query())
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.HasSuffix(s.key, "/")
+	var tree map[string]interface{}
+	switch {
+	case resp.StatusCode == http.StatusNotFound && prefix:
+		tree = map[string]interface{}{}
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: consul key %s", ErrValueNotFound, s.key)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("consul %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	case prefix:
+		if tree, err = consulTree(s.key, body); err != nil {
+			return nil, err
+		}
+	default:
+		if tree, err = decodeSource(body, pathpkg.Ext(s.key)); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.index, _ = strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	s.mu.Unlock()
+	return tree, nil
+}
+
+// consulTree builds a tree from the entries of a recursive KV listing
+func consulTree(prefix string, body []byte) (map[string]interface{}, error) {
+	var entries []struct {
+		Key   string
+		Value []byte
+	}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("decoding consul keys under %s: %w", prefix, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	tree := map[string]interface{}{}
+	for _, entry := range entries {
+		keys := strings.Split(strings.Trim(strings.TrimPrefix(entry.Key, prefix), "/"), "/")
+		// Folders are entries ending in / with no value
+		if keys[0] == "" || strings.HasSuffix(entry.Key, "/") && entry.Value == nil {
+			continue
+		}
+		if err := setLeaf(tree, keys, typedText(string(entry.Value))); err != nil {
+			return nil, fmt.Errorf("consul key %s: %w", entry.Key, err)
+		}
+	}
+	return tree, nil
+}
+
+// Watch runs blocking queries from the index of the last Load until
+// Consul reports a new one
+func (s *consulSource) Watch(ctx context.Context) error {
+	s.mu.Lock()
+	index := s.index
+	s.mu.Unlock()
+	for {
+		query := s.query()
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWatchWait.String())
+		resp, body, err := s.c.get(ctx, s.key, query)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("consul %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+		// Consul answers with the same index when the wait times out, and
+		// a lower one after a reset, which also calls for a reload
+		if next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64); next != index {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newConsulServer fakes the Consul KV endpoint
+func newConsulServer(t *testing.T, reads *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "acl" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if _, ok := r.URL.Query()["raw"]; !ok || r.URL.Query().Get("dc") != "dc2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(reads, 1)
+		switch r.URL.Path {
+		case "/v1/kv/config/app/db_host":
+			w.Write([]byte("db.internal"))
+		case "/v1/kv/config/app/port":
+			w.Write([]byte("5432"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestYamlProfile_ConsulScheme(t *testing.T) {
+	var reads int32
+	server := newConsulServer(t, &reads)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("acl\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	t.Setenv("CONSUL_HTTP_ADDR", server.URL)
+	t.Setenv("CONSUL_HTTP_TOKEN", "")
+	t.Setenv("CONSUL_HTTP_TOKEN_FILE", tokenFile)
+
+	p := New(false, WithConsul(ConsulConfig{Datacenter: "dc2"}))
+	yamlData := []byte(`
+db:
+  host: ${consul:config/app/db_host}
+  port: ${consul:config/app/port}
+  user: ${consul:config/app/db_user:admin}
+`)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"db.host", "db.internal"},
+		{"db.port", "5432"},
+		{"db.user", "admin"},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+
+	var cfg struct {
+		DB struct {
+			Port int `yaml:"port"`
+		} `yaml:"db"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, cfg.DB.Port, 5432, "decoded port")
+
+	before := atomic.LoadInt32(&reads)
+	p.Get("db.host")
+	if after := atomic.LoadInt32(&reads); after != before {
+		t.Errorf("expected cached lookup, got %d extra reads", after-before)
+	}
+}
+
+func TestYamlProfile_ConsulSchemeStrict(t *testing.T) {
+	var reads int32
+	server := newConsulServer(t, &reads)
+	defer server.Close()
+
+	p := New(false, WithStrict(true), WithConsul(ConsulConfig{Address: server.URL, Token: "acl", Datacenter: "dc2"}))
+	if err := p.Read([]byte("a: ${consul:config/missing}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := p.GetError("a"); err == nil {
+		t.Error("expected error for missing key in strict mode")
+	}
+}
+
+// consulStore fakes Consul KV with blocking queries over an in-memory
+// key space
+type consulStore struct {
+	mu      sync.Mutex
+	kvs     map[string]string
+	index   uint64
+	changed chan struct{}
+}
+
+func (s *consulStore) put(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kvs[key] = value
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *consulStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	query := r.URL.Query()
+	if wait, err := time.ParseDuration(query.Get("wait")); err == nil {
+		index, _ := strconv.ParseUint(query.Get("index"), 10, 64)
+		timeout := time.After(wait)
+	block:
+		for {
+			s.mu.Lock()
+			current, changed := s.index, s.changed
+			s.mu.Unlock()
+			if current != index {
+				break
+			}
+			select {
+			case <-changed:
+			case <-timeout:
+				break block
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(s.index, 10))
+	if _, recurse := query["recurse"]; recurse {
+		var entries []map[string]interface{}
+		for k, v := range s.kvs {
+			if strings.HasPrefix(k, key) {
+				entries = append(entries, map[string]interface{}{"Key": k, "Value": []byte(v)})
+			}
+		}
+		if len(entries) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+	value, ok := s.kvs[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write([]byte(value))
+}
+
+func TestConsulSource(t *testing.T) {
+	store := &consulStore{kvs: map[string]string{}, changed: make(chan struct{})}
+	store.put("config/app.json", `{"database": {"host": "db1"}}`)
+	store.put("svc/", "")
+	store.put("svc/database/port", "6543")
+	store.put("svc/name", "api")
+	server := httptest.NewServer(store)
+	defer server.Close()
+	cfg := ConsulConfig{Address: server.URL}
+
+	t.Run("prefix", func(t *testing.T) {
+		p, err := NewBuilder().Add(ConsulSource(cfg, "svc/")).Build(context.Background())
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		assert(t, p.Get("name"), "api", "name")
+		port, err := p.GetInt("database.port")
+		if err != nil {
+			t.Fatalf("GetInt failed: %v", err)
+		}
+		assert(t, port, 6543, "typed port")
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		defer func(wait time.Duration) { consulWatchWait = wait }(consulWatchWait)
+		consulWatchWait = 20 * time.Millisecond
+
+		b := NewBuilder().Add(ConsulSource(cfg, "config/app.json"))
+		p, err := b.Build(context.Background())
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		assert(t, p.Get("database.host"), "db1", "host")
+
+		reloaded := make(chan ChangeEvent, 1)
+		p.OnChange(func(ev ChangeEvent) { reloaded <- ev })
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- b.Watch(ctx, p) }()
+
+		// Let a few blocking queries time out unchanged first
+		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-reloaded:
+			t.Fatal("reloaded without a change")
+		default:
+		}
+		store.put("config/app.json", `{"database": {"host": "db2"}}`)
+		select {
+		case ev := <-reloaded:
+			assert(t, ev.Reloaded, true, "Reloaded")
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload after the key changed")
+		}
+		assert(t, p.Get("database.host"), "db2", "host after reload")
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected Watch to stop with context.Canceled, got %v", err)
+		}
+	})
+
+	if _, err := NewBuilder().Add(ConsulSource(cfg, "missing.yaml")).Build(context.Background()); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+}
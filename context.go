@@ -0,0 +1,16 @@
+package dollarYaml
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the configuration cfg
+func NewContext(ctx context.Context, cfg ConfigReader) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the configuration stored in ctx by NewContext
+func FromContext(ctx context.Context) (ConfigReader, bool) {
+	cfg, ok := ctx.Value(contextKey{}).(ConfigReader)
+	return cfg, ok
+}
@@ -0,0 +1,36 @@
+package dollarYaml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	p := New(false)
+	if err := p.Read([]byte("app:\n  name: ${CTX_APP_NAME:demo}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no config in empty context")
+	}
+
+	middleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+		})
+	}
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg, ok := FromContext(r.Context())
+		if !ok {
+			t.Fatal("expected config in request context")
+		}
+		w.Write([]byte(cfg.Get("app.name")))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert(t, rec.Body.String(), "demo", "app.name from context")
+}
@@ -0,0 +1,330 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnmappedKeys = errors.New("config keys do not map to any field")
+
+// UnmappedKey is a config key that no field of the target struct decodes
+type UnmappedKey struct {
+	// Path is the dot-path of the key in the config
+	Path string
+	// Field names the struct field the key most likely meant, if any
+	Field string
+	// Key is the YAML key Field actually expects
+	Key string
+}
+
+func (k UnmappedKey) String() string {
+	if k.Field == "" {
+		return k.Path + ": no matching field"
+	}
+	return fmt.Sprintf("%s: no matching field (did you mean %q for field %s?)", k.Path, k.Key, k.Field)
+}
+
+// DecodeReport lists the config keys that were dropped while decoding
+type DecodeReport struct {
+	Unmapped []UnmappedKey
+}
+
+// OK reports whether every key was decoded into a field
+func (r *DecodeReport) OK() bool {
+	return len(r.Unmapped) == 0
+}
+
+func (r *DecodeReport) String() string {
+	lines := make([]string, len(r.Unmapped))
+	for i, k := range r.Unmapped {
+		lines[i] = k.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DecodeError is returned by UnmarshalTo under WithKnownFields when keys
+// would be dropped. It wraps ErrUnmappedKeys.
+type DecodeError struct {
+	Report *DecodeReport
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v:\n%s", ErrUnmappedKeys, e.Report)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return ErrUnmappedKeys
+}
+
+// UnmarshalToReport decodes like UnmarshalTo and also reports the config
+// keys that map to no field of target, for example because a yaml tag is
+// missing or differs in case from the key
+func (p *YamlProfile) UnmarshalToReport(target interface{}) (*DecodeReport, error) {
+	return p.unmarshalReport(context.Background(), target)
+}
+
+func (p *YamlProfile) unmarshalReport(ctx context.Context, target interface{}) (*DecodeReport, error) {
+	processed, err := p.processValue(ctx, p.base, p.root())
+	if err != nil {
+		return nil, fmt.Errorf("processing environment variables: %w", err)
+	}
+	processed = p.coerceFor(processed, reflect.TypeOf(target))
+	if err := p.decode(processed, target); err != nil {
+		return nil, err
+	}
+	return unmappedReport(processed, reflect.TypeOf(target), ""), nil
+}
+
+// UnmarshalKey decodes just the value at path into target, such as the
+// database.master section into a server struct, so each module can bind
+// its own part of the config. Placeholders resolve as with UnmarshalTo,
+// and WithKnownFields applies to the keys below path.
+func (p *YamlProfile) UnmarshalKey(path string, target interface{}) error {
+	node, err := p.resolvedNode(path)
+	if err != nil {
+		return err
+	}
+	processed := p.coerceFor(node, reflect.TypeOf(target))
+	if err := p.decode(processed, target); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if p.knownFields {
+		if report := unmappedReport(processed, reflect.TypeOf(target), path); !report.OK() {
+			return &DecodeError{Report: report}
+		}
+	}
+	return nil
+}
+
+// unmappedReport reports the keys of processed, the tree at path, that no
+// field of typ decodes
+func unmappedReport(processed interface{}, typ reflect.Type, path string) *DecodeReport {
+	report := &DecodeReport{}
+	collectUnmapped(processed, typ, path, report)
+	sort.Slice(report.Unmapped, func(i, j int) bool {
+		return report.Unmapped[i].Path < report.Unmapped[j].Path
+	})
+	return report
+}
+
+// decode marshals the processed tree and decodes it into target
+func (p *YamlProfile) decode(processed interface{}, target interface{}) error {
+	p.debugf("Processed config before marshal: %#v\n", processed)
+
+	// Convert processed map to YAML bytes
+	data, err := yaml.Marshal(processed)
+	if err != nil {
+		return fmt.Errorf("marshaling processed config: %w", err)
+	}
+
+	p.debugf("Marshaled YAML:\n%s\n", string(data))
+
+	// Unmarshal into target struct
+	if err := yaml.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("unmarshaling to target: %w", err)
+	}
+	return nil
+}
+
+// resolvedText is the text of a resolved placeholder before coercion
+type resolvedText string
+
+// plainScalar encodes as an untagged plain scalar, leaving yaml to type
+// the text for the field it decodes into
+type plainScalar string
+
+func (s plainScalar) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(s)}, nil
+}
+
+// coerceFor replaces the resolvedText leaves of a processed tree, walking
+// it alongside the target type. Placeholders decoding into string fields
+// keep their exact text, so values like 08540 or True survive; all other
+// placeholders are coerced to ints, floats and bools as before, or with
+// WithTypeCoercion(false) kept as strings for untyped targets and left to
+// yaml for typed ones. A nil typ, as for yaml.Unmarshaler targets, counts
+// as typed.
+func (p *YamlProfile) coerceFor(value interface{}, typ reflect.Type) interface{} {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ != nil && (typ.Implements(yamlUnmarshalerType) || reflect.PtrTo(typ).Implements(yamlUnmarshalerType)) {
+		typ = nil
+	}
+
+	switch val := value.(type) {
+	case resolvedText:
+		if typ != nil && typ.Kind() == reflect.String {
+			return string(val)
+		}
+		if p.noCoercion {
+			if typ != nil && typ.Kind() == reflect.Interface {
+				return string(val)
+			}
+			return plainScalar(val)
+		}
+		return p.coerce(string(val))
+	case map[string]interface{}:
+		var fields map[string]structField
+		var elem reflect.Type
+		if typ != nil {
+			switch typ.Kind() {
+			case reflect.Struct:
+				fields = make(map[string]structField)
+				var inlineMap bool
+				collectFields(typ, fields, &inlineMap)
+			case reflect.Map:
+				elem = typ.Elem()
+			}
+		}
+		for k, item := range val {
+			itemType := elem
+			if f, ok := fields[k]; ok {
+				itemType = f.typ
+			}
+			val[k] = p.coerceFor(item, itemType)
+		}
+		return val
+	case []interface{}:
+		var elem reflect.Type
+		if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+			elem = typ.Elem()
+		}
+		for i, item := range val {
+			val[i] = p.coerceFor(item, elem)
+		}
+		return val
+	}
+	return value
+}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// collectUnmapped walks value alongside typ, recording map keys that yaml
+// decoding would drop
+func collectUnmapped(value interface{}, typ reflect.Type, path string, report *DecodeReport) {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		if typ.Implements(yamlUnmarshalerType) {
+			return
+		}
+		typ = typ.Elem()
+	}
+	if typ == nil || reflect.PtrTo(typ).Implements(yamlUnmarshalerType) {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := make(map[string]structField)
+		var inlineMap bool
+		collectFields(typ, fields, &inlineMap)
+		for key, item := range m {
+			itemPath := joinKey(path, key)
+			if f, ok := fields[key]; ok {
+				collectUnmapped(item, f.typ, itemPath, report)
+				continue
+			}
+			if !inlineMap {
+				report.Unmapped = append(report.Unmapped, suggestField(itemPath, key, fields))
+			}
+		}
+	case reflect.Map:
+		if m, ok := value.(map[string]interface{}); ok {
+			for key, item := range m {
+				collectUnmapped(item, typ.Elem(), joinKey(path, key), report)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if list, ok := value.([]interface{}); ok {
+			for i, item := range list {
+				collectUnmapped(item, typ.Elem(), joinPath(path, strconv.Itoa(i)), report)
+			}
+		}
+	}
+}
+
+// structField is a struct field as yaml.v3 sees it
+type structField struct {
+	name string
+	typ  reflect.Type
+}
+
+// collectFields records the YAML keys typ decodes, following yaml.v3's
+// rules: the tag name or else the lowercased field name, with ",inline"
+// structs flattened in. inlineMap is set when an inline map catches keys
+// without a field.
+func collectFields(typ reflect.Type, fields map[string]structField, inlineMap *bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "" && !strings.Contains(string(f.Tag), ":") {
+			tag = string(f.Tag)
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+opts+",", ",inline,") {
+			switch ft := indirectType(f.Type); ft.Kind() {
+			case reflect.Struct:
+				collectFields(ft, fields, inlineMap)
+			case reflect.Map:
+				*inlineMap = true
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = structField{name: f.Name, typ: f.Type}
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// suggestField finds the field an unmapped key most likely meant, first
+// by case-insensitive match, then by edit distance
+func suggestField(path, key string, fields map[string]structField) UnmappedKey {
+	unmapped := UnmappedKey{Path: path}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	normalize := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	}
+	for _, k := range keys {
+		if normalize(k) == normalize(key) || normalize(fields[k].name) == normalize(key) {
+			unmapped.Field, unmapped.Key = fields[k].name, k
+			return unmapped
+		}
+	}
+	if k := suggestPath(key, keys); k != "" {
+		unmapped.Field, unmapped.Key = fields[k].name, k
+	}
+	return unmapped
+}
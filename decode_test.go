@@ -0,0 +1,290 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type decodeDB struct {
+	DBHost  string        `yaml:"dbhost"`
+	Port    int           `yaml:"port"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type decodeBase struct {
+	Name string `yaml:"name"`
+}
+
+type decodeConfig struct {
+	decodeBase `yaml:",inline"`
+	MaxConns   int                 `yaml:"maxConns"`
+	Replicas   []decodeDB          `yaml:"replicas"`
+	DB         decodeDB            `yaml:"db"`
+	Labels     map[string]string   `yaml:"labels"`
+	Pools      map[string]decodeDB `yaml:"pools"`
+	Extra      interface{}         `yaml:"extra"`
+	LogLevel   string
+}
+
+func TestYamlProfile_UnmarshalToReport(t *testing.T) {
+	os.Setenv("DECODE_HOST", "db.internal")
+	defer os.Unsetenv("DECODE_HOST")
+
+	yamlData := []byte(`
+name: app
+maxconns: 10
+log_level: debug
+db:
+  dbHost: ${DECODE_HOST}
+  port: 5432
+  timout: 5s
+replicas:
+  - dbhost: r1
+    prot: 5433
+pools:
+  main:
+    dbhost: p1
+    unknown: true
+labels:
+  anything: goes
+extra:
+  free: form
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var cfg decodeConfig
+	report, err := p.UnmarshalToReport(&cfg)
+	if err != nil {
+		t.Fatalf("UnmarshalToReport failed: %v", err)
+	}
+	assert(t, cfg.Name, "app", "inline field decoded")
+	assert(t, cfg.DB.Port, 5432, "mapped field decoded")
+
+	want := []UnmappedKey{
+		{Path: "db.dbHost", Field: "DBHost", Key: "dbhost"},
+		{Path: "db.timout", Field: "Timeout", Key: "timeout"},
+		{Path: "log_level", Field: "LogLevel", Key: "loglevel"},
+		{Path: "maxconns", Field: "MaxConns", Key: "maxConns"},
+		{Path: "pools.main.unknown"},
+		{Path: "replicas.0.prot", Field: "Port", Key: "port"},
+	}
+	if !reflect.DeepEqual(report.Unmapped, want) {
+		t.Errorf("got unmapped keys\n%s\nwant\n%s", report, &DecodeReport{Unmapped: want})
+	}
+	if report.OK() {
+		t.Error("expected report not to be OK")
+	}
+}
+
+func TestYamlProfile_WithKnownFields(t *testing.T) {
+	p := New(false, WithKnownFields(true))
+	if err := p.Read([]byte("db:\n  dbhost: a\n  Port: 1\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	var cfg struct {
+		DB decodeDB `yaml:"db"`
+	}
+	err := p.UnmarshalTo(&cfg)
+	var decodeErr *DecodeError
+	if !errors.Is(err, ErrUnmappedKeys) || !errors.As(err, &decodeErr) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	assert(t, decodeErr.Report.Unmapped[0].Key, "port", "suggested key")
+
+	if err := p.Read([]byte("db:\n  dbhost: a\n  port: 1\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Errorf("expected no error for fully mapped config, got %v", err)
+	}
+}
+
+type cacheConfig struct {
+	Size    int               `yaml:"size"`
+	Path    string            `yaml:"path"`
+	Zip     string            `yaml:"zip"`
+	Tags    []string          `yaml:"tags"`
+	Extra   map[string]string `yaml:"extra"`
+	Enabled bool              `yaml:"enabled"`
+}
+
+func TestYamlProfile_UnmarshalMapOfStructs(t *testing.T) {
+	os.Setenv("MAPS_SIZE", "64")
+	os.Setenv("MAPS_ZIP", "08540")
+	defer os.Unsetenv("MAPS_SIZE")
+	defer os.Unsetenv("MAPS_ZIP")
+
+	base := []byte(`
+cache:
+  memory:
+    size: ${MAPS_SIZE}
+    zip: ${MAPS_ZIP}
+    tags: ["${MAPS_SIZE}", b]
+    extra: {zip: "${MAPS_ZIP}", flag: "${MAPS_FLAG:True}"}
+    enabled: ${MAPS_FLAG:true}
+  disk:
+    path: ${MAPS_PATH:/var/cache}
+    size: 1
+  1:
+    size: ${MAPS_SIZE}
+pointers:
+  a: {size: "${MAPS_SIZE}"}
+lists:
+  a: [{size: "${MAPS_SIZE}"}]
+nested:
+  x: {y: {size: "${MAPS_SIZE}"}}
+`)
+	overlay := []byte(`
+cache:
+  disk:
+    size: ${MAPS_SIZE}
+  redis:
+    path: ${MAPS_REDIS:redis://localhost}
+    size: ${MAPS_SIZE}
+`)
+	p, err := NewBuilder().
+		Add(BytesSource("base", base, "yaml")).
+		Add(BytesSource("overlay", overlay, "yaml")).
+		Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var cfg struct {
+		Cache    map[string]cacheConfig            `yaml:"cache"`
+		Pointers map[string]*cacheConfig           `yaml:"pointers"`
+		Lists    map[string][]cacheConfig          `yaml:"lists"`
+		Nested   map[string]map[string]cacheConfig `yaml:"nested"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+
+	want := map[string]cacheConfig{
+		"memory": {
+			Size: 64, Zip: "08540", Tags: []string{"64", "b"},
+			Extra: map[string]string{"zip": "08540", "flag": "True"}, Enabled: true,
+		},
+		"disk":  {Path: "/var/cache", Size: 64},
+		"1":     {Size: 64},
+		"redis": {Path: "redis://localhost", Size: 64},
+	}
+	if !reflect.DeepEqual(cfg.Cache, want) {
+		t.Errorf("got cache %+v\nwant %+v", cfg.Cache, want)
+	}
+	assert(t, cfg.Pointers["a"].Size, 64, "map of pointers")
+	assert(t, cfg.Lists["a"][0].Size, 64, "map of lists")
+	assert(t, cfg.Nested["x"]["y"].Size, 64, "map of maps")
+	assert(t, p.Get("cache.1.size"), "64", "Get under a non-string key")
+
+	// Untyped targets still receive coerced values
+	var untyped map[string]interface{}
+	if err := p.UnmarshalTo(&untyped); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	memory := untyped["cache"].(map[string]interface{})["memory"].(map[string]interface{})
+	assert(t, memory["zip"], 8540, "untyped zip is coerced")
+}
+
+func TestYamlProfile_WithTypeCoercion(t *testing.T) {
+	os.Setenv("COERCE_ZIP", "08540")
+	os.Setenv("COERCE_PORT", "0x1F90")
+	os.Setenv("COERCE_DEBUG", "true")
+	defer os.Unsetenv("COERCE_ZIP")
+	defer os.Unsetenv("COERCE_PORT")
+	defer os.Unsetenv("COERCE_DEBUG")
+
+	yamlData := []byte(`
+zip: ${COERCE_ZIP}
+port: ${COERCE_PORT}
+debug: ${COERCE_DEBUG}
+retries: ${COERCE_RETRIES:3|int}
+`)
+	p := New(false, WithTypeCoercion(false))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var untyped map[string]interface{}
+	if err := p.UnmarshalTo(&untyped); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	want := map[string]interface{}{"zip": "08540", "port": "0x1F90", "debug": "true", "retries": 3}
+	if !reflect.DeepEqual(untyped, want) {
+		t.Errorf("got %#v, want %#v", untyped, want)
+	}
+
+	var typed struct {
+		Zip     string `yaml:"zip"`
+		Port    int    `yaml:"port"`
+		Debug   bool   `yaml:"debug"`
+		Retries int    `yaml:"retries"`
+	}
+	if err := p.UnmarshalTo(&typed); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, typed.Zip, "08540", "zip")
+	assert(t, typed.Port, 8080, "port")
+	assert(t, typed.Debug, true, "debug")
+	assert(t, typed.Retries, 3, "retries")
+}
+
+func TestYamlProfile_UnmarshalKey(t *testing.T) {
+	t.Setenv("KEY_MASTER_HOST", "master.local")
+
+	yamlData := []byte(`
+database:
+  master:
+    dbhost: ${KEY_MASTER_HOST:localhost}
+    port: ${KEY_MASTER_PORT:5432}
+    timeout: 5s
+  replicas:
+    - {dbhost: r1, port: 5433}
+  zip: ${KEY_ZIP:08540}
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var master decodeDB
+	if err := p.UnmarshalKey("database.master", &master); err != nil {
+		t.Fatalf("UnmarshalKey failed: %v", err)
+	}
+	assert(t, master, decodeDB{DBHost: "master.local", Port: 5432, Timeout: 5 * time.Second}, "master")
+
+	var replicas []decodeDB
+	if err := p.UnmarshalKey("database.replicas", &replicas); err != nil {
+		t.Fatalf("UnmarshalKey failed: %v", err)
+	}
+	if !reflect.DeepEqual(replicas, []decodeDB{{DBHost: "r1", Port: 5433}}) {
+		t.Errorf("replicas = %v", replicas)
+	}
+
+	var zip string
+	if err := p.UnmarshalKey("database.zip", &zip); err != nil {
+		t.Fatalf("UnmarshalKey failed: %v", err)
+	}
+	assert(t, zip, "08540", "zip")
+
+	if err := p.UnmarshalKey("database.missing", &master); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+
+	strict := NewProfile(WithKnownFields(true))
+	if err := strict.Read([]byte("database:\n  master: {dbhost: a, Port: 1}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	var decodeErr *DecodeError
+	if err := strict.UnmarshalKey("database.master", &master); !errors.As(err, &decodeErr) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	assert(t, decodeErr.Report.Unmapped[0].Path, "database.master.Port", "unmapped path")
+}
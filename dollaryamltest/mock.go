@@ -0,0 +1,231 @@
+// Package dollaryamltest provides test doubles for code that consumes
+// dollarYaml configuration through the ConfigReader and ConfigWriter
+// interfaces.
+package dollaryamltest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kmlixh/dollarYaml"
+	"gopkg.in/yaml.v3"
+)
+
+// Mock is a ConfigReader and ConfigWriter backed by stubbed values instead
+// of YAML
+type Mock struct {
+	values map[string]interface{}
+	errs   map[string]error
+}
+
+var (
+	_ dollarYaml.ConfigReader  = (*Mock)(nil)
+	_ dollarYaml.ContextReader = (*Mock)(nil)
+	_ dollarYaml.ConfigWriter  = (*Mock)(nil)
+)
+
+// NewMock creates an empty Mock; every path is missing until stubbed
+func NewMock() *Mock {
+	return &Mock{
+		values: make(map[string]interface{}),
+		errs:   make(map[string]error),
+	}
+}
+
+// WithValue stubs the value returned for path
+func (m *Mock) WithValue(path string, value interface{}) *Mock {
+	delete(m.errs, path)
+	m.values[path] = value
+	return m
+}
+
+// WithMissing makes path report dollarYaml.ErrValueNotFound
+func (m *Mock) WithMissing(path string) *Mock {
+	delete(m.values, path)
+	delete(m.errs, path)
+	return m
+}
+
+// WithError makes path report err
+func (m *Mock) WithError(path string, err error) *Mock {
+	delete(m.values, path)
+	m.errs[path] = err
+	return m
+}
+
+// Get returns the stubbed value for path, or an empty string
+func (m *Mock) Get(path string) string {
+	val, _ := m.GetError(path)
+	return val
+}
+
+// GetError returns the stubbed value or error for path
+func (m *Mock) GetError(path string) (string, error) {
+	if err, ok := m.errs[path]; ok {
+		return "", err
+	}
+	if val, ok := m.values[path]; ok {
+		return fmt.Sprint(val), nil
+	}
+	return "", fmt.Errorf("%w: %s", dollarYaml.ErrValueNotFound, path)
+}
+
+// GetContext is GetError, failing early when ctx is already done
+func (m *Mock) GetContext(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return m.GetError(path)
+}
+
+// UnmarshalToContext is UnmarshalTo, failing early when ctx is already done
+func (m *Mock) UnmarshalToContext(ctx context.Context, target interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.UnmarshalTo(target)
+}
+
+// Has reports whether path is stubbed with a value, or is a map holding
+// stubbed values
+func (m *Mock) Has(path string) bool {
+	if _, ok := m.values[path]; ok {
+		return true
+	}
+	for stubbed := range m.values {
+		if strings.HasPrefix(stubbed, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// SubReader returns a Mock holding the values and errors stubbed under
+// path, relative to it, or nil if there are none
+func (m *Mock) SubReader(path string) dollarYaml.ConfigReader {
+	sub := NewMock()
+	prefix := path + "."
+	for stubbed, val := range m.values {
+		if strings.HasPrefix(stubbed, prefix) {
+			sub.values[strings.TrimPrefix(stubbed, prefix)] = val
+		}
+	}
+	for stubbed, err := range m.errs {
+		if strings.HasPrefix(stubbed, prefix) {
+			sub.errs[strings.TrimPrefix(stubbed, prefix)] = err
+		}
+	}
+	if len(sub.values) == 0 && len(sub.errs) == 0 {
+		return nil
+	}
+	return sub
+}
+
+// UnmarshalKey decodes the stubbed values under path into target, as
+// UnmarshalTo does for the whole tree. A stubbed error for path or a path
+// below it is returned first.
+func (m *Mock) UnmarshalKey(path string, target interface{}) error {
+	if err := m.firstError(path); err != nil {
+		return err
+	}
+	if !m.Has(path) {
+		return fmt.Errorf("%w: %s", dollarYaml.ErrValueNotFound, path)
+	}
+	var value interface{} = m.tree()
+	for _, key := range strings.Split(path, ".") {
+		nested, _ := value.(map[string]interface{})
+		value = nested[key]
+	}
+	return decode(value, target)
+}
+
+// UnmarshalTo decodes the stubbed values into target, treating each
+// stubbed dot-path as a nested key. Stubbed errors are returned first.
+func (m *Mock) UnmarshalTo(target interface{}) error {
+	if err := m.firstError(""); err != nil {
+		return err
+	}
+	return decode(m.tree(), target)
+}
+
+// Set stubs value for path, as WithValue does
+func (m *Mock) Set(path string, value interface{}) error {
+	m.WithValue(path, value)
+	return nil
+}
+
+// Merge stubs every leaf value of other, overriding the values stubbed
+// for the same paths
+func (m *Mock) Merge(other *dollarYaml.YamlProfile) error {
+	tree, err := other.GetRaw("")
+	if err != nil {
+		return err
+	}
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		nested, ok := v.(map[string]interface{})
+		if !ok {
+			if prefix != "" {
+				m.WithValue(prefix, v)
+			}
+			return
+		}
+		for k, item := range nested {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			walk(k, item)
+		}
+	}
+	walk("", tree)
+	return nil
+}
+
+// firstError returns the stubbed error of the first path, in sorted
+// order, at or below prefix; an empty prefix covers every path
+func (m *Mock) firstError(prefix string) error {
+	paths := make([]string, 0, len(m.errs))
+	for path := range m.errs {
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+".") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	sort.Strings(paths)
+	return fmt.Errorf("%s: %w", paths[0], m.errs[paths[0]])
+}
+
+// tree nests the stubbed values by their dot-paths
+func (m *Mock) tree() map[string]interface{} {
+	tree := make(map[string]interface{})
+	for path, val := range m.values {
+		keys := strings.Split(path, ".")
+		current := tree
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := current[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				current[key] = next
+			}
+			current = next
+		}
+		current[keys[len(keys)-1]] = val
+	}
+	return tree
+}
+
+// decode converts value into target through YAML
+func decode(value, target interface{}) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshaling stubbed values: %w", err)
+	}
+	if err := yaml.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("unmarshaling to target: %w", err)
+	}
+	return nil
+}
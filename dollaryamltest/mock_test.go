@@ -0,0 +1,108 @@
+package dollaryamltest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kmlixh/dollarYaml"
+)
+
+func TestMock(t *testing.T) {
+	errBackend := errors.New("backend down")
+	var cfg dollarYaml.ConfigReader = NewMock().
+		WithValue("db.host", "x").
+		WithValue("db.port", 5432).
+		WithValue("db.timeout", "30s").
+		WithMissing("db.user").
+		WithError("db.password", errBackend)
+
+	if got := cfg.Get("db.host"); got != "x" {
+		t.Errorf("db.host = %q, want %q", got, "x")
+	}
+	if got := cfg.Get("db.port"); got != "5432" {
+		t.Errorf("db.port = %q, want %q", got, "5432")
+	}
+	if _, err := cfg.GetError("db.user"); !errors.Is(err, dollarYaml.ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+	if _, err := cfg.GetError("db.password"); !errors.Is(err, errBackend) {
+		t.Errorf("expected stubbed error but got %v", err)
+	}
+
+	var config struct {
+		DB struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+		} `yaml:"db"`
+	}
+	if err := cfg.UnmarshalTo(&config); !errors.Is(err, errBackend) {
+		t.Errorf("expected stubbed error from UnmarshalTo but got %v", err)
+	}
+
+	ok := NewMock().WithValue("db.host", "x").WithValue("db.port", 5432)
+	if err := ok.UnmarshalTo(&config); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	if config.DB.Host != "x" || config.DB.Port != 5432 {
+		t.Errorf("decoded %+v, want host x and port 5432", config.DB)
+	}
+}
+
+func TestMock_SubAndWriter(t *testing.T) {
+	m := NewMock().
+		WithValue("db.host", "x").
+		WithValue("db.port", 5432).
+		WithError("cache.url", errors.New("backend down"))
+	var reader dollarYaml.ConfigReader = m
+	var writer dollarYaml.ConfigWriter = m
+
+	if !reader.Has("db") || !reader.Has("db.host") || reader.Has("db.user") {
+		t.Error("Has does not match the stubbed paths")
+	}
+	sub := reader.SubReader("db")
+	if sub == nil {
+		t.Fatal("SubReader(db) returned nil")
+	}
+	if got := sub.Get("port"); got != "5432" {
+		t.Errorf("sub port = %q, want 5432", got)
+	}
+	if _, err := reader.SubReader("cache").GetError("url"); err == nil {
+		t.Error("SubReader should carry stubbed errors")
+	}
+	if reader.SubReader("missing") != nil {
+		t.Error("SubReader of a missing path should be nil")
+	}
+
+	var db struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	if err := reader.UnmarshalKey("db", &db); err != nil {
+		t.Fatalf("UnmarshalKey failed: %v", err)
+	}
+	if db.Host != "x" || db.Port != 5432 {
+		t.Errorf("decoded %+v, want host x and port 5432", db)
+	}
+	if err := reader.UnmarshalKey("cache", &db); err == nil {
+		t.Error("expected the stubbed error from UnmarshalKey")
+	}
+	if err := reader.UnmarshalKey("missing", &db); !errors.Is(err, dollarYaml.ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+
+	if err := writer.Set("db.user", "app"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	other := dollarYaml.NewProfile()
+	if err := other.Read([]byte("db:\n  host: y\nlog:\n  level: debug\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if err := writer.Merge(other); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	for path, want := range map[string]string{"db.user": "app", "db.host": "y", "db.port": "5432", "log.level": "debug"} {
+		if got := m.Get(path); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
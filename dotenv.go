@@ -0,0 +1,156 @@
+package dollarYaml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrDotenv = errors.New("invalid dotenv file")
+
+// LoadDotenv reads the .env file at path and makes its variables available
+// to env placeholders after the process environment, so exported
+// variables still win. Files loaded later override the variables of
+// earlier ones. Lines are KEY=VALUE, optionally starting with export;
+// values may be single-quoted, taken literally, or double-quoted with
+// \n, \t, \", \\ and \$ escapes and may then span lines. Unquoted values
+// end at a " #" comment. Values are not expanded.
+func (p *YamlProfile) LoadDotenv(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading dotenv file: %w", err)
+	}
+	vars, err := parseDotenv(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	// Replace rather than update the map, which derived profiles share
+	merged := make(map[string]string, len(p.dotenv)+len(vars))
+	for k, v := range p.dotenv {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	p.dotenv = merged
+	return nil
+}
+
+// parseDotenv parses the contents of a .env file
+func parseDotenv(src string) (map[string]string, error) {
+	vars := make(map[string]string)
+	line := 1
+	for len(src) > 0 {
+		var entry string
+		entry, src = cutLine(src)
+		start := line
+		line++
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		entry = strings.TrimPrefix(entry, "export ")
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validEnvName(key) {
+			return nil, fmt.Errorf("%w: line %d: expected KEY=VALUE", ErrDotenv, start)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: line %d: unterminated quote", ErrDotenv, start)
+			}
+			if !trailingComment(value[end+2:]) {
+				return nil, fmt.Errorf("%w: line %d: unexpected text after the quoted value", ErrDotenv, start)
+			}
+			vars[key] = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// Double-quoted values continue on the following lines until
+			// the closing quote
+			text := value[1:]
+			for {
+				decoded, rest, closed := unquoteDotenv(text)
+				if closed {
+					if !trailingComment(rest) {
+						return nil, fmt.Errorf("%w: line %d: unexpected text after the quoted value", ErrDotenv, start)
+					}
+					vars[key] = decoded
+					break
+				}
+				if src == "" {
+					return nil, fmt.Errorf("%w: line %d: unterminated quote", ErrDotenv, start)
+				}
+				var next string
+				next, src = cutLine(src)
+				line++
+				text += "\n" + next
+			}
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			vars[key] = strings.TrimSpace(value)
+		}
+	}
+	return vars, nil
+}
+
+// cutLine splits the first line, without its line break, from src
+func cutLine(src string) (string, string) {
+	line, rest, _ := strings.Cut(src, "\n")
+	return strings.TrimSuffix(line, "\r"), rest
+}
+
+// unquoteDotenv decodes s up to its closing double quote, returning the
+// text after the quote and whether there was one
+func unquoteDotenv(s string) (string, string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), s[i+1:], true
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// trailingComment reports whether s holds only blanks and a comment
+func trailingComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
+
+// validEnvName reports whether name is a usable variable name
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && (c >= '0' && c <= '9' || c == '.' || c == '-')) {
+			return false
+		}
+	}
+	return true
+}
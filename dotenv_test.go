@@ -0,0 +1,107 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_LoadDotenv(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+	if err := os.WriteFile(base, []byte("DOTENV_HOST=db.local\nDOTENV_PORT=5432\nDOTENV_USER=app\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(local, []byte("DOTENV_PORT=6432\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	t.Setenv("DOTENV_USER", "from-env")
+
+	p := NewProfile()
+	if err := p.Read([]byte(`
+db:
+  host: ${DOTENV_HOST:localhost}
+  port: ${DOTENV_PORT}
+  user: ${DOTENV_USER}
+  name: ${DOTENV_NAME:main}
+`)); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, p.Get("db.host"), "localhost", "before loading")
+
+	if err := p.LoadDotenv(base); err != nil {
+		t.Fatalf("LoadDotenv failed: %v", err)
+	}
+	if err := p.LoadDotenv(local); err != nil {
+		t.Fatalf("LoadDotenv failed: %v", err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"db.host", "db.local"},
+		{"db.port", "6432"},
+		{"db.user", "from-env"},
+		{"db.name", "main"},
+	}
+	for _, tt := range tests {
+		assert(t, p.Get(tt.path), tt.want, tt.path)
+	}
+	assert(t, p.Sub("db").Get("host"), "db.local", "derived profile")
+
+	if err := p.LoadDotenv(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist but got %v", err)
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	src := "# comment\n" +
+		"export A=plain value # comment\n" +
+		"B = 'single $HOME # kept'\n" +
+		"C=\"tab\\tquote\\\" dollar\\$\" # comment\n" +
+		"D=\"first\n" +
+		"second\"\n" +
+		"E=\n" +
+		"F=a#b\r\n" +
+		"\n"
+	got, err := parseDotenv(src)
+	if err != nil {
+		t.Fatalf("parseDotenv failed: %v", err)
+	}
+	want := map[string]string{
+		"A": "plain value",
+		"B": "single $HOME # kept",
+		"C": "tab\tquote\" dollar$",
+		"D": "first\nsecond",
+		"E": "",
+		"F": "a#b",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"NOEQUALS", "1A=x", "A='open", "A=\"open\nstill open", "A=\"x\" y"} {
+		if _, err := parseDotenv(bad); !errors.Is(err, ErrDotenv) {
+			t.Errorf("%q: expected ErrDotenv but got %v", bad, err)
+		}
+	}
+
+	// ToDotenv output reads back unchanged
+	p := NewProfile()
+	if err := p.Read([]byte("secret: 'p@ss \"w\" $X\\n'\nnote: \"two\\nlines\"")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	data, err := p.ToDotenv()
+	if err != nil {
+		t.Fatalf("ToDotenv failed: %v", err)
+	}
+	back, err := parseDotenv(string(data))
+	if err != nil {
+		t.Fatalf("parseDotenv failed: %v", err)
+	}
+	assert(t, back["SECRET"], `p@ss "w" $X\n`, "round trip")
+	assert(t, back["NOTE"], "two\nlines", "round trip of a line break")
+}
@@ -0,0 +1,292 @@
+package dollarYaml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSnapshotMismatch = errors.New("config does not match snapshot hash")
+	ErrEnvNotAllowed    = errors.New("env var not allowed")
+)
+
+// EnvSnapshot records the environment variables referenced by a config
+// together with the hash of the config source they were captured for
+type EnvSnapshot struct {
+	ConfigHash string            `json:"configHash" yaml:"configHash"`
+	Vars       map[string]string `json:"vars" yaml:"vars"`
+}
+
+// lookupEnv reads an environment variable from the active env source:
+// a snapshot being replayed, then the file set with WithEnvFile, then the
+// process environment, then the files loaded with LoadDotenv
+func (p *YamlProfile) lookupEnv(name string) (string, bool) {
+	p.mu.RLock()
+	env := p.env
+	p.mu.RUnlock()
+	if env != nil {
+		val, ok := env[name]
+		return val, ok
+	}
+	if p.envFile != nil {
+		if val, ok := p.envFile.lookup(name); ok {
+			return val, ok
+		}
+	}
+	if val, ok := os.LookupEnv(name); ok {
+		return val, ok
+	}
+	val, ok := p.dotenv[name]
+	return val, ok
+}
+
+// checkEnv fails if the lists set with WithEnvAllowlist and
+// WithEnvDenylist forbid placeholders from reading the env var name
+func (p *YamlProfile) checkEnv(name string) error {
+	if matchesEnvGlob(p.envDeny, name) {
+		return fmt.Errorf("%w: %s is denied", ErrEnvNotAllowed, name)
+	}
+	if p.envAllow != nil && !matchesEnvGlob(p.envAllow, name) {
+		return fmt.Errorf("%w: %s is not in the allowlist", ErrEnvNotAllowed, name)
+	}
+	return nil
+}
+
+// matchesEnvGlob reports whether name matches any of the glob patterns
+func matchesEnvGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigHash returns the hex encoded SHA-256 of the last source read
+func (p *YamlProfile) ConfigHash() string {
+	p.mu.RLock()
+	sum := sha256.Sum256(p.raw)
+	p.mu.RUnlock()
+	return hex.EncodeToString(sum[:])
+}
+
+// SnapshotEnv captures the environment variables referenced by placeholders
+// in the configuration, including those referenced from the values of
+// other variables when WithRecursiveExpansion is enabled. Variables that
+// are unset are left out of the snapshot, so replaying it resolves them to
+// their defaults again.
+func (p *YamlProfile) SnapshotEnv() EnvSnapshot {
+	snap := EnvSnapshot{
+		ConfigHash: p.ConfigHash(),
+		Vars:       make(map[string]string),
+	}
+	for _, name := range p.referencedEnv() {
+		if val, ok := p.lookupEnv(name); ok {
+			snap.Vars[name] = val
+		}
+	}
+	return snap
+}
+
+// LoadWithEnvSnapshot reads data and resolves its placeholders against the
+// snapshot instead of the process environment. The snapshot stays in
+// effect for all later lookups on p.
+func (p *YamlProfile) LoadWithEnvSnapshot(data []byte, snap EnvSnapshot) error {
+	sum := sha256.Sum256(data)
+	if snap.ConfigHash != "" && snap.ConfigHash != hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("%w: %s", ErrSnapshotMismatch, snap.ConfigHash)
+	}
+	env := make(map[string]string, len(snap.Vars))
+	for k, v := range snap.Vars {
+		env[k] = v
+	}
+	result, tags, err := p.parseYAML(data)
+	if err != nil {
+		return err
+	}
+	// Install the snapshot with the config so OnChange hooks see it
+	p.loadWith(data, result, tags, func() {
+		p.env = env
+	})
+	return nil
+}
+
+// referencedEnv returns the sorted names of all env vars used in
+// placeholders, leaving out those WithEnvAllowlist or WithEnvDenylist forbid
+func (p *YamlProfile) referencedEnv() []string {
+	seen := make(map[string]bool)
+	p.walkPlaceholders(func(path, expr, prefix string) {
+		p.collectPlaceholderEnv(expr, prefix, seen)
+	})
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if p.checkEnv(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandConventionalEnv returns the sorted names of the env vars that can
+// change the value at path or anywhere below it: the variables named by
+// its placeholders with the $envPrefix of their subtree applied, those
+// referenced from defaults, and those read by ${base64:} and ${json:}.
+// For a profile built with FromEnvPrefix sources, the names those sources
+// map to each value are listed too, such as APP_DATABASE__HOST for
+// database.host with the prefix APP_. Placeholder variables forbidden by
+// WithEnvAllowlist or WithEnvDenylist are left out. An empty path covers
+// the whole config. A path that cannot be changed through the environment
+// returns nil.
+func (p *YamlProfile) ExpandConventionalEnv(path string) []string {
+	path = normalizePath(path)
+	seen := make(map[string]bool)
+	p.walkPlaceholders(func(phPath, expr, prefix string) {
+		if path == "" || phPath == path || strings.HasPrefix(phPath, path+".") {
+			p.collectPlaceholderEnv(expr, prefix, seen)
+		}
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if p.checkEnv(name) == nil {
+			names = append(names, name)
+		}
+	}
+	names = append(names, p.envSourceNames(path, seen)...)
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envSourceNames returns the names FromEnvPrefix sources of p would read
+// for the values at path or below it, leaving out those in seen. A
+// missing path is included, since such a variable would add it.
+func (p *YamlProfile) envSourceNames(path string, seen map[string]bool) []string {
+	p.mu.RLock()
+	prefixes := p.envSources
+	p.mu.RUnlock()
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	var paths []string
+	if path != "" {
+		paths = append(paths, path)
+	}
+	for _, key := range allKeys(p.root()) {
+		if path == "" || strings.HasPrefix(key, path+".") {
+			paths = append(paths, key)
+		}
+	}
+	var names []string
+	for _, key := range paths {
+		if node, _, err := p.lookup(key); err == nil {
+			if _, isMap := node.(map[string]interface{}); isMap {
+				continue
+			}
+		}
+		suffix := strings.ToUpper(strings.Join(splitPath(joinPath(p.base, key)), "__"))
+		for _, prefix := range prefixes {
+			if name := prefix + suffix; !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// walkPlaceholders calls fn for every placeholder in the tree with its
+// path relative to p and the env prefix in effect there. A placeholder
+// used as a map key is reported with the path of its entry.
+func (p *YamlProfile) walkPlaceholders(fn func(path, expr, prefix string)) {
+	var walk func(v interface{}, path, prefix string)
+	walk = func(v interface{}, path, prefix string) {
+		switch val := v.(type) {
+		case string:
+			p.eachPlaceholder(val, func(expr string) {
+				fn(path, expr, prefix)
+			})
+		case map[string]interface{}:
+			sc := scope{envPrefix: prefix}
+			applyDirectives(val, &sc)
+			for k, item := range val {
+				if isDirective(k) {
+					continue
+				}
+				p.eachPlaceholder(k, func(expr string) {
+					fn(joinKey(path, k), expr, sc.envPrefix)
+				})
+				walk(item, joinKey(path, k), sc.envPrefix)
+			}
+		case []interface{}:
+			for i, item := range val {
+				walk(item, joinPath(path, strconv.Itoa(i)), prefix)
+			}
+		}
+	}
+	walk(p.root(), "", p.envPrefix)
+}
+
+// eachPlaceholder calls fn with each placeholder in str, whether str is a
+// whole-value placeholder or text with placeholders embedded in it
+func (p *YamlProfile) eachPlaceholder(str string, fn func(expr string)) {
+	if expr, ok := p.placeholderExpr(str); ok {
+		fn(expr)
+		return
+	}
+	parts, _ := parseTemplate(str)
+	for _, part := range parts {
+		if part.expr != "" {
+			fn(part.expr)
+		}
+	}
+}
+
+// collectPlaceholderEnv records the env var named by a placeholder, any
+// variables referenced from its default segment and, with
+// WithRecursiveExpansion, from its value, applying the env prefix of the
+// placeholder's subtree
+func (p *YamlProfile) collectPlaceholderEnv(str, prefix string, seen map[string]bool) {
+	str, _ = p.splitFilters(str)
+	name, defaultValue, hasDefault := parsePlaceholder(str)
+	// A scheme name without a key, such as ${file}, reads an env var
+	// unless the scheme is bare, as in resolveSingle
+	if sch, ok := p.scheme(name); ok && (hasDefault || sch.bare) {
+		if name == "base64" || name == "json" {
+			// The key of these placeholders starts with an env var name
+			if idx := strings.IndexAny(defaultValue, ":."); idx != -1 {
+				defaultValue = defaultValue[:idx]
+			}
+			seen[defaultValue] = true
+		}
+		return
+	}
+	full := prefix + name
+	if !seen[full] && p.maxExpansion > 0 && p.checkEnv(full) == nil {
+		// WithRecursiveExpansion resolves the placeholders in the value too
+		seen[full] = true
+		if val, ok := p.lookupEnv(full); ok {
+			p.eachPlaceholder(val, func(expr string) {
+				p.collectPlaceholderEnv(expr, prefix, seen)
+			})
+		}
+	}
+	seen[full] = true
+	os.Expand(defaultValue, func(ref string) string {
+		if ref != "$" {
+			p.collectPlaceholderEnv("${"+ref+"}", prefix, seen)
+		}
+		return ""
+	})
+}
@@ -0,0 +1,275 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestYamlProfile_EnvSnapshot(t *testing.T) {
+	yamlData := []byte(`
+db:
+  host: ${SNAP_DB_HOST:localhost}
+  port: ${SNAP_DB_PORT:$SNAP_DB_DEFAULT_PORT}
+  tags:
+    - ${SNAP_DB_TAG}
+`)
+
+	os.Setenv("SNAP_DB_HOST", "db.internal")
+	os.Setenv("SNAP_DB_TAG", "primary")
+	os.Setenv("SNAP_DB_DEFAULT_PORT", "5432")
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	snap := p.SnapshotEnv()
+	os.Unsetenv("SNAP_DB_HOST")
+	os.Unsetenv("SNAP_DB_TAG")
+	os.Unsetenv("SNAP_DB_DEFAULT_PORT")
+
+	if len(snap.Vars) != 3 {
+		t.Errorf("snapshot vars = %v, want 3 entries", snap.Vars)
+	}
+	if snap.ConfigHash != p.ConfigHash() {
+		t.Errorf("snapshot hash = %q, want %q", snap.ConfigHash, p.ConfigHash())
+	}
+
+	t.Run("replay resolves captured values", func(t *testing.T) {
+		os.Setenv("SNAP_DB_PORT", "6543")
+		defer os.Unsetenv("SNAP_DB_PORT")
+
+		replay := New(false)
+		if err := replay.LoadWithEnvSnapshot(yamlData, snap); err != nil {
+			t.Fatalf("LoadWithEnvSnapshot failed: %v", err)
+		}
+		assert(t, replay.Get("db.host"), "db.internal", "db.host")
+		assert(t, replay.Get("db.port"), "5432", "db.port")
+
+		var config struct {
+			DB struct {
+				Tags []string `yaml:"tags"`
+			} `yaml:"db"`
+		}
+		if err := replay.UnmarshalTo(&config); err != nil {
+			t.Fatalf("UnmarshalTo failed: %v", err)
+		}
+		assert(t, config.DB.Tags[0], "primary", "db.tags[0]")
+	})
+
+	t.Run("replay rejects different config", func(t *testing.T) {
+		replay := New(false)
+		err := replay.LoadWithEnvSnapshot([]byte("db: {}"), snap)
+		if !errors.Is(err, ErrSnapshotMismatch) {
+			t.Errorf("expected ErrSnapshotMismatch but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_EnvSnapshotRecursive(t *testing.T) {
+	yamlData := []byte("url: ${SNAPR_URL}\n")
+	t.Setenv("SNAPR_URL", "postgres://${SNAPR_USER}@${SNAPR_HOST:db}")
+	t.Setenv("SNAPR_USER", "app")
+	t.Setenv("SNAPR_HOST", "db.internal")
+	p := New(false, WithRecursiveExpansion(3))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	snap := p.SnapshotEnv()
+	want := map[string]string{
+		"SNAPR_URL":  "postgres://${SNAPR_USER}@${SNAPR_HOST:db}",
+		"SNAPR_USER": "app",
+		"SNAPR_HOST": "db.internal",
+	}
+	if !reflect.DeepEqual(snap.Vars, want) {
+		t.Errorf("snapshot vars = %v, want %v", snap.Vars, want)
+	}
+
+	t.Setenv("SNAPR_USER", "other")
+	t.Setenv("SNAPR_HOST", "other")
+	replay := New(false, WithRecursiveExpansion(3))
+	if err := replay.LoadWithEnvSnapshot(yamlData, snap); err != nil {
+		t.Fatalf("LoadWithEnvSnapshot failed: %v", err)
+	}
+	assert(t, replay.Get("url"), "postgres://app@db.internal", "url")
+}
+
+func TestYamlProfile_EnvSnapshotConcurrentReads(t *testing.T) {
+	yamlData := []byte("host: ${SNAPC_HOST:localhost}\n")
+	snap := EnvSnapshot{Vars: map[string]string{"SNAPC_HOST": "db"}}
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if host := p.Get("host"); host != "localhost" && host != "db" {
+				t.Errorf("host = %q", host)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		if err := p.LoadWithEnvSnapshot(yamlData, snap); err != nil {
+			t.Fatalf("LoadWithEnvSnapshot failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	assert(t, p.Get("host"), "db", "host from the snapshot")
+}
+
+func TestYamlProfile_ExpandConventionalEnv(t *testing.T) {
+	yamlData := []byte(`
+server:
+  host: ${HOST:localhost}
+  url: ${URL:http://${HOST}:${PORT:80}}
+  port: 8080
+worker:
+  $envPrefix: WORKER_
+  threads: ${THREADS:4|int}
+  token: ${base64:TOKEN_B64}
+  services: ${json:VCAP_SERVICES.db.0.uri}
+  tls:
+    $envPrefix: TLS_
+    cert: ${file:/etc/cert.pem}
+    key: ${KEY}
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"server.host", []string{"HOST"}},
+		{"server.url", []string{"HOST", "PORT", "URL"}},
+		{"server.port", nil},
+		{"server", []string{"HOST", "PORT", "URL"}},
+		{"worker.threads", []string{"WORKER_THREADS"}},
+		{"worker.tls", []string{"WORKER_TLS_KEY"}},
+		{"worker", []string{"TOKEN_B64", "VCAP_SERVICES", "WORKER_THREADS", "WORKER_TLS_KEY"}},
+		{"", []string{"HOST", "PORT", "TOKEN_B64", "URL", "VCAP_SERVICES", "WORKER_THREADS", "WORKER_TLS_KEY"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := p.ExpandConventionalEnv(tt.path)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYamlProfile_ExpandConventionalEnvSchemeNames(t *testing.T) {
+	p := New(false)
+	if err := p.Read([]byte("a: ${file}\nb: ${now}\nc: ${file:/etc/x}\nd: ${hostname}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	// ${file} and ${now} have no key, so they read env vars of that name
+	want := []string{"file", "now"}
+	if got := p.ExpandConventionalEnv(""); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestYamlProfile_ExpandConventionalEnvPrefixSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.yaml": "database:\n  host: ${DB_HOST:localhost}\n  port: 5432\nhosts: [a, b]\n",
+	})
+	p, err := NewBuilder().
+		Add(FileSource(filepath.Join(dir, "app.yaml"))).
+		Add(FromEnvPrefix("CONV_")).
+		Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"database.host", []string{"CONV_DATABASE__HOST", "DB_HOST"}},
+		{"database", []string{"CONV_DATABASE__HOST", "CONV_DATABASE__PORT", "DB_HOST"}},
+		{"hosts", []string{"CONV_HOSTS"}},
+		{"missing", []string{"CONV_MISSING"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := p.ExpandConventionalEnv(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := p.Sub("database").ExpandConventionalEnv("port"); !reflect.DeepEqual(got, []string{"CONV_DATABASE__PORT"}) {
+		t.Errorf("through Sub: got %v", got)
+	}
+}
+
+func TestYamlProfile_EnvAllowDenylist(t *testing.T) {
+	t.Setenv("APP_NAME", "svc")
+	t.Setenv("APP_SECRET", "s3cret")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "aws")
+	t.Setenv("APP_BLOB", "aGk=")
+
+	yamlData := []byte(`
+name: ${APP_NAME}
+secret: ${APP_SECRET:none}
+aws: ${AWS_SECRET_ACCESS_KEY:none}
+blob: ${base64:APP_BLOB}
+nested: ${APP_NAME:${AWS_SECRET_ACCESS_KEY}}
+`)
+	p := New(false, WithEnvAllowlist([]string{"APP_*"}), WithEnvDenylist([]string{"*_SECRET*"}))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		allowed bool
+	}{
+		{"name", "svc", true},
+		{"blob", "hi", true},
+		{"nested", "svc", true},
+		{"secret", "", false},
+		{"aws", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := p.GetError(tt.path)
+			if !tt.allowed {
+				if !errors.Is(err, ErrEnvNotAllowed) {
+					t.Errorf("expected ErrEnvNotAllowed but got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert(t, got, tt.want, tt.path)
+		})
+	}
+
+	snap := p.SnapshotEnv()
+	want := map[string]string{"APP_NAME": "svc", "APP_BLOB": "aGk="}
+	if !reflect.DeepEqual(snap.Vars, want) {
+		t.Errorf("snapshot got %v, want %v", snap.Vars, want)
+	}
+}
@@ -0,0 +1,438 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EtcdConfig configures the ${etcd:/key} resolver, which talks to the
+// etcd v3 JSON gateway
+type EtcdConfig struct {
+	// Endpoints are tried in order until one answers; defaults to
+	// ETCDCTL_ENDPOINTS or http://127.0.0.1:2379
+	Endpoints []string
+	// Username and Password enable etcd authentication; default to
+	// ETCDCTL_USER (user:password) and ETCDCTL_PASSWORD
+	Username string
+	Password string
+	// CAFile, CertFile and KeyFile configure TLS; default to
+	// ETCDCTL_CACERT, ETCDCTL_CERT and ETCDCTL_KEY
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables server certificate checks
+	InsecureSkipVerify bool
+	// Timeout bounds each request to etcd; defaults to 10 seconds
+	Timeout time.Duration
+	// CacheTTL is how long a fetched value is reused; defaults to 5
+	// minutes, and a negative value disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the client used to reach etcd, including its
+	// TLS configuration
+	HTTPClient *http.Client
+}
+
+// etcdResolver reads keys through the etcd v3 gateway
+type etcdResolver struct {
+	cfg   EtcdConfig
+	cache *ttlCache
+	err   error
+
+	mu    sync.Mutex
+	token string
+}
+
+func newEtcdResolver(cfg EtcdConfig) *etcdResolver {
+	if len(cfg.Endpoints) == 0 {
+		if env := os.Getenv("ETCDCTL_ENDPOINTS"); env != "" {
+			cfg.Endpoints = strings.Split(env, ",")
+		} else {
+			cfg.Endpoints = []string{"http://127.0.0.1:2379"}
+		}
+	}
+	// Normalize a copy, leaving the caller's slice as given
+	cfg.Endpoints = cloneSlice(cfg.Endpoints)
+	for i, e := range cfg.Endpoints {
+		e = strings.TrimRight(strings.TrimSpace(e), "/")
+		if !strings.Contains(e, "://") {
+			e = "http://" + e
+		}
+		cfg.Endpoints[i] = e
+	}
+	if cfg.Username == "" {
+		user := os.Getenv("ETCDCTL_USER")
+		cfg.Username, cfg.Password, _ = strings.Cut(user, ":")
+		if cfg.Password == "" {
+			cfg.Password = os.Getenv("ETCDCTL_PASSWORD")
+		}
+	}
+	if cfg.CAFile == "" {
+		cfg.CAFile = os.Getenv("ETCDCTL_CACERT")
+	}
+	if cfg.CertFile == "" {
+		cfg.CertFile = os.Getenv("ETCDCTL_CERT")
+	}
+	if cfg.KeyFile == "" {
+		cfg.KeyFile = os.Getenv("ETCDCTL_KEY")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	r := &etcdResolver{cfg: cfg, cache: newTTLCache(cfg.CacheTTL)}
+	if r.cfg.HTTPClient == nil {
+		r.cfg.HTTPClient, r.err = etcdClient(cfg)
+	}
+	return r
+}
+
+// etcdClient builds an HTTP client with the configured TLS settings
+func etcdClient(cfg EtcdConfig) (*http.Client, error) {
+	if cfg.CAFile == "" && cfg.CertFile == "" && !cfg.InsecureSkipVerify {
+		return http.DefaultClient, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" {
+		pair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// resolve returns the value stored at key
+func (r *etcdResolver) resolve(ctx context.Context, key string) (string, error) {
+	if r.err != nil {
+		return "", fmt.Errorf("etcd config: %w", r.err)
+	}
+	value, err := r.cache.get(ctx, key, func() (interface{}, error) {
+		return r.get(ctx, key)
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// errEtcdUnauthenticated signals an expired or missing auth token
+var errEtcdUnauthenticated = errors.New("etcd: unauthenticated")
+
+func (r *etcdResolver) get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	var out struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	payload := map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key))}
+	err := r.withLogin(func() error {
+		return r.call(ctx, "/v3/kv/range", payload, &out, true)
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(out.Kvs) == 0 {
+		return "", fmt.Errorf("%w: etcd key %s", ErrValueNotFound, key)
+	}
+	value, err := base64.StdEncoding.DecodeString(out.Kvs[0].Value)
+	if err != nil {
+		return "", fmt.Errorf("decoding etcd key %s: %w", key, err)
+	}
+	return string(value), nil
+}
+
+// withLogin runs an authenticated request, logging in again once if the
+// token has expired
+func (r *etcdResolver) withLogin(request func() error) error {
+	err := request()
+	if errors.Is(err, errEtcdUnauthenticated) && r.cfg.Username != "" {
+		r.mu.Lock()
+		r.token = ""
+		r.mu.Unlock()
+		err = request()
+	}
+	return err
+}
+
+// authToken logs in with the configured user when no token is held
+func (r *etcdResolver) authToken(ctx context.Context) (string, error) {
+	if r.cfg.Username == "" {
+		return "", nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token != "" {
+		return r.token, nil
+	}
+	var out struct {
+		Token string `json:"token"`
+	}
+	if err := r.call(ctx, "/v3/auth/authenticate", map[string]string{"name": r.cfg.Username, "password": r.cfg.Password}, &out, false); err != nil {
+		return "", fmt.Errorf("etcd login: %w", err)
+	}
+	r.token = out.Token
+	return r.token, nil
+}
+
+// call posts payload to path on the first endpoint that answers and
+// decodes the response into out
+func (r *etcdResolver) call(ctx context.Context, path string, payload, out interface{}, authenticated bool) error {
+	resp, err := r.open(ctx, path, payload, authenticated)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// open posts payload to path on the first endpoint that answers and
+// returns its successful response with the body still to be read
+func (r *etcdResolver) open(ctx context.Context, path string, payload interface{}, authenticated bool) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	token := ""
+	if authenticated {
+		if token, err = r.authToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for _, endpoint := range r.cfg.Endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp, err := r.cfg.HTTPClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			return nil, fmt.Errorf("%w: %s", errEtcdUnauthenticated, strings.TrimSpace(string(data)))
+		case resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("etcd %s: %s", endpoint, resp.Status)
+			continue
+		}
+		return nil, fmt.Errorf("etcd %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return nil, lastErr
+}
+
+// etcdSource loads a config, or a tree of keys, from etcd
+type etcdSource struct {
+	r   *etcdResolver
+	key string
+
+	mu       sync.Mutex
+	revision int64
+}
+
+// EtcdSource returns a Source loading key from etcd. A key ending in / is
+// read as a prefix: every key below it becomes a path, split at /, whose
+// value is typed as in YAML, so /config/app/database/port under the
+// prefix /config/app/ is read as database.port. Any other key holds a
+// whole config, decoded with the codec registered for its extension and
+// as YAML by default. The source is a WatchableSource, so Builder.Watch
+// reloads the profile whenever the key or a key under the prefix changes.
+func EtcdSource(cfg EtcdConfig, key string) Source {
+	return &etcdSource{r: newEtcdResolver(cfg), key: key}
+}
+
+func (s *etcdSource) Name() string { return "etcd:" + s.key }
+
+// prefix reports whether the source reads a tree of keys
+func (s *etcdSource) prefix() bool {
+	return strings.HasSuffix(s.key, "/")
+}
+
+// keyRange returns the key and range_end fields selecting the source
+func (s *etcdSource) keyRange() map[string]string {
+	fields := map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(s.key))}
+	if s.prefix() {
+		fields["range_end"] = base64.StdEncoding.EncodeToString(prefixEnd(s.key))
+	}
+	return fields
+}
+
+// prefixEnd returns the first key after every key starting with prefix
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+func (s *etcdSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	if s.r.err != nil {
+		return nil, fmt.Errorf("etcd config: %w", s.r.err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.r.cfg.Timeout)
+	defer cancel()
+
+	var out struct {
+		Header struct {
+			Revision int64 `json:"revision,string"`
+		} `json:"header"`
+		Kvs []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	err := s.r.withLogin(func() error {
+		return s.r.call(ctx, "/v3/kv/range", s.keyRange(), &out, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	if s.prefix() {
+		tree = map[string]interface{}{}
+		for _, kv := range out.Kvs {
+			key, err := base64.StdEncoding.DecodeString(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("decoding etcd key: %w", err)
+			}
+			value, err := base64.StdEncoding.DecodeString(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("decoding etcd key %s: %w", key, err)
+			}
+			keys := strings.Split(strings.Trim(strings.TrimPrefix(string(key), s.key), "/"), "/")
+			if keys[0] == "" {
+				continue
+			}
+			if err := setLeaf(tree, keys, typedText(string(value))); err != nil {
+				return nil, fmt.Errorf("etcd key %s: %w", key, err)
+			}
+		}
+	} else {
+		if len(out.Kvs) == 0 {
+			return nil, fmt.Errorf("%w: etcd key %s", ErrValueNotFound, s.key)
+		}
+		value, err := base64.StdEncoding.DecodeString(out.Kvs[0].Value)
+		if err != nil {
+			return nil, fmt.Errorf("decoding etcd key %s: %w", s.key, err)
+		}
+		if tree, err = decodeSource(value, pathpkg.Ext(s.key)); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.revision = out.Header.Revision
+	s.mu.Unlock()
+	return tree, nil
+}
+
+// Watch opens a watch on the key or prefix from the revision after the
+// last Load and returns once an event arrives
+func (s *etcdSource) Watch(ctx context.Context) error {
+	if s.r.err != nil {
+		return fmt.Errorf("etcd config: %w", s.r.err)
+	}
+	s.mu.Lock()
+	request := s.keyRange()
+	if s.revision > 0 {
+		request["start_revision"] = strconv.FormatInt(s.revision+1, 10)
+	}
+	s.mu.Unlock()
+
+	var resp *http.Response
+	err := s.r.withLogin(func() error {
+		var err error
+		resp, err = s.r.open(ctx, "/v3/watch", map[string]interface{}{"create_request": request}, true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Result struct {
+				Canceled        bool              `json:"canceled"`
+				CancelReason    string            `json:"cancel_reason"`
+				CompactRevision int64             `json:"compact_revision,string"`
+				Events          []json.RawMessage `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("etcd watch %s: %w", s.key, err)
+		}
+		switch {
+		case msg.Error != nil:
+			return fmt.Errorf("etcd watch %s: %s", s.key, msg.Error.Message)
+		case len(msg.Result.Events) > 0, msg.Result.CompactRevision > 0:
+			// Events were missed if the revision was compacted, so reload
+			return nil
+		case msg.Result.Canceled:
+			return fmt.Errorf("etcd watch %s canceled: %s", s.key, msg.Result.CancelReason)
+		}
+	}
+}
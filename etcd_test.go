@@ -0,0 +1,268 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newEtcdServer fakes the etcd v3 gateway with authentication enabled.
+// Tokens are invalidated after the first range request to exercise re-login.
+func newEtcdServer(t *testing.T, logins *int32) *httptest.Server {
+	var ranges int32
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			if in["name"] != "app" || in["password"] != "pw" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			n := atomic.AddInt32(logins, 1)
+			json.NewEncoder(w).Encode(map[string]string{"token": "tok" + string(rune('0'+n))})
+		case "/v3/kv/range":
+			token := r.Header.Get("Authorization")
+			if token == "" || (token == "tok1" && atomic.AddInt32(&ranges, 1) > 1) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"etcdserver: invalid auth token"}`))
+				return
+			}
+			key, _ := base64.StdEncoding.DecodeString(in["key"])
+			values := map[string]string{"/config/app/db_host": "db.internal", "/config/app/port": "2379"}
+			value, ok := values[string(key)]
+			if !ok {
+				json.NewEncoder(w).Encode(map[string]interface{}{"header": map[string]string{}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"kvs": []map[string]string{{"key": in["key"], "value": base64.StdEncoding.EncodeToString([]byte(value))}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestYamlProfile_EtcdScheme(t *testing.T) {
+	var logins int32
+	server := newEtcdServer(t, &logins)
+	defer server.Close()
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caPath, ca, 0o600); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+	t.Setenv("ETCDCTL_ENDPOINTS", dead.URL+","+server.URL)
+	t.Setenv("ETCDCTL_USER", "app:pw")
+	t.Setenv("ETCDCTL_CACERT", caPath)
+
+	p := New(false, WithEtcd(EtcdConfig{CacheTTL: -1}))
+	yamlData := []byte(`
+host: ${etcd:/config/app/db_host}
+port: ${etcd:/config/app/port}
+user: ${etcd:/config/app/user:admin}
+`)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"host", "db.internal"},
+		// The first token has expired by now, so this logs in again
+		{"port", "2379"},
+		{"user", "admin"},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+	assert(t, atomic.LoadInt32(&logins), int32(2), "logins")
+}
+
+func TestYamlProfile_EtcdSchemeErrors(t *testing.T) {
+	var logins int32
+	server := newEtcdServer(t, &logins)
+	defer server.Close()
+
+	p := New(false, WithEtcd(EtcdConfig{Endpoints: []string{server.URL}, Username: "app", Password: "wrong", HTTPClient: server.Client()}))
+	if err := p.Read([]byte("a: ${etcd:/config/app/db_host:fallback}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := p.GetError("a"); err == nil {
+		t.Error("expected login failure")
+	}
+
+	q := New(false, WithEtcd(EtcdConfig{Endpoints: []string{server.URL}, CAFile: filepath.Join(t.TempDir(), "missing.pem")}))
+	if err := q.Read([]byte("a: ${etcd:/config/app/db_host}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := q.GetError("a"); err == nil {
+		t.Error("expected error for missing CA file")
+	}
+}
+
+// etcdStore fakes the etcd v3 gateway for range and watch requests over
+// an in-memory key space
+type etcdStore struct {
+	mu       sync.Mutex
+	kvs      map[string]string
+	revision int64
+	changed  chan struct{}
+}
+
+func (s *etcdStore) put(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kvs[key] = value
+	s.revision++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *etcdStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b64 := base64.StdEncoding
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		key, _ := b64.DecodeString(in["key"])
+		end, _ := b64.DecodeString(in["range_end"])
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var keys []string
+		for k := range s.kvs {
+			if k == string(key) || len(end) > 0 && k >= string(key) && k < string(end) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		kvs := []map[string]string{}
+		for _, k := range keys {
+			kvs = append(kvs, map[string]string{"key": b64.EncodeToString([]byte(k)), "value": b64.EncodeToString([]byte(s.kvs[k]))})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"header": map[string]string{"revision": strconv.FormatInt(s.revision, 10)},
+			"kvs":    kvs,
+		})
+	case "/v3/watch":
+		var in struct {
+			CreateRequest struct {
+				StartRevision int64 `json:"start_revision,string"`
+			} `json:"create_request"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]interface{}{"created": true}})
+		w.(http.Flusher).Flush()
+		for {
+			s.mu.Lock()
+			revision, changed := s.revision, s.changed
+			s.mu.Unlock()
+			if revision >= in.CreateRequest.StartRevision {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"result": map[string]interface{}{"events": []map[string]string{{"type": "PUT"}}},
+				})
+				return
+			}
+			select {
+			case <-changed:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestEtcdSource(t *testing.T) {
+	store := &etcdStore{kvs: map[string]string{}, changed: make(chan struct{})}
+	store.put("/config/app.yaml", "database:\n  host: db1\n  port: ${ETCD_SOURCE_PORT:5432}\n")
+	store.put("/svc/database/port", "6543")
+	store.put("/svc/database/replicas/a", "r1")
+	store.put("/svc/name", "api")
+	server := httptest.NewServer(store)
+	defer server.Close()
+	cfg := EtcdConfig{Endpoints: []string{server.URL}}
+
+	t.Run("prefix", func(t *testing.T) {
+		p, err := NewBuilder().Add(EtcdSource(cfg, "/svc/")).Build(context.Background())
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		assert(t, p.Get("name"), "api", "name")
+		assert(t, p.Get("database.replicas.a"), "r1", "nested key")
+		port, err := p.GetInt("database.port")
+		if err != nil {
+			t.Fatalf("GetInt failed: %v", err)
+		}
+		assert(t, port, 6543, "typed port")
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		b := NewBuilder().Add(EtcdSource(cfg, "/config/app.yaml"))
+		p, err := b.Build(context.Background())
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		assert(t, p.Get("database.port"), "5432", "placeholder default")
+
+		reloaded := make(chan ChangeEvent, 1)
+		p.OnChange(func(ev ChangeEvent) { reloaded <- ev })
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- b.Watch(ctx, p) }()
+
+		store.put("/config/app.yaml", "database:\n  host: db2\n")
+		select {
+		case ev := <-reloaded:
+			assert(t, ev.Reloaded, true, "Reloaded")
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload after the key changed")
+		}
+		assert(t, p.Get("database.host"), "db2", "host after reload")
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected Watch to stop with context.Canceled, got %v", err)
+		}
+	})
+
+	if _, err := NewBuilder().Add(EtcdSource(cfg, "/missing.yaml")).Build(context.Background()); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+	if err := NewBuilder().Add(BytesSource("inline", nil, "")).Watch(context.Background(), NewProfile()); !errors.Is(err, ErrNotWatchable) {
+		t.Errorf("expected ErrNotWatchable but got %v", err)
+	}
+}
+
+func TestWithEtcd_KeepsEndpoints(t *testing.T) {
+	endpoints := []string{" etcd-1:2379/ ", "https://etcd-2:2379"}
+	NewProfile(WithEtcd(EtcdConfig{Endpoints: endpoints}))
+	want := []string{" etcd-1:2379/ ", "https://etcd-2:2379"}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("endpoints = %q, want %q", endpoints, want)
+	}
+}
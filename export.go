@@ -0,0 +1,212 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// EnvOption configures ExportEnv and Environ
+type EnvOption func(*envConfig)
+
+type envConfig struct {
+	prefix string
+	name   func(path string) string
+}
+
+// EnvPrefix prepends prefix to every variable name, e.g. APP_ gives
+// APP_DATABASE_MASTER_PORT
+func EnvPrefix(prefix string) EnvOption {
+	return func(c *envConfig) {
+		c.prefix = prefix
+	}
+}
+
+// EnvNaming replaces EnvName as the scheme turning a leaf path into a
+// variable name. Leaves it names "" are not exported.
+func EnvNaming(name func(path string) string) EnvOption {
+	return func(c *envConfig) {
+		c.name = name
+	}
+}
+
+// EnvName is the default naming scheme of ExportEnv: the segments of path
+// upper-cased and joined with underscores, with characters not allowed in
+// variable names also replaced by underscores, so database.master.port
+// becomes DATABASE_MASTER_PORT
+func EnvName(path string) string {
+	segments := splitPath(path)
+	for i, seg := range segments {
+		segments[i] = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+				return r
+			}
+			return '_'
+		}, seg)
+	}
+	return strings.Join(segments, "_")
+}
+
+// Environ returns the leaves of the effective config, flattened as by
+// Flatten, as NAME=value pairs sorted by name, ready for exec.Cmd.Env.
+// Two leaves getting the same name fail with ErrDuplicateKey.
+func (p *YamlProfile) Environ(opts ...EnvOption) ([]string, error) {
+	vars, err := p.envVars(opts)
+	if err != nil {
+		return nil, err
+	}
+	env := make([]string, 0, len(vars))
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// ExportEnv sets the leaves of the effective config as environment
+// variables of the process, named as by Environ, so child processes
+// inherit them
+func (p *YamlProfile) ExportEnv(opts ...EnvOption) error {
+	vars, err := p.envVars(opts)
+	if err != nil {
+		return err
+	}
+	for name, value := range vars {
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("setting %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// envVars names the flattened leaves of the config as configured by opts
+func (p *YamlProfile) envVars(opts []EnvOption) (map[string]string, error) {
+	cfg := envConfig{name: EnvName}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	flat, err := p.Flatten()
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(flat))
+	owners := make(map[string]string, len(flat))
+	for path, value := range flat {
+		name := cfg.name(path)
+		if name == "" {
+			continue
+		}
+		name = cfg.prefix + name
+		if other, ok := owners[name]; ok {
+			if other > path {
+				other, path = path, other
+			}
+			return nil, fmt.Errorf("%w: %s and %s both export as %s", ErrDuplicateKey, other, path, name)
+		}
+		owners[name] = path
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// ToDotenv encodes the leaves of the effective config in .env format, one
+// NAME=value line per leaf sorted by name, with the names of Environ.
+// Values other than plain words are double-quoted, with backslashes,
+// quotes, line breaks and dollar signs escaped so loaders do not expand
+// them.
+func (p *YamlProfile) ToDotenv(opts ...EnvOption) ([]byte, error) {
+	env, err := p.Environ(opts...)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		buf.WriteString(name + "=" + dotenvValue(value) + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// dotenvValue quotes value for a .env file unless it is a plain word
+func dotenvValue(value string) string {
+	if strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,:/@+") == "" {
+		return value
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\', '"', '$':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ToProperties encodes the leaves of the effective config in the Java
+// properties format, one key=value line per leaf sorted by key, the keys
+// being the paths of Flatten. Characters are escaped as
+// java.util.Properties.store does, with non-ASCII ones written as \uXXXX.
+func (p *YamlProfile) ToProperties() ([]byte, error) {
+	flat, err := p.Flatten()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(propertiesText(k, true) + "=" + propertiesText(flat[k], false) + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// propertiesText escapes s as a properties key or value. Keys escape
+// every space and values only leading ones.
+func propertiesText(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(' ')
+		case r == '\\' || r == '=' || r == ':' || r == '#' || r == '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04X`, u)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
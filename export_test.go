@@ -0,0 +1,130 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYamlProfile_Environ(t *testing.T) {
+	t.Setenv("EXPORT_PORT", "5433")
+
+	yamlData := []byte(`
+database:
+  master:
+    port: ${EXPORT_PORT:5432}
+    host: db.local
+  replicas: [r1, r2]
+annotations:
+  example.com/team: core
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	env, err := p.Environ()
+	if err != nil {
+		t.Fatalf("Environ failed: %v", err)
+	}
+	want := []string{
+		"ANNOTATIONS_EXAMPLE_COM_TEAM=core",
+		"DATABASE_MASTER_HOST=db.local",
+		"DATABASE_MASTER_PORT=5433",
+		"DATABASE_REPLICAS_0=r1",
+		"DATABASE_REPLICAS_1=r2",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Environ() = %v, want %v", env, want)
+	}
+
+	env, err = p.Environ(EnvPrefix("APP_"), EnvNaming(func(path string) string {
+		if !strings.HasPrefix(path, "database.master.") {
+			return ""
+		}
+		return EnvName(strings.TrimPrefix(path, "database."))
+	}))
+	if err != nil {
+		t.Fatalf("Environ failed: %v", err)
+	}
+	want = []string{"APP_MASTER_HOST=db.local", "APP_MASTER_PORT=5433"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Environ() with options = %v, want %v", env, want)
+	}
+
+	t.Run("collisions", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.Read([]byte("a: {b_c: 1}\na_b: {c: 2}")); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		if _, err := p.Environ(); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected ErrDuplicateKey but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_ExportEnv(t *testing.T) {
+	p := NewProfile()
+	if err := p.Read([]byte("service: {name: '${EXPORT_NAME:api}', port: 8080}")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	t.Setenv("EXPORTED_SERVICE_NAME", "")
+	t.Setenv("EXPORTED_SERVICE_PORT", "")
+	if err := p.ExportEnv(EnvPrefix("EXPORTED_")); err != nil {
+		t.Fatalf("ExportEnv failed: %v", err)
+	}
+	assert(t, os.Getenv("EXPORTED_SERVICE_NAME"), "api", "EXPORTED_SERVICE_NAME")
+	assert(t, os.Getenv("EXPORTED_SERVICE_PORT"), "8080", "EXPORTED_SERVICE_PORT")
+}
+
+func TestYamlProfile_ToDotenv(t *testing.T) {
+	p := NewProfile()
+	if err := p.Read([]byte(`
+db:
+  url: postgres://u@db.local:5432/app
+  password: 'p@ss "word" $HOME'
+  note: "two\nlines"
+  empty: ""
+`)); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	got, err := p.ToDotenv(EnvPrefix("APP_"))
+	if err != nil {
+		t.Fatalf("ToDotenv failed: %v", err)
+	}
+	want := `APP_DB_EMPTY=
+APP_DB_NOTE="two\nlines"
+APP_DB_PASSWORD="p@ss \"word\" \$HOME"
+APP_DB_URL=postgres://u@db.local:5432/app
+`
+	assert(t, string(got), want, "ToDotenv")
+}
+
+func TestYamlProfile_ToProperties(t *testing.T) {
+	t.Setenv("EXPORT_PORT", "5433")
+
+	p := NewProfile()
+	if err := p.Read([]byte(`
+server:
+  port: ${EXPORT_PORT:8080}
+  greeting: " héllo: world"
+  path: C:\data
+my key: a=b
+`)); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	got, err := p.ToProperties()
+	if err != nil {
+		t.Fatalf("ToProperties failed: %v", err)
+	}
+	want := `my\ key=a\=b
+server.greeting=\ h\u00E9llo\: world
+server.path=C\:\\data
+server.port=5433
+`
+	assert(t, string(got), want, "ToProperties")
+}
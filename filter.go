@@ -0,0 +1,246 @@
+package dollarYaml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrFilter = errors.New("filter failed")
+
+// Filter transforms the resolved value of a placeholder, as in
+// ${NAME|upper}. arg is the text after the filter name's colon, empty if
+// there is none. A filter returns a string, or a []string to turn the value
+// into a list. Filters after one that produced a list apply to each item.
+// An int, float64 or bool result is final, like the type hints below.
+type Filter func(value, arg string) (interface{}, error)
+
+// literalText is a placeholder result hinted as a string, which is never
+// coerced to a number or bool
+type literalText string
+
+// builtinFilters are available to every profile
+var builtinFilters = map[string]Filter{
+	"upper": func(value, _ string) (interface{}, error) {
+		return strings.ToUpper(value), nil
+	},
+	"lower": func(value, _ string) (interface{}, error) {
+		return strings.ToLower(value), nil
+	},
+	"trim":    filterTrim,
+	"replace": filterReplace,
+	"split":   filterSplit,
+	"quote": func(value, _ string) (interface{}, error) {
+		return strconv.Quote(value), nil
+	},
+
+	// Type hints fix the type a placeholder decodes as, instead of
+	// guessing from the text, and fail on values that do not parse
+	"int": func(value, _ string) (interface{}, error) {
+		v, err := parseInt(value)
+		if err != nil {
+			return nil, err
+		}
+		return int(v), nil
+	},
+	"float": func(value, _ string) (interface{}, error) {
+		return parseFloat(value)
+	},
+	"bool": func(value, _ string) (interface{}, error) {
+		return parseBool(value)
+	},
+	"string": func(value, _ string) (interface{}, error) {
+		return literalText(value), nil
+	},
+}
+
+// filterTrim strips surrounding whitespace, or the characters in arg
+func filterTrim(value, arg string) (interface{}, error) {
+	if arg == "" {
+		return strings.TrimSpace(value), nil
+	}
+	return strings.Trim(value, arg), nil
+}
+
+// filterReplace replaces every occurrence of old with new, written as
+// replace:old:new
+func filterReplace(value, arg string) (interface{}, error) {
+	colonIdx := strings.Index(arg, ":")
+	if colonIdx == -1 {
+		return nil, errors.New("replace needs old and new text, as in replace:old:new")
+	}
+	return strings.ReplaceAll(value, arg[:colonIdx], arg[colonIdx+1:]), nil
+}
+
+// filterSplit splits the value on arg, a comma by default, trimming the
+// space around each item. An empty value gives an empty list.
+func filterSplit(value, arg string) (interface{}, error) {
+	if arg == "" {
+		arg = ","
+	}
+	items := []string{}
+	if value == "" {
+		return items, nil
+	}
+	for _, item := range strings.Split(value, arg) {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items, nil
+}
+
+// RegisterFilter makes f available as ${NAME|name} in this profile and the
+// profiles derived from it, replacing any built-in filter of the same name.
+// Register filters before resolving values; registration is not
+// synchronized with lookups.
+func (p *YamlProfile) RegisterFilter(name string, f Filter) {
+	if p.filters == nil {
+		p.filters = make(map[string]Filter)
+	}
+	p.filters[name] = f
+}
+
+// filter returns the filter registered for name, preferring filters
+// registered on the profile over the built-in ones
+func (p *YamlProfile) filter(name string) (Filter, bool) {
+	if f, ok := p.filters[name]; ok {
+		return f, true
+	}
+	f, ok := builtinFilters[name]
+	return f, ok
+}
+
+// filterCall is one step of a placeholder's filter chain
+type filterCall struct {
+	name string
+	arg  string
+	fn   Filter
+}
+
+// splitFilters separates the filter chain from a ${...} placeholder,
+// returning the placeholder without it. A | starts the chain only when
+// every step names a known filter, so defaults containing a literal | keep
+// working; a | inside a nested ${...} never does.
+func (p *YamlProfile) splitFilters(str string) (string, []filterCall) {
+	inner := str[2 : len(str)-1]
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch {
+		case strings.HasPrefix(inner[i:], "${"):
+			depth++
+			i++
+		case inner[i] == '}' && depth > 0:
+			depth--
+		case inner[i] == '|' && depth == 0:
+			parts = append(parts, inner[start:i])
+			start = i + 1
+		}
+	}
+	if parts == nil {
+		return str, nil
+	}
+	parts = append(parts, inner[start:])
+
+	calls := make([]filterCall, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		name, arg := part, ""
+		if colonIdx := strings.Index(part, ":"); colonIdx != -1 {
+			name, arg = part[:colonIdx], part[colonIdx+1:]
+		}
+		fn, ok := p.filter(strings.TrimSpace(name))
+		if !ok {
+			return str, nil
+		}
+		calls = append(calls, filterCall{name: strings.TrimSpace(name), arg: arg, fn: fn})
+	}
+	return "${" + parts[0] + "}", calls
+}
+
+// applyFilters runs value through the chain. The result is a string, a
+// typed value from a type hint, or a []interface{} of those after a filter
+// such as split.
+func applyFilters(value string, calls []filterCall) (interface{}, error) {
+	var current interface{} = value
+	for _, call := range calls {
+		switch val := current.(type) {
+		case string:
+			out, err := runFilter(call, val)
+			if err != nil {
+				return nil, err
+			}
+			if items, ok := out.([]string); ok {
+				list := make([]interface{}, len(items))
+				for i, item := range items {
+					list[i] = item
+				}
+				out = list
+			}
+			current = out
+		case []interface{}:
+			list := make([]interface{}, len(val))
+			for i, item := range val {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%w: %s follows a type hint; type hints go last", ErrFilter, call.name)
+				}
+				out, err := runFilter(call, s)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := out.([]string); ok {
+					return nil, fmt.Errorf("%w: %s: cannot apply to list items that are already lists", ErrFilter, call.name)
+				}
+				list[i] = out
+			}
+			current = list
+		default:
+			return nil, fmt.Errorf("%w: %s follows a type hint; type hints go last", ErrFilter, call.name)
+		}
+	}
+	return current, nil
+}
+
+// runFilter calls a single filter and checks the type of its result
+func runFilter(call filterCall, value string) (interface{}, error) {
+	out, err := call.fn(value, call.arg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrFilter, call.name, err)
+	}
+	switch out.(type) {
+	case string, []string, literalText, int, float64, bool:
+		return out, nil
+	}
+	return nil, fmt.Errorf("%w: %s returned %T, want string or []string", ErrFilter, call.name, out)
+}
+
+// filteredText renders a filter result as text for string lookups, lists
+// printing the way Get prints any list
+func filteredText(value interface{}) string {
+	switch val := value.(type) {
+	case string:
+		return val
+	case literalText:
+		return string(val)
+	}
+	return fmt.Sprint(value)
+}
+
+// filteredValue prepares a filter result for decoding. Unhinted text
+// becomes resolvedText for coerceFor to convert; hinted values keep their
+// type.
+func filteredValue(value interface{}) interface{} {
+	switch val := value.(type) {
+	case string:
+		return resolvedText(val)
+	case literalText:
+		return string(val)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = filteredValue(item)
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,170 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYamlProfile_Filters(t *testing.T) {
+	os.Setenv("FILTER_PATH", "  /usr/bin  ")
+	os.Setenv("FILTER_HOSTS", "a.example.com, b.example.com,c.example.com")
+	os.Setenv("FILTER_PORTS", "80;443")
+	defer os.Unsetenv("FILTER_PATH")
+	defer os.Unsetenv("FILTER_HOSTS")
+	defer os.Unsetenv("FILTER_PORTS")
+
+	yamlData := []byte(`
+name: ${FILTER_NAME:myapp|upper}
+lower: ${FILTER_NAME:MyApp|lower}
+path: ${FILTER_PATH|trim}
+slashes: ${FILTER_PATH|trim|trim:/}
+domain: ${FILTER_NAME:a.example.com|replace:.example.com:.internal}
+quoted: ${FILTER_NAME:say "hi"|quote}
+hosts: ${FILTER_HOSTS|split:,}
+upperHosts: ${FILTER_HOSTS|split|upper}
+ports: ${FILTER_PORTS|split:;}
+empty: ${FILTER_EMPTY:|split}
+literal: ${FILTER_NAME:a|b}
+nested: ${FILTER_NAME:${FILTER_OTHER:x|upper}}
+custom: ${FILTER_NAME:abc|reverse}
+badReplace: ${FILTER_NAME:abc|replace:x}
+`)
+	p := New(false, WithFilter("reverse", func(value, _ string) (interface{}, error) {
+		r := []rune(value)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r), nil
+	}))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"name", "MYAPP"},
+		{"lower", "myapp"},
+		{"path", "/usr/bin"},
+		{"slashes", "usr/bin"},
+		{"domain", "a.internal"},
+		{"quoted", `"say \"hi\""`},
+		{"hosts", "[a.example.com b.example.com c.example.com]"},
+		{"upperHosts", "[A.EXAMPLE.COM B.EXAMPLE.COM C.EXAMPLE.COM]"},
+		{"literal", "a|b"},
+		{"nested", "X"},
+		{"custom", "cba"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := p.GetError(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert(t, got, tt.want, tt.path)
+		})
+	}
+
+	t.Run("bad arguments", func(t *testing.T) {
+		_, err := p.GetError("badReplace")
+		if !errors.Is(err, ErrFilter) || !strings.Contains(err.Error(), "replace") {
+			t.Errorf("expected ErrFilter for replace but got %v", err)
+		}
+	})
+
+	t.Run("decode lists", func(t *testing.T) {
+		var cfg struct {
+			Hosts []string `yaml:"hosts"`
+			Ports []int    `yaml:"ports"`
+			Empty []string `yaml:"empty"`
+		}
+		p := New(false)
+		if err := p.Read([]byte("hosts: ${FILTER_HOSTS|split}\nports: ${FILTER_PORTS|split:;}\nempty: ${FILTER_EMPTY:|split}\n")); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		if err := p.UnmarshalTo(&cfg); err != nil {
+			t.Fatalf("UnmarshalTo failed: %v", err)
+		}
+		if !reflect.DeepEqual(cfg.Hosts, []string{"a.example.com", "b.example.com", "c.example.com"}) {
+			t.Errorf("got hosts %v", cfg.Hosts)
+		}
+		if !reflect.DeepEqual(cfg.Ports, []int{80, 443}) {
+			t.Errorf("got ports %v", cfg.Ports)
+		}
+		if cfg.Empty == nil || len(cfg.Empty) != 0 {
+			t.Errorf("got empty %#v, want an empty list", cfg.Empty)
+		}
+	})
+
+	t.Run("referenced env", func(t *testing.T) {
+		seen := make(map[string]bool)
+		p.collectPlaceholderEnv("${FILTER_HOSTS|split:,}", "", seen)
+		if !seen["FILTER_HOSTS"] || len(seen) != 1 {
+			t.Errorf("got %v, want only FILTER_HOSTS", seen)
+		}
+	})
+}
+
+func TestYamlProfile_TypeHints(t *testing.T) {
+	os.Setenv("HINT_VERSION", "1.10")
+	os.Setenv("HINT_PORTS", "80, 443")
+	defer os.Unsetenv("HINT_VERSION")
+	defer os.Unsetenv("HINT_PORTS")
+
+	yamlData := []byte(`
+port: ${HINT_PORT:8080|int}
+ratio: ${HINT_RATIO:0.5|float}
+debug: ${HINT_DEBUG:TRUE|bool}
+version: ${HINT_VERSION|string}
+guessed: ${HINT_VERSION}
+ports: ${HINT_PORTS|split|int}
+codes: ${HINT_CODES:007,010|split|string}
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var cfg map[string]interface{}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"port":    8080,
+		"ratio":   0.5,
+		"debug":   true,
+		"version": "1.10",
+		"guessed": 1.1,
+		"ports":   []interface{}{80, 443},
+		"codes":   []interface{}{"007", "010"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %#v\nwant %#v", cfg, want)
+	}
+	assert(t, p.Get("version"), "1.10", "Get of a string hint")
+	assert(t, p.Get("debug"), "true", "Get of a bool hint")
+
+	errorTests := []struct {
+		name string
+		yaml string
+	}{
+		{"not an int", "v: ${HINT_VERSION|int}"},
+		{"not a bool", "v: ${HINT_VERSION|bool}"},
+		{"filter after hint", "v: ${HINT_PORT:1|int|upper}"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(false)
+			if err := p.Read([]byte(tt.yaml)); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			if _, err := p.GetError("v"); !errors.Is(err, ErrFilter) {
+				t.Errorf("expected ErrFilter but got %v", err)
+			}
+		})
+	}
+}
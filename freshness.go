@@ -0,0 +1,94 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrStale = errors.New("config is stale")
+
+// LoadedAt returns the time of the last successful Read, or the zero time
+// if nothing has been loaded yet
+func (p *YamlProfile) LoadedAt() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loadedAt
+}
+
+// SourceTime returns the modification time of the file last loaded with
+// ReadFromPath or ReadFromFS, or of the config last loaded with
+// ReadFromURL or from object storage, or the zero time for sources
+// without one
+func (p *YamlProfile) SourceTime() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.sourceTime
+}
+
+// Age returns the time since the last successful Read
+func (p *YamlProfile) Age() time.Duration {
+	loadedAt := p.LoadedAt()
+	if loadedAt.IsZero() {
+		return 0
+	}
+	return time.Since(loadedAt)
+}
+
+// SourceAge returns the time since SourceTime, or 0 when the source has
+// no modification time
+func (p *YamlProfile) SourceAge() time.Duration {
+	sourceTime := p.SourceTime()
+	if sourceTime.IsZero() {
+		return 0
+	}
+	return time.Since(sourceTime)
+}
+
+// CheckFresh returns ErrStale once the config is older than the limit set
+// with WithMaxAge, or its source older than the limit set with
+// WithMaxSourceAge, so a readiness probe can notice a refresh loop that
+// has silently stopped or a source that is no longer updated. Sources
+// without a modification time are never stale by their age. It always
+// succeeds when no limit is set.
+func (p *YamlProfile) CheckFresh() error {
+	if p.maxAge <= 0 && p.maxSourceAge <= 0 {
+		return nil
+	}
+	if p.LoadedAt().IsZero() {
+		return fmt.Errorf("%w: never loaded", ErrStale)
+	}
+	if age := p.Age(); p.maxAge > 0 && age > p.maxAge {
+		p.debugf("Config is %s old, limit is %s\n", age, p.maxAge)
+		return fmt.Errorf("%w: loaded %s ago, limit is %s", ErrStale, age.Round(time.Second), p.maxAge)
+	}
+	if age := p.SourceAge(); p.maxSourceAge > 0 && age > p.maxSourceAge {
+		p.debugf("Config source is %s old, limit is %s\n", age, p.maxSourceAge)
+		return fmt.Errorf("%w: source modified %s ago, limit is %s", ErrStale, age.Round(time.Second), p.maxSourceAge)
+	}
+	return nil
+}
+
+// WatchFreshness runs CheckFresh every interval until ctx is done and
+// passes the error to the handler set with WithStaleHandler when the
+// config becomes stale, and again only after a reload made it fresh. With
+// no handler, staleness is logged when debug output is enabled.
+// WatchFreshness blocks, so run it in its own goroutine.
+func (p *YamlProfile) WatchFreshness(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	stale := false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		err := p.CheckFresh()
+		if err != nil && !stale && p.staleHandler != nil {
+			p.staleHandler(err)
+		}
+		stale = err != nil
+	}
+}
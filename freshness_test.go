@@ -0,0 +1,165 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_Freshness(t *testing.T) {
+	p := New(false, WithMaxAge(time.Hour))
+	if err := p.CheckFresh(); !errors.Is(err, ErrStale) {
+		t.Errorf("expected ErrStale before first load but got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("app: demo\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	modTime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("failed to read from file: %v", err)
+	}
+	if err := p.CheckFresh(); err != nil {
+		t.Errorf("unexpected error after load: %v", err)
+	}
+	if !p.SourceTime().Equal(modTime) {
+		t.Errorf("SourceTime = %v, want %v", p.SourceTime(), modTime)
+	}
+	if p.Age() > time.Minute {
+		t.Errorf("Age = %v right after load", p.Age())
+	}
+
+	// Simulate a refresh loop that stopped two hours ago
+	p.loadedAt = time.Now().Add(-2 * time.Hour)
+	if err := p.CheckFresh(); !errors.Is(err, ErrStale) {
+		t.Errorf("expected ErrStale but got %v", err)
+	}
+
+	if err := p.Read([]byte("app: demo\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if err := p.CheckFresh(); err != nil {
+		t.Errorf("unexpected error after refresh: %v", err)
+	}
+	if !p.SourceTime().IsZero() {
+		t.Errorf("expected zero SourceTime for in-memory source, got %v", p.SourceTime())
+	}
+
+	if err := New(false).CheckFresh(); err != nil {
+		t.Errorf("expected no limit by default but got %v", err)
+	}
+}
+
+func TestYamlProfile_MaxSourceAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("app: demo\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	modTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+
+	p := New(false, WithMaxAge(time.Hour), WithMaxSourceAge(24*time.Hour))
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("failed to read from file: %v", err)
+	}
+	if age := p.SourceAge(); age < 47*time.Hour {
+		t.Errorf("SourceAge = %v, want about 48h", age)
+	}
+	if err := p.CheckFresh(); !errors.Is(err, ErrStale) {
+		t.Errorf("expected ErrStale for an old source but got %v", err)
+	}
+
+	// A source without a modification time is never stale by its age
+	if err := p.Read([]byte("app: demo\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, p.SourceAge(), time.Duration(0), "SourceAge without a source time")
+	if err := p.CheckFresh(); err != nil {
+		t.Errorf("unexpected error without a source time: %v", err)
+	}
+}
+
+func TestYamlProfile_WatchFreshness(t *testing.T) {
+	warnings := make(chan error, 10)
+	p := New(false, WithMaxAge(time.Hour), WithStaleHandler(func(err error) { warnings <- err }))
+	if err := p.Read([]byte("app: demo\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	p.mu.Lock()
+	p.loadedAt = time.Now().Add(-2 * time.Hour)
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- p.WatchFreshness(ctx, time.Millisecond) }()
+
+	select {
+	case err := <-warnings:
+		if !errors.Is(err, ErrStale) {
+			t.Errorf("handler got %v, want ErrStale", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stale handler was not called")
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("WatchFreshness returned %v", err)
+	}
+	assert(t, len(warnings), 0, "warnings while the config stays stale")
+}
+
+func TestYamlProfile_SourceTimeDuringReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("app: demo\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	p := NewProfile()
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("failed to read from file: %v", err)
+	}
+	var zero int32
+	p.OnChange(func(ChangeEvent) {
+		if p.SourceTime().IsZero() {
+			atomic.AddInt32(&zero, 1)
+		}
+	})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			p.SourceTime()
+			p.SourceAge()
+			p.Get("app")
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if err := p.ReadFromPath(path); err != nil {
+			t.Fatalf("failed to read from file: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	assert(t, atomic.LoadInt32(&zero), int32(0), "hooks seeing a zero SourceTime")
+}
@@ -0,0 +1,70 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CommandCodec evaluates a source language with an external tool that
+// reads the source on stdin and prints the result as a JSON object. The
+// evaluated tree then goes through the usual ${} resolution and decoding,
+// so CUE or Jsonnet configs can use env and secret placeholders.
+//
+// Frontends are not registered by default since they run external
+// programs; enable one with RegisterCodec, e.g.
+//
+//	dollarYaml.RegisterCodec(dollarYaml.CUEFrontend, ".cue")
+type CommandCodec struct {
+	// Command is the program and its arguments, run without a shell
+	Command []string
+	// Timeout bounds each evaluation; defaults to DefaultExecTimeout
+	Timeout time.Duration
+}
+
+var (
+	// CUEFrontend evaluates CUE with `cue export`
+	CUEFrontend = CommandCodec{Command: []string{"cue", "export", "--out", "json", "-"}}
+	// JsonnetFrontend evaluates Jsonnet with the jsonnet command
+	JsonnetFrontend = CommandCodec{Command: []string{"jsonnet", "-"}}
+)
+
+// Unmarshal evaluates data and decodes the JSON the command prints
+func (c CommandCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	if len(c.Command) == 0 {
+		return errors.New("empty command")
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("evaluating with %s: %w", c.Command[0], ctx.Err())
+		}
+		return fmt.Errorf("evaluating with %s: %w: %s", c.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+		return fmt.Errorf("decoding %s output: %w", c.Command[0], err)
+	}
+	return nil
+}
+
+// Marshal writes the tree as indented JSON, which CUE and Jsonnet both
+// accept as source
+func (c CommandCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	return json.MarshalIndent(data, "", "  ")
+}
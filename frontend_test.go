@@ -0,0 +1,70 @@
+package dollarYaml
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandCodec(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+	// JSON is valid output, so cat stands in for an evaluator
+	RegisterCodec(CommandCodec{Command: []string{"cat"}}, ".catjson")
+	os.Setenv("FRONTEND_HOST", "db.internal")
+	defer os.Unsetenv("FRONTEND_HOST")
+
+	path := filepath.Join(t.TempDir(), "app.catjson")
+	source := `{"db": {"host": "${FRONTEND_HOST}", "port": 5432}}`
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	p := New(false)
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	assert(t, p.Get("db.host"), "db.internal", "placeholder in evaluated tree")
+
+	var cfg struct {
+		DB struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+		} `yaml:"db"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	if cfg.DB.Port != 5432 {
+		t.Errorf("expected port 5432, got %d", cfg.DB.Port)
+	}
+}
+
+func TestCommandCodec_Errors(t *testing.T) {
+	var out map[string]interface{}
+	if err := (CommandCodec{}).Unmarshal(nil, &out); err == nil {
+		t.Error("expected error for empty command")
+	}
+	err := CommandCodec{Command: []string{"sh", "-c", "echo bad input >&2; exit 1"}}.Unmarshal(nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Errorf("expected stderr in error, got %v", err)
+	}
+	if err := (CommandCodec{Command: []string{"echo", "not json"}}).Unmarshal(nil, &out); err == nil {
+		t.Error("expected error for non-JSON output")
+	}
+}
+
+func TestCUEFrontend(t *testing.T) {
+	if _, err := exec.LookPath("cue"); err != nil {
+		t.Skip("cue not installed")
+	}
+	var out map[string]interface{}
+	if err := CUEFrontend.Unmarshal([]byte("port: 8000 + 80\nhost: \"${CUE_HOST:localhost}\"\n"), &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out["port"] != float64(8080) || out["host"] != "${CUE_HOST:localhost}" {
+		t.Errorf("unexpected tree %#v", out)
+	}
+}
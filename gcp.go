@@ -0,0 +1,350 @@
+package dollarYaml
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// GCPToken is an OAuth2 access token for Google Cloud APIs
+type GCPToken struct {
+	AccessToken string
+	// Expires is when the token stops working, zero if unknown
+	Expires time.Time
+}
+
+// GCPConfig configures the ${gcp-sm:...} resolver
+type GCPConfig struct {
+	// Token overrides Application Default Credentials, e.g. to plug in a
+	// token source from golang.org/x/oauth2/google
+	Token func(ctx context.Context) (GCPToken, error)
+	// Endpoint overrides the Secret Manager URL, e.g. for a regional
+	// endpoint or an emulator
+	Endpoint string
+	// StorageEndpoint overrides the Cloud Storage URL used by
+	// ReadFromGCS, e.g. for fake-gcs-server
+	StorageEndpoint string
+	// Timeout bounds each request to Google; defaults to 10 seconds
+	Timeout time.Duration
+	// CacheTTL is how long a fetched secret is reused; defaults to 5
+	// minutes, and a negative value disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the client used to reach Google
+	HTTPClient *http.Client
+}
+
+// gcpResolver fetches secret versions from GCP Secret Manager
+type gcpResolver struct {
+	cfg   GCPConfig
+	cache *ttlCache
+
+	mu    sync.Mutex
+	token GCPToken
+}
+
+func newGCPResolver(cfg GCPConfig) *gcpResolver {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://secretmanager.googleapis.com"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	r := &gcpResolver{cfg: cfg, cache: newTTLCache(cfg.CacheTTL)}
+	if r.cfg.Token == nil {
+		r.cfg.Token = r.defaultToken
+	}
+	return r
+}
+
+// resolve accesses the secret version named by key, e.g.
+// projects/p/secrets/name/versions/latest. A key without a version reads
+// the latest one.
+func (r *gcpResolver) resolve(ctx context.Context, key string) (string, error) {
+	name := strings.Trim(key, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	value, err := r.cache.get(ctx, name, func() (interface{}, error) {
+		return r.access(ctx, name)
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// access calls the AccessSecretVersion API
+func (r *gcpResolver) access(ctx context.Context, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.Endpoint+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: gcp secret %s", ErrValueNotFound, name)
+	default:
+		return "", fmt.Errorf("gcp secret manager %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("decoding secret %s: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// accessToken returns a cached token, refreshing it before expiry
+func (r *gcpResolver) accessToken(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token.AccessToken == "" || (!r.token.Expires.IsZero() && time.Until(r.token.Expires) < time.Minute) {
+		token, err := r.cfg.Token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("gcp credentials: %w", err)
+		}
+		r.token = token
+	}
+	return r.token.AccessToken, nil
+}
+
+// gcpCredentialsFile is the subset of a service account key or gcloud
+// user credentials file used for Application Default Credentials
+type gcpCredentialsFile struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// defaultToken follows Application Default Credentials: the file named by
+// GOOGLE_APPLICATION_CREDENTIALS, the gcloud well-known file, and finally
+// the metadata server, which serves Workload Identity tokens on GKE
+func (r *gcpResolver) defaultToken(ctx context.Context) (GCPToken, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			wellKnown := filepath.Join(dir, "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(wellKnown); err == nil {
+				path = wellKnown
+			}
+		}
+	}
+	if path == "" {
+		return r.metadataToken(ctx)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return GCPToken{}, err
+	}
+	var creds gcpCredentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return GCPToken{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	switch creds.Type {
+	case "service_account":
+		return r.serviceAccountToken(ctx, creds)
+	case "authorized_user":
+		return r.exchangeToken(ctx, "https://oauth2.googleapis.com/token", url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		})
+	}
+	return GCPToken{}, fmt.Errorf("unsupported credentials type %q in %s", creds.Type, path)
+}
+
+// serviceAccountToken exchanges a self-signed JWT for an access token
+func (r *gcpResolver) serviceAccountToken(ctx context.Context, creds gcpCredentialsFile) (GCPToken, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return GCPToken{}, errors.New("service account private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return GCPToken{}, fmt.Errorf("parsing service account key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return GCPToken{}, errors.New("service account key is not an RSA key")
+	}
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": creds.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": gcpCloudPlatformScope,
+		"aud":   tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return GCPToken{}, err
+	}
+
+	return r.exchangeToken(ctx, tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	})
+}
+
+// exchangeToken posts an OAuth2 token request
+func (r *gcpResolver) exchangeToken(ctx context.Context, tokenURI string, form url.Values) (GCPToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return GCPToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r.decodeToken(req)
+}
+
+// metadataToken asks the GCE/GKE metadata server for the default service
+// account's token
+func (r *gcpResolver) metadataToken(ctx context.Context) (GCPToken, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return GCPToken{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	token, err := r.decodeToken(req)
+	if err != nil {
+		return GCPToken{}, fmt.Errorf("no credentials file found and metadata server unavailable: %w", err)
+	}
+	return token, nil
+}
+
+// decodeToken performs req and parses the OAuth2 token response
+func (r *gcpResolver) decodeToken(req *http.Request) (GCPToken, error) {
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return GCPToken{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return GCPToken{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return GCPToken{}, fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return GCPToken{}, err
+	}
+	token := GCPToken{AccessToken: out.AccessToken}
+	if out.ExpiresIn > 0 {
+		token.Expires = time.Now().Add(time.Duration(out.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// getObject downloads a Cloud Storage object, returning its content and
+// update time
+func (r *gcpResolver) getObject(ctx context.Context, bucket, object string) ([]byte, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	token, err := r.accessToken(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	endpoint := r.cfg.StorageEndpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	endpoint = strings.TrimRight(endpoint, "/") + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(object) + "?alt=media"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, time.Time{}, fmt.Errorf("%w: gcs object %s/%s", ErrValueNotFound, bucket, object)
+	default:
+		return nil, time.Time{}, fmt.Errorf("gcs %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return body, modified, nil
+}
@@ -0,0 +1,150 @@
+package dollarYaml
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newGCPServer fakes Secret Manager, the OAuth2 token endpoint and the
+// metadata server. Service account assertions are checked against key.
+func newGCPServer(t *testing.T, key *rsa.PrivateKey, reads *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			parts := strings.Split(r.Form.Get("assertion"), ".")
+			if len(parts) != 3 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "sa-token", "expires_in": 3600})
+			return
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "md-token", "expires_in": 3600})
+			return
+		}
+
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sa-token" && auth != "Bearer md-token" && auth != "Bearer static" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(reads, 1)
+		switch r.URL.Path {
+		case "/v1/projects/p/secrets/db/versions/latest:access":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("s3cret"))},
+			})
+		case "/v1/projects/p/secrets/db/versions/2:access":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("old"))},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestYamlProfile_GCPScheme(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	var reads int32
+	server := newGCPServer(t, key, &reads)
+	defer server.Close()
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyFile, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "app@p.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	keyPath := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(keyPath, keyFile, 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	yamlData := []byte(`
+latest: ${gcp-sm:projects/p/secrets/db/versions/latest}
+short: ${gcp-sm:projects/p/secrets/db}
+pinned: ${gcp-sm:projects/p/secrets/db/versions/2}
+missing: ${gcp-sm:projects/p/secrets/other:fallback}
+`)
+
+	auths := map[string]struct {
+		cfg GCPConfig
+		env map[string]string
+	}{
+		"static": {cfg: GCPConfig{Token: func(ctx context.Context) (GCPToken, error) {
+			return GCPToken{AccessToken: "static"}, nil
+		}}},
+		"service account": {env: map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": keyPath}},
+		"metadata": {env: map[string]string{
+			"GOOGLE_APPLICATION_CREDENTIALS": "",
+			"HOME":                           t.TempDir(),
+			"XDG_CONFIG_HOME":                t.TempDir(),
+			"GCE_METADATA_HOST":              strings.TrimPrefix(server.URL, "http://"),
+		}},
+	}
+	for name, auth := range auths {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range auth.env {
+				t.Setenv(k, v)
+			}
+			cfg := auth.cfg
+			cfg.Endpoint = server.URL
+			p := New(false, WithGCP(cfg))
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+
+			tests := []struct {
+				path string
+				want string
+			}{
+				{"latest", "s3cret"},
+				{"short", "s3cret"},
+				{"pinned", "old"},
+				{"missing", "fallback"},
+			}
+			for _, tt := range tests {
+				got, err := p.GetError(tt.path)
+				if err != nil {
+					t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+				}
+				assert(t, got, tt.want, tt.path)
+			}
+
+			before := atomic.LoadInt32(&reads)
+			p.Get("latest")
+			if after := atomic.LoadInt32(&reads); after != before {
+				t.Errorf("expected cached lookup, got %d extra reads", after-before)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package dollarYaml
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const alnumChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// valueCache memoizes generated placeholder values between Reads
+type valueCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newValueCache() *valueCache {
+	return &valueCache{values: make(map[string]string)}
+}
+
+// get returns the cached value for key, generating it on first use
+func (c *valueCache) get(key string, generate func() (string, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if val, ok := c.values[key]; ok {
+		return val, nil
+	}
+	val, err := generate()
+	if err != nil {
+		return "", err
+	}
+	c.values[key] = val
+	return val, nil
+}
+
+// resolveUUID generates a random version 4 UUID
+func resolveUUID(context.Context, string) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// resolveRandom generates a random string from a key of the form
+// kind:length, where kind is hex, base64 or alnum and length is the number
+// of characters produced
+func resolveRandom(_ context.Context, key string) (string, error) {
+	kind, lengthStr, ok := strings.Cut(key, ":")
+	if !ok {
+		return "", fmt.Errorf("random placeholder %q must be kind:length", key)
+	}
+	length, err := strconv.Atoi(lengthStr)
+	if err != nil || length <= 0 {
+		return "", fmt.Errorf("random placeholder %q has invalid length", key)
+	}
+
+	switch kind {
+	case "hex":
+		b := make([]byte, (length+1)/2)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(b)[:length], nil
+	case "base64":
+		b := make([]byte, length)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(b)[:length], nil
+	case "alnum":
+		out := make([]byte, length)
+		max := big.NewInt(int64(len(alnumChars)))
+		for i := range out {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			out[i] = alnumChars[n.Int64()]
+		}
+		return string(out), nil
+	}
+	return "", fmt.Errorf("random placeholder %q has unknown kind %s", key, kind)
+}
@@ -0,0 +1,63 @@
+package dollarYaml
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestYamlProfile_GeneratedValues(t *testing.T) {
+	yamlData := []byte(`
+instance:
+  id: ${uuid}
+  other: ${uuid}
+  token: ${random:hex:32}
+  secret: ${random:alnum:12}
+  key: ${random:base64:20}
+`)
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	id := p.Get("instance.id")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("id %q is not a v4 UUID", id)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(p.Get("instance.token")) {
+		t.Errorf("token %q is not 32 hex characters", p.Get("instance.token"))
+	}
+	if !regexp.MustCompile(`^[A-Za-z0-9]{12}$`).MatchString(p.Get("instance.secret")) {
+		t.Errorf("secret %q is not 12 alphanumeric characters", p.Get("instance.secret"))
+	}
+	assert(t, len(p.Get("instance.key")), 20, "key length")
+
+	assert(t, p.Get("instance.id"), id, "id on second Get")
+	if p.Get("instance.other") == id {
+		t.Error("expected separate placeholders to generate separate values")
+	}
+
+	var config struct {
+		Instance struct {
+			ID    string `yaml:"id"`
+			Token string `yaml:"token"`
+		} `yaml:"instance"`
+	}
+	if err := p.UnmarshalTo(&config); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, config.Instance.ID, id, "decoded id")
+	assert(t, config.Instance.Token, p.Get("instance.token"), "decoded token")
+
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if p.Get("instance.id") == id {
+		t.Error("expected a new value after Read")
+	}
+
+	p.Read([]byte("bad: ${random:hex}"))
+	if _, err := p.GetError("bad"); err == nil {
+		t.Error("expected error for random placeholder without length")
+	}
+}
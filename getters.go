@@ -0,0 +1,332 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrConversion is returned by the typed getters when the value at a path
+// does not parse as the requested type
+var ErrConversion = errors.New("value cannot be converted")
+
+// GetInt retrieves the value at path as an int, resolving placeholders and
+// their defaults first
+func (p *YamlProfile) GetInt(path string) (int, error) {
+	s, err := p.GetError(path)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(path, s)
+}
+
+// GetInt64 retrieves the value at path as an int64
+func (p *YamlProfile) GetInt64(path string) (int64, error) {
+	s, err := p.GetError(path)
+	if err != nil {
+		return 0, err
+	}
+	return toInt64(path, s)
+}
+
+// GetFloat64 retrieves the value at path as a float64
+func (p *YamlProfile) GetFloat64(path string) (float64, error) {
+	s, err := p.GetError(path)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat64(path, s)
+}
+
+// GetBool retrieves the value at path as a bool. It accepts the forms of
+// strconv.ParseBool in any case, such as true, FALSE or 1.
+func (p *YamlProfile) GetBool(path string) (bool, error) {
+	s, err := p.GetError(path)
+	if err != nil {
+		return false, err
+	}
+	return toBool(path, s)
+}
+
+// GetDuration retrieves the value at path as a time.Duration. It accepts
+// the forms of time.ParseDuration, such as 30s or 1h30m, and plain numbers
+// as seconds.
+func (p *YamlProfile) GetDuration(path string) (time.Duration, error) {
+	s, err := p.GetError(path)
+	if err != nil {
+		return 0, err
+	}
+	return toDuration(path, s)
+}
+
+// defaultTimeLayouts are tried by GetTime when no layouts are given
+var defaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// GetTime retrieves the value at path as a time.Time, trying each of
+// layouts in turn. Without layouts it accepts RFC 3339 timestamps, dates
+// and date-times without a zone, which are taken as UTC. Timestamps that
+// yaml already decoded, unquoted in the file, are returned as they are.
+func (p *YamlProfile) GetTime(path string, layouts ...string) (time.Time, error) {
+	if raw, _, err := p.lookup(path); err == nil {
+		if ts, ok := raw.(time.Time); ok {
+			return ts, nil
+		}
+	}
+	s, err := p.GetError(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(layouts) == 0 {
+		layouts = defaultTimeLayouts
+	}
+	for _, layout := range layouts {
+		if ts, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, conversionError(path, s, "a time")
+}
+
+// GetStringSlice retrieves the list at path with its items resolved. A
+// scalar, such as an env var holding "a, b, c", is split on commas with
+// the space around each item trimmed; an empty scalar gives an empty slice.
+func (p *YamlProfile) GetStringSlice(path string) ([]string, error) {
+	node, err := p.resolvedNode(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []interface{}
+	switch val := node.(type) {
+	case []interface{}:
+		items = val
+	case map[string]interface{}:
+		return nil, fmt.Errorf("%w: %s is a map, not a list", ErrConversion, path)
+	default:
+		text := strings.TrimSpace(scalarText(val))
+		if text == "" {
+			return []string{}, nil
+		}
+		for _, item := range strings.Split(text, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	}
+
+	list := make([]string, len(items))
+	for i, item := range items {
+		if !isScalar(item) {
+			return nil, conversionError(joinPath(path, strconv.Itoa(i)), fmt.Sprint(item), "a scalar")
+		}
+		list[i] = scalarText(item)
+	}
+	return list, nil
+}
+
+// GetIntSlice retrieves the list at path as ints, splitting a scalar on
+// commas like GetStringSlice
+func (p *YamlProfile) GetIntSlice(path string) ([]int, error) {
+	items, err := p.GetStringSlice(path)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]int, len(items))
+	for i, item := range items {
+		v, err := parseInt(item)
+		if err != nil || int64(int(v)) != v {
+			return nil, conversionError(joinPath(path, strconv.Itoa(i)), item, "an int")
+		}
+		list[i] = int(v)
+	}
+	return list, nil
+}
+
+// GetStringMap retrieves the map at path with its keys and values
+// resolved. Values that are maps or lists themselves fail with
+// ErrConversion; use UnmarshalTo for nested structures.
+func (p *YamlProfile) GetStringMap(path string) (map[string]string, error) {
+	node, err := p.resolvedNode(path)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, conversionError(path, scalarText(node), "a map")
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if !isScalar(v) {
+			return nil, conversionError(joinKey(path, k), fmt.Sprint(v), "a scalar")
+		}
+		out[k] = scalarText(v)
+	}
+	return out, nil
+}
+
+// GetOrDefault retrieves the value at path, returning fallback when the
+// path is missing, resolves to an empty string or fails to resolve. Like
+// Get it reports no errors; failures are logged in debug mode.
+func (p *YamlProfile) GetOrDefault(path, fallback string) string {
+	v, err := p.GetError(path)
+	if err != nil || v == "" {
+		p.fallbackf(path, err)
+		return fallback
+	}
+	return v
+}
+
+// GetIntOrDefault is GetInt returning fallback on any error or empty
+// value, like GetOrDefault
+func (p *YamlProfile) GetIntOrDefault(path string, fallback int) int {
+	return orDefault(p, path, fallback, toInt)
+}
+
+// GetFloat64OrDefault is GetFloat64 returning fallback on any error or
+// empty value, like GetOrDefault
+func (p *YamlProfile) GetFloat64OrDefault(path string, fallback float64) float64 {
+	return orDefault(p, path, fallback, toFloat64)
+}
+
+// GetBoolOrDefault is GetBool returning fallback on any error or empty
+// value, like GetOrDefault
+func (p *YamlProfile) GetBoolOrDefault(path string, fallback bool) bool {
+	return orDefault(p, path, fallback, toBool)
+}
+
+// GetDurationOrDefault is GetDuration returning fallback on any error or
+// empty value, like GetOrDefault
+func (p *YamlProfile) GetDurationOrDefault(path string, fallback time.Duration) time.Duration {
+	return orDefault(p, path, fallback, toDuration)
+}
+
+// orDefault resolves path once and converts it with conv, falling back
+// as GetOrDefault does
+func orDefault[T any](p *YamlProfile, path string, fallback T, conv func(path, s string) (T, error)) T {
+	s, err := p.GetError(path)
+	if err != nil || s == "" {
+		p.fallbackf(path, err)
+		return fallback
+	}
+	v, err := conv(path, s)
+	if err != nil {
+		p.fallbackf(path, err)
+		return fallback
+	}
+	return v
+}
+
+func (p *YamlProfile) fallbackf(path string, err error) {
+	if err != nil {
+		p.debugf("Using fallback for %s: %v\n", path, err)
+	}
+}
+
+// GetAs decodes the value at path into a T, such as a struct, slice, map
+// or scalar, resolving placeholders as UnmarshalTo does:
+//
+//	db, err := dollarYaml.GetAs[DBConfig](p, "database")
+func GetAs[T any](p *YamlProfile, path string) (T, error) {
+	var out T
+	err := p.UnmarshalKey(path, &out)
+	return out, err
+}
+
+// resolvedNode returns the node at path with the placeholders in it and
+// below resolved, as processValue does for UnmarshalTo
+func (p *YamlProfile) resolvedNode(path string) (interface{}, error) {
+	value, rawPath, err := p.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.processValue(context.Background(), joinPath(p.base, rawPath), value)
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// scalarText renders a resolved scalar as Get would, with null as empty
+func scalarText(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return filteredText(v)
+}
+
+// toInt, toInt64, toFloat64, toBool and toDuration convert the resolved
+// text s of path for the typed getters
+
+func toInt(path, s string) (int, error) {
+	v, err := toInt64(path, s)
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(v)) != v {
+		return 0, fmt.Errorf("%w: %s = %d overflows int", ErrConversion, path, v)
+	}
+	return int(v), nil
+}
+
+func toInt64(path, s string) (int64, error) {
+	v, err := parseInt(s)
+	if err != nil {
+		return 0, conversionError(path, s, "an int")
+	}
+	return v, nil
+}
+
+func toFloat64(path, s string) (float64, error) {
+	v, err := parseFloat(s)
+	if err != nil {
+		return 0, conversionError(path, s, "a float")
+	}
+	return v, nil
+}
+
+func toBool(path, s string) (bool, error) {
+	v, err := parseBool(s)
+	if err != nil {
+		return false, conversionError(path, s, "a bool")
+	}
+	return v, nil
+}
+
+func toDuration(path, s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+		return d, nil
+	}
+	secs, err := parseFloat(s)
+	if err != nil {
+		return 0, conversionError(path, s, "a duration")
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func conversionError(path, value, kind string) error {
+	return fmt.Errorf("%w: %s = %q is not %s", ErrConversion, path, value, kind)
+}
+
+// parseInt, parseFloat and parseBool parse resolved text the way the type
+// hints and typed getters do, ignoring surrounding space
+
+func parseInt(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+func parseBool(s string) (bool, error) {
+	return strconv.ParseBool(strings.ToLower(strings.TrimSpace(s)))
+}
@@ -0,0 +1,343 @@
+package dollarYaml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_TypedGetters(t *testing.T) {
+	t.Setenv("GETTER_PORT", "8080")
+	t.Setenv("GETTER_DEBUG", "TRUE")
+
+	yamlData := []byte(`
+port: ${GETTER_PORT:80}
+workers: ${GETTER_WORKERS:4}
+big: 9000000000
+ratio: ${GETTER_RATIO:0.75}
+plain: 1.5
+debug: ${GETTER_DEBUG:false}
+enabled: true
+name: app
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	t.Run("int", func(t *testing.T) {
+		tests := []struct {
+			path    string
+			want    int64
+			wantErr error
+		}{
+			{"port", 8080, nil},
+			{"workers", 4, nil},
+			{"big", 9000000000, nil},
+			{"ratio", 0, ErrConversion},
+			{"name", 0, ErrConversion},
+			{"missing", 0, ErrValueNotFound},
+		}
+		for _, tt := range tests {
+			got, err := p.GetInt64(tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetInt64(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+			}
+			assert(t, got, tt.want, tt.path)
+		}
+		port, err := p.GetInt("port")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert(t, port, 8080, "port")
+	})
+
+	t.Run("float", func(t *testing.T) {
+		tests := []struct {
+			path    string
+			want    float64
+			wantErr error
+		}{
+			{"ratio", 0.75, nil},
+			{"plain", 1.5, nil},
+			{"port", 8080, nil},
+			{"name", 0, ErrConversion},
+		}
+		for _, tt := range tests {
+			got, err := p.GetFloat64(tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetFloat64(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+			}
+			assert(t, got, tt.want, tt.path)
+		}
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		tests := []struct {
+			path    string
+			want    bool
+			wantErr error
+		}{
+			{"debug", true, nil},
+			{"enabled", true, nil},
+			{"name", false, ErrConversion},
+		}
+		for _, tt := range tests {
+			got, err := p.GetBool(tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetBool(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+			}
+			assert(t, got, tt.want, tt.path)
+		}
+	})
+}
+
+func TestYamlProfile_GetDuration(t *testing.T) {
+	t.Setenv("GETTER_TIMEOUT", "5m")
+
+	yamlData := []byte(`
+timeout: ${GETTER_TIMEOUT:30s}
+retry: ${GETTER_RETRY:1h30m}
+seconds: 45
+fraction: ${GETTER_FRACTION:1.5}
+name: app
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		want    time.Duration
+		wantErr error
+	}{
+		{"timeout", 5 * time.Minute, nil},
+		{"retry", 90 * time.Minute, nil},
+		{"seconds", 45 * time.Second, nil},
+		{"fraction", 1500 * time.Millisecond, nil},
+		{"name", 0, ErrConversion},
+		{"missing", 0, ErrValueNotFound},
+	}
+	for _, tt := range tests {
+		got, err := p.GetDuration(tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("GetDuration(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+}
+
+func TestYamlProfile_GetTime(t *testing.T) {
+	t.Setenv("GETTER_START", "2024-03-01T09:30:00+02:00")
+
+	yamlData := []byte(`
+start: ${GETTER_START}
+date: "2024-03-01"
+unquoted: 2024-03-01T09:30:00Z
+custom: 01/03/2024
+name: app
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		layouts []string
+		want    time.Time
+		wantErr error
+	}{
+		{"start", nil, time.Date(2024, 3, 1, 7, 30, 0, 0, time.UTC), nil},
+		{"date", nil, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"unquoted", nil, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), nil},
+		{"custom", []string{"02/01/2006"}, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"custom", nil, time.Time{}, ErrConversion},
+		{"name", nil, time.Time{}, ErrConversion},
+	}
+	for _, tt := range tests {
+		got, err := p.GetTime(tt.path, tt.layouts...)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("GetTime(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("GetTime(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestYamlProfile_CollectionGetters(t *testing.T) {
+	t.Setenv("GETTER_HOSTS", "a.local, b.local,c.local")
+	t.Setenv("GETTER_SECOND", "8443")
+
+	yamlData := []byte(`
+hosts: ${GETTER_HOSTS}
+empty: ${GETTER_EMPTY:}
+ports: [80, "${GETTER_SECOND}"]
+split: ${GETTER_HOSTS|split|upper}
+nested: [[1, 2]]
+labels:
+  team: ${GETTER_TEAM:core}
+  tier: 1
+  note: ~
+  extra: {a: b}
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	stringTests := []struct {
+		path    string
+		want    []string
+		wantErr error
+	}{
+		{"hosts", []string{"a.local", "b.local", "c.local"}, nil},
+		{"empty", []string{}, nil},
+		{"ports", []string{"80", "8443"}, nil},
+		{"split", []string{"A.LOCAL", "B.LOCAL", "C.LOCAL"}, nil},
+		{"nested", nil, ErrConversion},
+		{"labels", nil, ErrConversion},
+		{"missing", nil, ErrValueNotFound},
+	}
+	for _, tt := range stringTests {
+		got, err := p.GetStringSlice(tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("GetStringSlice(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetStringSlice(%q) = %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	ports, err := p.GetIntSlice("ports")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ports, []int{80, 8443}) {
+		t.Errorf("GetIntSlice(ports) = %v", ports)
+	}
+	if _, err := p.GetIntSlice("hosts"); !errors.Is(err, ErrConversion) {
+		t.Errorf("expected ErrConversion for hosts but got %v", err)
+	}
+
+	if _, err := p.GetStringMap("labels"); !errors.Is(err, ErrConversion) {
+		t.Errorf("expected ErrConversion for a nested map but got %v", err)
+	}
+	if err := p.Read([]byte("labels: {team: '${GETTER_TEAM:core}', tier: 1, note: ~}")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	labels, err := p.GetStringMap("labels")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"team": "core", "tier": "1", "note": ""}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("GetStringMap(labels) = %v, want %v", labels, want)
+	}
+}
+
+func TestGetAs(t *testing.T) {
+	t.Setenv("GETTER_DB_HOST", "db.local")
+	t.Setenv("GETTER_ZIP", "08540")
+
+	yamlData := []byte(`
+database:
+  host: ${GETTER_DB_HOST:localhost}
+  port: ${GETTER_DB_PORT:5432}
+replicas:
+  - {host: r1, port: 5433}
+  - {host: r2, port: "${GETTER_R2_PORT:5434}"}
+zip: ${GETTER_ZIP}
+port: ${GETTER_DB_PORT:5432}
+`)
+	type dbConfig struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	db, err := GetAs[dbConfig](p, "database")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert(t, db, dbConfig{Host: "db.local", Port: 5432}, "database")
+
+	replicas, err := GetAs[[]dbConfig](p, "replicas")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(replicas, []dbConfig{{"r1", 5433}, {"r2", 5434}}) {
+		t.Errorf("replicas = %v", replicas)
+	}
+
+	raw, err := GetAs[map[string]interface{}](p, "database")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert(t, raw["port"], 5432, "database.port as interface")
+
+	zip, err := GetAs[string](p, "zip")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert(t, zip, "08540", "zip")
+
+	port, err := GetAs[uint16](p, "port")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert(t, port, uint16(5432), "port")
+
+	if _, err := GetAs[int](p, "database"); err == nil {
+		t.Error("expected an error decoding a map into an int")
+	}
+	if _, err := GetAs[dbConfig](p, "missing"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+}
+
+func TestYamlProfile_GetOrDefault(t *testing.T) {
+	t.Setenv("GETTER_EMPTY", "")
+	t.Setenv("GETTER_WORKERS", "8")
+
+	yamlData := []byte(`
+name: ${GETTER_NAME:app}
+empty: ${GETTER_EMPTY}
+unset: ${GETTER_UNSET}
+workers: ${GETTER_WORKERS}
+ratio: 0.5
+debug: "yes"
+timeout: 10s
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	assert(t, p.GetOrDefault("name", "other"), "app", "name")
+	assert(t, p.GetOrDefault("empty", "other"), "other", "empty")
+	assert(t, p.GetOrDefault("unset", "other"), "other", "unset")
+	assert(t, p.GetOrDefault("missing", "other"), "other", "missing")
+
+	assert(t, p.GetIntOrDefault("workers", 4), 8, "workers")
+	assert(t, p.GetIntOrDefault("missing", 4), 4, "missing int")
+	assert(t, p.GetIntOrDefault("name", 4), 4, "int conversion failure")
+	assert(t, p.GetFloat64OrDefault("ratio", 1), 0.5, "ratio")
+	assert(t, p.GetFloat64OrDefault("empty", 1), 1.0, "empty float")
+	assert(t, p.GetBoolOrDefault("debug", true), true, "unparsable bool")
+	assert(t, p.GetBoolOrDefault("missing", false), false, "missing bool")
+	assert(t, p.GetDurationOrDefault("timeout", time.Second), 10*time.Second, "timeout")
+	assert(t, p.GetDurationOrDefault("unset", time.Second), time.Second, "unset duration")
+
+	strict := NewProfile(WithStrict(true))
+	if err := strict.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, strict.GetOrDefault("unset", "other"), "other", "unresolved in strict mode")
+}
@@ -0,0 +1,96 @@
+package dollarYaml
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadGlob reads the files matching pattern, as filepath.Glob matches
+// them, in lexical order and deep-merges them as Merge would, each file
+// overriding the ones before it. Each file is decoded as ReadFromPath
+// decodes it, and the latest modification time becomes the SourceTime.
+// Directories are skipped, and it is an error for nothing to match.
+func (p *YamlProfile) ReadGlob(pattern string) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("reading glob: %w", err)
+	}
+	var files []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			files = append(files, match)
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("reading glob: %w: no files match %s", fs.ErrNotExist, pattern)
+	}
+	sort.Strings(files)
+	return p.readFiles(files)
+}
+
+// ReadDir reads the config files in dir, such as a conf.d directory, in
+// lexical order and deep-merges them as ReadGlob does. Files with a .yaml
+// or .yml extension or one with a registered codec are read; hidden
+// files and subdirectories are skipped. An empty directory loads an
+// empty config.
+func (p *YamlProfile) ReadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		ext := filepath.Ext(name)
+		if _, ok := CodecFor(ext); ok || ext == ".yaml" || ext == ".yml" {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	return p.readFiles(files)
+}
+
+// readFiles loads the files in order, each merged over the ones before it
+func (p *YamlProfile) readFiles(files []string) error {
+	var merged interface{} = map[string]interface{}{}
+	tags := make(map[string]string)
+	var sources []string
+	var latest time.Time
+	for _, file := range files {
+		other := NewProfile()
+		if err := other.ReadFromPath(file); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		fileTags := other.Tags()
+		m := merger{lists: p.listMerge, tags: fileTags}
+		merged = m.value(merged, other.data, "")
+		for k, v := range fileTags {
+			if !isMergeTag(v) {
+				tags[k] = v
+			}
+		}
+		sources = append(sources, other.sources...)
+		if other.sourceTime.After(latest) {
+			latest = other.sourceTime
+		}
+	}
+
+	raw, err := yaml.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	p.loadWith(raw, merged, tags, func() {
+		p.sources = sources
+		p.sourceTime = latest
+	})
+	return nil
+}
@@ -0,0 +1,85 @@
+package dollarYaml
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_ReadGlobAndDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "conf.d")
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	files := map[string]string{
+		"10-base.yaml":     "database:\n  host: localhost\n  port: 5432\nservers: [a]\n",
+		"20-prod.yaml":     "database:\n  host: ${GLOB_DB_HOST:db.prod}\nservers: !append [b]\n",
+		"30-pool.json":     `{"database": {"pool": 10}}`,
+		".99-hidden.yaml":  "database:\n  host: hidden\n",
+		"README.md":        "not config",
+		"nested/40-x.yaml": "database:\n  host: nested\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	check := func(t *testing.T, p *YamlProfile) {
+		t.Helper()
+		assert(t, p.Get("database.host"), "db.prod", "host")
+		assert(t, p.Get("database.port"), "5432", "port")
+		var servers []string
+		if err := p.UnmarshalKey("servers", &servers); err != nil {
+			t.Fatalf("UnmarshalKey failed: %v", err)
+		}
+		if !reflect.DeepEqual(servers, []string{"a", "b"}) {
+			t.Errorf("servers = %v, want [a b]", servers)
+		}
+	}
+
+	t.Run("dir", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.ReadDir(dir); err != nil {
+			t.Fatalf("ReadDir failed: %v", err)
+		}
+		check(t, p)
+		assert(t, p.Get("database.pool"), "10", "pool")
+		assert(t, len(p.sources), 3, "sources")
+		assert(t, p.SourceTime().IsZero(), false, "SourceTime")
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.ReadGlob(filepath.Join(dir, "*.yaml")); err != nil {
+			t.Fatalf("ReadGlob failed: %v", err)
+		}
+		check(t, p)
+		if _, err := p.GetError("database.pool"); err == nil {
+			t.Error("expected the JSON file not to match")
+		}
+		// Unlike ReadDir, the pattern decides about hidden files
+		assert(t, len(p.sources), 3, "sources")
+		assert(t, p.sources[2], filepath.Join(dir, "20-prod.yaml"), "last source")
+	})
+
+	if err := NewProfile().ReadGlob(filepath.Join(dir, "*.toml")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist but got %v", err)
+	}
+	empty := t.TempDir()
+	p := NewProfile()
+	if err := p.ReadDir(empty); err != nil {
+		t.Fatalf("ReadDir of an empty directory failed: %v", err)
+	}
+	assert(t, len(p.AllKeys()), 0, "keys of an empty directory")
+
+	if err := os.WriteFile(filepath.Join(empty, "bad.yaml"), []byte("a: [b"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := NewProfile().ReadDir(empty); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+}
@@ -0,0 +1,744 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrHCL = errors.New("invalid HCL")
+
+func init() {
+	RegisterCodec(hclCodec{}, ".hcl")
+}
+
+// ReadHCL loads HCL data. Attributes become keys and blocks nested maps,
+// one level per label, so database "primary" { port = 5432 } is read as
+// database.primary.port. ${...} in strings and heredocs is kept as a
+// placeholder of this package rather than evaluated as HCL, and $${ is a
+// literal ${ as in YAML.
+func (p *YamlProfile) ReadHCL(data []byte) error {
+	return p.ReadAs(data, ".hcl")
+}
+
+// hclCodec is the built-in HCL codec for HCL2 native syntax. Only literal
+// values are supported: strings, heredocs, numbers, booleans, null,
+// tuples and objects; references, function calls, operators and
+// template directives are rejected. A block repeated at the same path,
+// as unlabeled blocks of one type are, collects its bodies in a list.
+type hclCodec struct{}
+
+func (hclCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	if !utf8.Valid(data) {
+		return fmt.Errorf("%w: not UTF-8", ErrHCL)
+	}
+	h := &hclParser{src: string(data), line: 1}
+	root := map[string]interface{}{}
+	if err := h.parseBody(root, false); err != nil {
+		return err
+	}
+	*out = root
+	return nil
+}
+
+func (hclCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeHCLBody(&buf, nil, "", data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// hclParser decodes an HCL body into a tree
+type hclParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (h *hclParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrHCL, h.line, fmt.Sprintf(format, args...))
+}
+
+// parseBody parses attributes and blocks into m, up to the closing brace
+// of a block body or the end of the file
+func (h *hclParser) parseBody(m map[string]interface{}, block bool) error {
+	for {
+		h.skipSpace(true)
+		if h.pos >= len(h.src) {
+			if block {
+				return h.errorf("unterminated block")
+			}
+			return nil
+		}
+		if h.src[h.pos] == '}' {
+			if !block {
+				return h.errorf("unexpected }")
+			}
+			h.pos++
+			return nil
+		}
+
+		name, err := h.parseIdentifier()
+		if err != nil {
+			return err
+		}
+		h.skipSpace(false)
+		if h.pos < len(h.src) && h.src[h.pos] == '=' {
+			h.pos++
+			h.skipSpace(false)
+			value, err := h.parseExpression()
+			if err != nil {
+				return err
+			}
+			if _, exists := m[name]; exists {
+				return h.errorf("duplicate %s", name)
+			}
+			m[name] = value
+		} else if err := h.parseBlock(m, name); err != nil {
+			return err
+		}
+		if err := h.endOfStatement(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseBlock parses the labels and body of a block of type name
+func (h *hclParser) parseBlock(m map[string]interface{}, name string) error {
+	keys := []string{name}
+	for h.pos < len(h.src) && h.src[h.pos] != '{' {
+		var label string
+		var err error
+		if h.src[h.pos] == '"' {
+			label, err = h.parseString()
+		} else {
+			label, err = h.parseIdentifier()
+		}
+		if err != nil {
+			return h.errorf("expected = or a block after %s", name)
+		}
+		keys = append(keys, label)
+		h.skipSpace(false)
+	}
+	if h.pos >= len(h.src) {
+		return h.errorf("expected = or a block after %s", name)
+	}
+	h.pos++
+	body := map[string]interface{}{}
+	if err := h.parseBody(body, true); err != nil {
+		return err
+	}
+
+	parent := m
+	for i, k := range keys[:len(keys)-1] {
+		switch next := parent[k].(type) {
+		case nil:
+			child := map[string]interface{}{}
+			parent[k] = child
+			parent = child
+		case map[string]interface{}:
+			parent = next
+		default:
+			return h.errorf("%s is already set", strings.Join(keys[:i+1], "."))
+		}
+	}
+	last := keys[len(keys)-1]
+	switch prev := parent[last].(type) {
+	case nil:
+		parent[last] = body
+	case map[string]interface{}:
+		parent[last] = []interface{}{prev, body}
+	case []interface{}:
+		if !isTableArray(prev) {
+			return h.errorf("%s is already set", strings.Join(keys, "."))
+		}
+		parent[last] = append(prev, body)
+	default:
+		return h.errorf("%s is already set", strings.Join(keys, "."))
+	}
+	return nil
+}
+
+// skipSpace skips blanks and comments, and line breaks too when newlines
+// is set
+func (h *hclParser) skipSpace(newlines bool) {
+	for h.pos < len(h.src) {
+		rest := h.src[h.pos:]
+		switch c := rest[0]; {
+		case c == ' ' || c == '\t' || c == '\r' && !strings.HasPrefix(rest, "\r\n"):
+			h.pos++
+		case c == '#' || strings.HasPrefix(rest, "//"):
+			for h.pos < len(h.src) && h.src[h.pos] != '\n' {
+				h.pos++
+			}
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				h.pos = len(h.src)
+				return
+			}
+			h.line += strings.Count(rest[:end+2], "\n")
+			h.pos += end + 4
+		case newlines && c == '\n':
+			h.pos++
+			h.line++
+		case newlines && strings.HasPrefix(rest, "\r\n"):
+			h.pos += 2
+			h.line++
+		default:
+			return
+		}
+	}
+}
+
+// endOfStatement checks that an attribute or block ends its line, or is
+// followed by the brace closing a one-line block
+func (h *hclParser) endOfStatement() error {
+	h.skipSpace(false)
+	if h.pos >= len(h.src) {
+		return nil
+	}
+	rest := h.src[h.pos:]
+	if rest[0] == '\n' || rest[0] == '}' || strings.HasPrefix(rest, "\r\n") {
+		return nil
+	}
+	return h.errorf("unexpected %q; only literal values are supported", rest[0])
+}
+
+// parseIdentifier parses a name made of letters, digits, _ and -
+func (h *hclParser) parseIdentifier() (string, error) {
+	start := h.pos
+	for h.pos < len(h.src) {
+		r, size := utf8.DecodeRuneInString(h.src[h.pos:])
+		if !isHCLIdentRune(r, h.pos == start) {
+			break
+		}
+		h.pos += size
+	}
+	if h.pos == start {
+		if h.pos >= len(h.src) {
+			return "", h.errorf("unexpected end of file")
+		}
+		return "", h.errorf("unexpected %q", h.src[h.pos])
+	}
+	return h.src[start:h.pos], nil
+}
+
+func isHCLIdentRune(r rune, first bool) bool {
+	if unicode.IsLetter(r) || r == '_' {
+		return true
+	}
+	return !first && (unicode.IsDigit(r) || r == '-')
+}
+
+func (h *hclParser) parseExpression() (interface{}, error) {
+	if h.pos >= len(h.src) {
+		return nil, h.errorf("expected a value")
+	}
+	rest := h.src[h.pos:]
+	switch c := rest[0]; {
+	case c == '"':
+		return h.parseString()
+	case strings.HasPrefix(rest, "<<"):
+		return h.parseHeredoc()
+	case c == '[':
+		return h.parseTuple()
+	case c == '{':
+		return h.parseObject()
+	case isDigit(c) || c == '-' && len(rest) > 1 && isDigit(rest[1]):
+		return h.parseNumber()
+	}
+	start := h.pos
+	name, err := h.parseIdentifier()
+	if err != nil {
+		return nil, h.errorf("expected a value")
+	}
+	switch name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	h.pos = start
+	return nil, h.errorf("%s: only literal values are supported", name)
+}
+
+// parseString parses a "quoted" template on a single line
+func (h *hclParser) parseString() (string, error) {
+	h.pos++
+	var b strings.Builder
+	for h.pos < len(h.src) {
+		rest := h.src[h.pos:]
+		switch c := rest[0]; {
+		case c == '"':
+			h.pos++
+			return b.String(), nil
+		case c == '\\':
+			if err := h.parseEscape(&b); err != nil {
+				return "", err
+			}
+		case c == '\n':
+			return "", h.errorf("line break in a string")
+		default:
+			if err := h.templateText(&b); err != nil {
+				return "", err
+			}
+		}
+	}
+	return "", h.errorf("unterminated string")
+}
+
+// templateText copies the template text at h.pos into b: a placeholder
+// through its closing brace, or a single byte. %%{ is a literal %{.
+func (h *hclParser) templateText(b *strings.Builder) error {
+	rest := h.src[h.pos:]
+	switch {
+	case strings.HasPrefix(rest, "$${"):
+		b.WriteString("$${")
+		h.pos += 3
+	case strings.HasPrefix(rest, "${"):
+		depth := 0
+		for i := 0; i < len(rest); i++ {
+			switch rest[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			case '\n':
+				return h.errorf("unterminated ${")
+			}
+			if depth == 0 {
+				b.WriteString(rest[:i+1])
+				h.pos += i + 1
+				return nil
+			}
+		}
+		return h.errorf("unterminated ${")
+	case strings.HasPrefix(rest, "%%{"):
+		b.WriteString("%{")
+		h.pos += 3
+	case strings.HasPrefix(rest, "%{"):
+		return h.errorf("template directives are not supported")
+	default:
+		b.WriteByte(rest[0])
+		h.pos++
+	}
+	return nil
+}
+
+// parseEscape decodes the escape sequence at h.pos into b
+func (h *hclParser) parseEscape(b *strings.Builder) error {
+	if h.pos+1 >= len(h.src) {
+		return h.errorf("unterminated escape")
+	}
+	c := h.src[h.pos+1]
+	h.pos += 2
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if h.pos+n > len(h.src) {
+			return h.errorf("short unicode escape")
+		}
+		code, err := strconv.ParseUint(h.src[h.pos:h.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return h.errorf("invalid unicode escape \\%c%s", c, h.src[h.pos:h.pos+n])
+		}
+		b.WriteRune(rune(code))
+		h.pos += n
+	default:
+		return h.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// parseHeredoc parses a <<MARKER or <<-MARKER heredoc. Its text keeps
+// the final line break, and the indented form strips the indentation
+// common to its non-blank lines. Escapes are not decoded.
+func (h *hclParser) parseHeredoc() (string, error) {
+	h.pos += 2
+	indented := h.pos < len(h.src) && h.src[h.pos] == '-'
+	if indented {
+		h.pos++
+	}
+	marker, err := h.parseIdentifier()
+	if err != nil {
+		return "", err
+	}
+	h.skipSpace(false)
+	if h.pos >= len(h.src) || h.src[h.pos] != '\n' && !strings.HasPrefix(h.src[h.pos:], "\r\n") {
+		return "", h.errorf("expected a line break after <<%s", marker)
+	}
+	start := h.line
+
+	var lines []string
+	for {
+		if strings.HasPrefix(h.src[h.pos:], "\r\n") {
+			h.pos += 2
+		} else {
+			h.pos++
+		}
+		h.line++
+		if h.pos >= len(h.src) {
+			h.line = start
+			return "", h.errorf("unterminated heredoc %s", marker)
+		}
+		end := strings.IndexByte(h.src[h.pos:], '\n')
+		if end < 0 {
+			end = len(h.src) - h.pos
+		}
+		line := strings.TrimSuffix(h.src[h.pos:h.pos+end], "\r")
+		h.pos += end
+		if strings.TrimSpace(line) == marker {
+			break
+		}
+		lines = append(lines, line)
+		if h.pos >= len(h.src) {
+			h.line = start
+			return "", h.errorf("unterminated heredoc %s", marker)
+		}
+	}
+
+	if indented {
+		trim := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if n := len(line) - len(strings.TrimLeft(line, " \t")); trim < 0 || n < trim {
+				trim = n
+			}
+		}
+		for i, line := range lines {
+			if len(line) >= trim && trim > 0 {
+				lines[i] = line[trim:]
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		sub := &hclParser{src: line + "\n", line: h.line}
+		for sub.pos < len(sub.src) {
+			if err := sub.templateText(&b); err != nil {
+				return "", err
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// parseTuple parses [values], which may span lines and end with a comma
+func (h *hclParser) parseTuple() (interface{}, error) {
+	h.pos++
+	list := []interface{}{}
+	for {
+		h.skipSpace(true)
+		if h.pos >= len(h.src) {
+			return nil, h.errorf("unterminated tuple")
+		}
+		if h.src[h.pos] == ']' {
+			h.pos++
+			return list, nil
+		}
+		value, err := h.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		h.skipSpace(true)
+		if h.pos < len(h.src) && h.src[h.pos] == ',' {
+			h.pos++
+		} else if h.pos < len(h.src) && h.src[h.pos] != ']' {
+			return nil, h.errorf("expected , or ] in tuple")
+		}
+	}
+}
+
+// parseObject parses {key = value, ...}, whose items are separated by
+// commas or line breaks and whose keys are names or strings
+func (h *hclParser) parseObject() (interface{}, error) {
+	h.pos++
+	object := map[string]interface{}{}
+	for {
+		h.skipSpace(true)
+		if h.pos >= len(h.src) {
+			return nil, h.errorf("unterminated object")
+		}
+		if h.src[h.pos] == '}' {
+			h.pos++
+			return object, nil
+		}
+		var key string
+		var err error
+		if h.src[h.pos] == '"' {
+			key, err = h.parseString()
+		} else {
+			key, err = h.parseIdentifier()
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.skipSpace(false)
+		if h.pos >= len(h.src) || h.src[h.pos] != '=' && h.src[h.pos] != ':' {
+			return nil, h.errorf("expected = after %s", key)
+		}
+		h.pos++
+		h.skipSpace(false)
+		value, err := h.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := object[key]; exists {
+			return nil, h.errorf("duplicate %s", key)
+		}
+		object[key] = value
+
+		h.skipSpace(false)
+		if h.pos >= len(h.src) {
+			return nil, h.errorf("unterminated object")
+		}
+		switch h.src[h.pos] {
+		case ',', '\n', '}':
+			if h.src[h.pos] == ',' {
+				h.pos++
+			}
+		case '\r':
+		default:
+			return nil, h.errorf("expected , or } in object")
+		}
+	}
+}
+
+// parseNumber parses a number, as int when it is a whole number that
+// fits, as float64 otherwise
+func (h *hclParser) parseNumber() (interface{}, error) {
+	start := h.pos
+	if h.src[h.pos] == '-' {
+		h.pos++
+	}
+	digits := func() {
+		for h.pos < len(h.src) && isDigit(h.src[h.pos]) {
+			h.pos++
+		}
+	}
+	digits()
+	if h.pos+1 < len(h.src) && h.src[h.pos] == '.' && isDigit(h.src[h.pos+1]) {
+		h.pos++
+		digits()
+	}
+	if h.pos < len(h.src) && (h.src[h.pos] == 'e' || h.src[h.pos] == 'E') {
+		h.pos++
+		if h.pos < len(h.src) && (h.src[h.pos] == '+' || h.src[h.pos] == '-') {
+			h.pos++
+		}
+		digits()
+	}
+	token := h.src[start:h.pos]
+	if n, err := strconv.Atoi(token); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, h.errorf("invalid number %q", token)
+	}
+	return f, nil
+}
+
+// encodeHCLBody writes the attributes of m, then its maps as blocks and
+// its lists of maps as repeated blocks, each sorted by key. Maps with
+// keys that are not names are written as object attributes instead.
+func encodeHCLBody(buf *bytes.Buffer, path []string, indent string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var blocks []string
+	for _, k := range keys {
+		if !isHCLIdentifier(k) {
+			return fmt.Errorf("%w: %s: %q is not a valid attribute name", ErrHCL, strings.Join(path, "."), k)
+		}
+		if hclBlocks(m[k]) != nil {
+			blocks = append(blocks, k)
+			continue
+		}
+		text, err := hclValue(m[k])
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrHCL, strings.Join(append(path, k), "."), err)
+		}
+		buf.WriteString(indent + k + " = " + text + "\n")
+	}
+
+	for _, k := range blocks {
+		sub := append(append([]string{}, path...), k)
+		for _, body := range hclBlocks(m[k]) {
+			if buf.Len() > 0 {
+				buf.WriteByte('\n')
+			}
+			buf.WriteString(indent + k + " {\n")
+			if err := encodeHCLBody(buf, sub, indent+"  ", body); err != nil {
+				return err
+			}
+			buf.WriteString(indent + "}\n")
+		}
+	}
+	return nil
+}
+
+// hclBlocks returns the bodies v is written as, nil when v is written as
+// an attribute
+func hclBlocks(v interface{}) []map[string]interface{} {
+	var bodies []map[string]interface{}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		bodies = append(bodies, val)
+	case []interface{}:
+		if !isTableArray(val) {
+			return nil
+		}
+		for _, item := range val {
+			bodies = append(bodies, item.(map[string]interface{}))
+		}
+	default:
+		return nil
+	}
+	for _, body := range bodies {
+		for k := range body {
+			if !isHCLIdentifier(k) {
+				return nil
+			}
+		}
+	}
+	return bodies
+}
+
+func isHCLIdentifier(s string) bool {
+	for i, r := range s {
+		if !isHCLIdentRune(r, i == 0) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// hclValue renders v as an HCL literal
+func hclValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return hclString(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float32:
+		return hclFloat(float64(val))
+	case float64:
+		return hclFloat(val)
+	case time.Time:
+		return hclString(val.Format(time.RFC3339Nano)), nil
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			text, err := hclValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(keys))
+		for _, k := range keys {
+			text, err := hclValue(val[k])
+			if err != nil {
+				return "", err
+			}
+			key := k
+			if !isHCLIdentifier(k) {
+				key = hclString(k)
+			}
+			items = append(items, key+" = "+text)
+		}
+		return "{" + strings.Join(items, ", ") + "}", nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return hclString(fmt.Sprint(v)), nil
+}
+
+// hclString renders s as a quoted string, escaping %{ so it is not read
+// as a template directive
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '%':
+			b.WriteByte('%')
+			if strings.HasPrefix(s[i+1:], "{") {
+				b.WriteByte('%')
+			}
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// hclFloat renders f so it reads back as a number
+func hclFloat(f float64) (string, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("%v cannot be written in HCL", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s, nil
+}
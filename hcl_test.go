@@ -0,0 +1,125 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHCLCodec(t *testing.T) {
+	t.Setenv("HCL_DB_HOST", "db.local")
+
+	data := []byte(`# service config
+name    = "api"
+debug   = false
+ratio   = 0.25
+retries = -3
+tags    = ["a", "b",]
+labels  = {
+  team    = "core"
+  "k.8s" : "yes"
+}
+
+/* connections */
+database "primary" {
+  host = "${HCL_DB_HOST:localhost}"
+  port = 5432
+  dsn  = "$${not_a_placeholder} 100%%{x}"
+}
+database "replica" { port = 5433 }
+
+listener {
+  port = 80
+}
+listener {
+  port = 443 // tls
+}
+
+motd = <<-EOT
+    Hello ${HCL_GREETING_NAME:friend}
+      welcome
+    EOT
+`)
+	path := filepath.Join(t.TempDir(), "app.hcl")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	p := NewProfile()
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"name", "api"},
+		{"ratio", "0.25"},
+		{"retries", "-3"},
+		{"tags.1", "b"},
+		{"labels.[k.8s]", "yes"},
+		{"database.primary.host", "db.local"},
+		{"database.primary.dsn", "${not_a_placeholder} 100%{x}"},
+		{"database.replica.port", "5433"},
+		{"listener.1.port", "443"},
+		{"motd", "Hello friend\n  welcome\n"},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+
+	var cfg struct {
+		Database map[string]struct {
+			Port int `yaml:"port"`
+		} `yaml:"database"`
+		Listener []struct {
+			Port int `yaml:"port"`
+		} `yaml:"listener"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, cfg.Database["primary"].Port, 5432, "primary port")
+	assert(t, len(cfg.Listener), 2, "listeners")
+
+	t.Run("marshal", func(t *testing.T) {
+		var tree map[string]interface{}
+		if err := (hclCodec{}).Unmarshal(data, &tree); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		out, err := hclCodec{}.Marshal(tree)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var back map[string]interface{}
+		if err := (hclCodec{}).Unmarshal(out, &back); err != nil {
+			t.Fatalf("failed to read marshaled HCL: %v\n%s", err, out)
+		}
+		if !reflect.DeepEqual(back, tree) {
+			t.Errorf("round trip = %v, want %v", back, tree)
+		}
+	})
+
+	for _, bad := range []string{
+		`a = var.x`,
+		`a = 1 + 2`,
+		`a = upper("x")`,
+		`a = "%{ if x }y%{ endif }"`,
+		`a = 1` + "\n" + `a = 2`,
+		`a = 1` + "\n" + `a { }`,
+		`block {`,
+		`a = "open`,
+		"a = <<EOT\ntext\n",
+	} {
+		var tree map[string]interface{}
+		if err := (hclCodec{}).Unmarshal([]byte(bad), &tree); !errors.Is(err, ErrHCL) {
+			t.Errorf("%q: expected ErrHCL but got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package dollarYaml
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrIncludeCycle reports a file that includes itself, directly or through
+// other files
+var ErrIncludeCycle = errors.New("include cycle")
+
+// includeKey splices the files it names into the map holding it, as in
+// $include: ./common.yaml, and tagInclude replaces a scalar with the tree
+// of the file it names, as in db: !include ./db.yaml
+const (
+	includeKey = "$include"
+	tagInclude = "!include"
+)
+
+// includer expands the includes of the files read by ReadFromPath,
+// ReadFromFS, FileSource and FSSource. Relative names resolve against the
+// directory of the including file.
+type includer struct {
+	read  func(name string) ([]byte, error)
+	join  func(from, name string) string
+	lists ListMergeStrategy
+	// stack holds the files being expanded, outermost first
+	stack []string
+	// files lists every file included, in the order they were read
+	files []string
+}
+
+// osIncluder reads included files from disk
+func osIncluder(lists ListMergeStrategy) *includer {
+	return &includer{
+		read: os.ReadFile,
+		join: func(from, name string) string {
+			if filepath.IsAbs(name) {
+				return filepath.Clean(name)
+			}
+			return filepath.Join(filepath.Dir(from), name)
+		},
+		lists: lists,
+	}
+}
+
+// fsIncluder reads included files from fsys, where names are always
+// slash separated and relative to its root
+func fsIncluder(fsys fs.FS, lists ListMergeStrategy) *includer {
+	return &includer{
+		read: func(name string) ([]byte, error) {
+			return fs.ReadFile(fsys, name)
+		},
+		join: func(from, name string) string {
+			return pathpkg.Join(pathpkg.Dir(from), name)
+		},
+		lists: lists,
+	}
+}
+
+// expand replaces the includes in tree, the contents of file, with the
+// trees of the files they name. tags holds the custom tags of tree and
+// gains those of the included files; it may be nil for untagged trees.
+func (inc *includer) expand(tree interface{}, tags map[string]string, file string) (interface{}, error) {
+	inc.stack = append(inc.stack, file)
+	defer func() { inc.stack = inc.stack[:len(inc.stack)-1] }()
+	return inc.value(tree, tags, file, "")
+}
+
+// value expands the includes in v, the value at path in file
+func (inc *includer) value(v interface{}, tags map[string]string, file, path string) (interface{}, error) {
+	if tags[path] == tagInclude {
+		name, ok := v.(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s: %s: %s needs a file name", file, path, tagInclude)
+		}
+		delete(tags, path)
+		return inc.include(file, []string{name}, tags, path)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		var included interface{}
+		spec, hasInclude := val[includeKey]
+		if hasInclude {
+			names, err := includeNames(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", file, joinKey(path, includeKey), err)
+			}
+			delete(val, includeKey)
+			delete(tags, joinKey(path, includeKey))
+			if included, err = inc.include(file, names, tags, path); err != nil {
+				return nil, err
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			expanded, err := inc.value(val[k], tags, file, joinKey(path, k))
+			if err != nil {
+				return nil, err
+			}
+			val[k] = expanded
+		}
+		if !hasInclude {
+			return val, nil
+		}
+		if len(val) == 0 {
+			return included, nil
+		}
+		m := merger{lists: inc.lists, tags: tags}
+		return m.value(included, val, path), nil
+	case []interface{}:
+		for i, item := range val {
+			expanded, err := inc.value(item, tags, file, joinPath(path, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			val[i] = expanded
+		}
+	}
+	return v, nil
+}
+
+// include reads the files named from file and merges their trees in
+// order, recording their tags under path
+func (inc *includer) include(from string, names []string, tags map[string]string, path string) (interface{}, error) {
+	var result interface{}
+	for i, name := range names {
+		target := inc.join(from, name)
+		for _, open := range inc.stack {
+			if open == target {
+				chain := append(append([]string{}, inc.stack...), target)
+				return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(chain, " -> "))
+			}
+		}
+		data, err := inc.read(target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: including %s: %w", from, name, err)
+		}
+		other := NewProfile(WithListMergeStrategy(inc.lists))
+		otherTree, otherTags, err := other.parseFile(data, filepath.Ext(target))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+		inc.files = append(inc.files, target)
+		tree, err := inc.expand(normalizeTree(otherTree), otherTags, target)
+		if err != nil {
+			return nil, err
+		}
+
+		fileTags := make(map[string]string, len(otherTags))
+		for k, v := range otherTags {
+			fileTags[joinPath(path, k)] = v
+		}
+		if i == 0 {
+			result = tree
+		} else {
+			m := merger{lists: inc.lists, tags: fileTags}
+			result = m.value(result, tree, path)
+		}
+		if tags == nil {
+			continue
+		}
+		for k, v := range fileTags {
+			if !isMergeTag(v) {
+				tags[k] = v
+			}
+		}
+	}
+	return result, nil
+}
+
+// includeNames returns the file names given to $include, a single name or
+// a list of them
+func includeNames(spec interface{}) ([]string, error) {
+	switch val := spec.(type) {
+	case string:
+		if val != "" {
+			return []string{val}, nil
+		}
+	case []interface{}:
+		names := make([]string, 0, len(val))
+		for _, item := range val {
+			name, ok := item.(string)
+			if !ok || name == "" {
+				return nil, errors.New("include needs file names")
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	return nil, errors.New("include needs a file name or a list of them")
+}
+
+// readIncluding loads data, the contents of the file name modified at
+// modified, with its includes expanded by inc
+func (p *YamlProfile) readIncluding(data []byte, name string, inc *includer, modified time.Time) error {
+	tree, tags, err := p.parseFile(data, filepath.Ext(name))
+	if err != nil {
+		return err
+	}
+	tree, err = inc.expand(normalizeTree(tree), tags, name)
+	if err != nil {
+		return err
+	}
+	if len(inc.files) > 0 {
+		if data, err = yaml.Marshal(tree); err != nil {
+			return err
+		}
+	}
+	sources := append([]string{name}, inc.files...)
+	p.loadWith(data, tree, tags, func() {
+		p.sources = sources
+		p.sourceTime = modified
+	})
+	return nil
+}
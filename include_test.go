@@ -0,0 +1,167 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+var includeFiles = map[string]string{
+	"app.yaml": "$include: ./common/base.yaml\n" +
+		"server:\n  port: 9090\n" +
+		"database: !include common/db.yaml\n" +
+		"features:\n  $include: [flags/a.yaml, flags/b.json]\n  beta: true\n",
+	"common/base.yaml": "name: app\nserver:\n  host: 0.0.0.0\n  port: 8080\n",
+	"common/db.yaml":   "$include: ../shared/pool.yaml\nhost: ${INCLUDE_DB_HOST:localhost}\nsecret: !vault db/password\n",
+	"shared/pool.yaml": "pool: 10\nhost: ignored\n",
+	"flags/a.yaml":     "alpha: true\nbeta: false\n",
+	"flags/b.json":     `{"gamma": 3}`,
+}
+
+func checkIncludes(t *testing.T, p *YamlProfile) {
+	t.Helper()
+	assert(t, p.Get("name"), "app", "name")
+	assert(t, p.Get("server.host"), "0.0.0.0", "server.host")
+	assert(t, p.Get("server.port"), "9090", "server.port")
+	assert(t, p.Get("database.host"), "localhost", "database.host")
+	assert(t, p.Get("database.pool"), "10", "database.pool")
+	assert(t, p.Get("features.alpha"), "true", "features.alpha")
+	assert(t, p.Get("features.beta"), "true", "features.beta")
+	assert(t, p.Get("features.gamma"), "3", "features.gamma")
+	if _, ok := p.data.(map[string]interface{})[includeKey]; ok {
+		t.Error("$include key should be removed")
+	}
+	tag, _ := p.Tag("database.secret")
+	assert(t, tag, "!vault", "tag of included value")
+	if _, ok := p.Tag("database"); ok {
+		t.Error("!include tag should be removed")
+	}
+}
+
+func TestYamlProfile_ReadFromPathInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, includeFiles)
+
+	p := NewProfile()
+	if err := p.ReadFromPath(filepath.Join(dir, "app.yaml")); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	checkIncludes(t, p)
+	want := []string{
+		filepath.Join(dir, "app.yaml"),
+		filepath.Join(dir, "common", "base.yaml"),
+		filepath.Join(dir, "common", "db.yaml"),
+		filepath.Join(dir, "shared", "pool.yaml"),
+		filepath.Join(dir, "flags", "a.yaml"),
+		filepath.Join(dir, "flags", "b.json"),
+	}
+	if !reflect.DeepEqual(p.sources, want) {
+		t.Errorf("sources = %v, want %v", p.sources, want)
+	}
+}
+
+func TestYamlProfile_ReadFromFSInclude(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for name, content := range includeFiles {
+		fsys["conf/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	p := NewProfile()
+	if err := p.ReadFromFS(fsys, "conf/app.yaml"); err != nil {
+		t.Fatalf("ReadFromFS failed: %v", err)
+	}
+	checkIncludes(t, p)
+	assert(t, p.sources[1], "conf/common/base.yaml", "included source")
+}
+
+func TestYamlProfile_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		cycle bool
+	}{
+		{
+			name:  "self",
+			files: map[string]string{"app.yaml": "$include: app.yaml\n"},
+			cycle: true,
+		},
+		{
+			name: "indirect",
+			files: map[string]string{
+				"app.yaml":   "a: !include sub/a.yaml\n",
+				"sub/a.yaml": "$include: ../b.yaml\n",
+				"b.yaml":     "$include: ./app.yaml\n",
+			},
+			cycle: true,
+		},
+		{
+			name:  "missing",
+			files: map[string]string{"app.yaml": "$include: missing.yaml\n"},
+		},
+		{
+			name:  "not a name",
+			files: map[string]string{"app.yaml": "$include: {a: 1}\n"},
+		},
+		{
+			name:  "tagged map",
+			files: map[string]string{"app.yaml": "a: !include {b: 1}\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			p := NewProfile()
+			err := p.ReadFromPath(filepath.Join(dir, "app.yaml"))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			assert(t, errors.Is(err, ErrIncludeCycle), tt.cycle, "cycle: "+err.Error())
+		})
+	}
+}
+
+func TestYamlProfile_IncludeSharedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.yaml":    "a: !include shared.yaml\nb: !include shared.yaml\n",
+		"shared.yaml": "x: 1\n",
+	})
+	p := NewProfile()
+	if err := p.ReadFromPath(filepath.Join(dir, "app.yaml")); err != nil {
+		t.Fatalf("a file included twice is not a cycle: %v", err)
+	}
+	assert(t, p.Get("a.x"), "1", "a.x")
+	assert(t, p.Get("b.x"), "1", "b.x")
+}
+
+func TestFileSourceInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.yaml":  "$include: base.yaml\nport: 9090\n",
+		"base.yaml": "host: localhost\nport: 8080\n",
+	})
+	p, err := NewBuilder().Add(FileSource(filepath.Join(dir, "app.yaml"))).Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	assert(t, p.Get("host"), "localhost", "host")
+	assert(t, p.Get("port"), "9090", "port")
+}
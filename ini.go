@@ -0,0 +1,238 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrINI = errors.New("invalid INI")
+
+func init() {
+	RegisterCodec(iniCodec{}, ".ini")
+}
+
+// ReadINI loads INI data, for migrating legacy configs. Sections and dotted
+// keys become nested maps; placeholders in values are resolved as in YAML.
+func (p *YamlProfile) ReadINI(data []byte) error {
+	return p.ReadAs(data, ".ini")
+}
+
+// iniCodec is the built-in INI codec. Sections and dotted keys become
+// nested maps, so [database.master] port=5432 is read as
+// database.master.port, and keys ending in [] collect their values in a
+// list. Lines starting with ; or # are comments, as is text after " ;"
+// or " #" in unquoted values. Values are typed as in YAML.
+type iniCodec struct{}
+
+func (iniCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	root := map[string]interface{}{}
+	section := root
+	var sectionKeys []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		lineErr := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%w: line %d: %s", ErrINI, i+1, fmt.Sprintf(format, args...))
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 || !iniComment(line[end+1:]) {
+				return lineErr("malformed section header")
+			}
+			sectionKeys = splitINIKey(line[1:end])
+			var err error
+			if section, err = nestedMap(root, sectionKeys); err != nil {
+				return lineErr("%v", err)
+			}
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep <= 0 {
+			return lineErr("expected key = value")
+		}
+		key := strings.TrimSpace(line[:sep])
+		value := iniValue(strings.TrimSpace(line[sep+1:]))
+		keys := splitINIKey(strings.TrimSuffix(key, "[]"))
+		parent, err := nestedMap(section, keys[:len(keys)-1])
+		if err != nil {
+			return lineErr("%v", err)
+		}
+		last := keys[len(keys)-1]
+		if _, isMap := parent[last].(map[string]interface{}); isMap {
+			return lineErr("%s is already a section", key)
+		}
+		if strings.HasSuffix(key, "[]") {
+			list, _ := parent[last].([]interface{})
+			parent[last] = append(list, value)
+		} else {
+			parent[last] = value
+		}
+	}
+	*out = root
+	return nil
+}
+
+// splitINIKey splits a dotted section name or key, trimming each part
+func splitINIKey(key string) []string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// iniValue unquotes or strips the comment from a raw value and types it
+func iniValue(raw string) interface{} {
+	if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') {
+		if end := strings.IndexByte(raw[1:], raw[0]); end >= 0 && iniComment(raw[end+2:]) {
+			return raw[1 : end+1]
+		}
+	}
+	for _, marker := range []string{" ;", " #", "\t;", "\t#"} {
+		if i := strings.Index(raw, marker); i >= 0 {
+			raw = strings.TrimSpace(raw[:i])
+		}
+	}
+	return typedText(raw)
+}
+
+// iniComment reports whether s holds only blanks and a comment
+func iniComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || s[0] == ';' || s[0] == '#'
+}
+
+// nestedMap returns the map at keys below m, creating missing maps
+func nestedMap(m map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for i, k := range keys {
+		switch next := m[k].(type) {
+		case nil:
+			child := map[string]interface{}{}
+			m[k] = child
+			m = child
+		case map[string]interface{}:
+			m = next
+		default:
+			return nil, fmt.Errorf("%s is already a value", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return m, nil
+}
+
+// setLeaf sets the value at keys below m, creating missing maps. It fails
+// rather than replace a value with a map or a map with a value.
+func setLeaf(m map[string]interface{}, keys []string, value interface{}) error {
+	parent, err := nestedMap(m, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, isMap := parent[last].(map[string]interface{}); isMap {
+		return fmt.Errorf("%s already has nested keys", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// typedText converts text read from a format without types to the int,
+// float or bool YAML would read it as. Numbers are only converted when
+// they read back unchanged, so text such as 08540 stays a string.
+func typedText(s string) interface{} {
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, err := strconv.Atoi(s); err == nil && strconv.Itoa(n) == s {
+		return n
+	}
+	if strings.ContainsAny(s, ".eE") && s != "" && (isDigit(s[0]) || s[0] == '-' && len(s) > 1 && isDigit(s[1])) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+func (iniCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeINISection(&buf, nil, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeINISection writes the values of m, then each nested map as its
+// own section named by its dotted path
+func encodeINISection(buf *bytes.Buffer, path []string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sections []string
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case map[string]interface{}:
+			sections = append(sections, k)
+		case []interface{}:
+			for _, item := range v {
+				if !isScalar(item) {
+					return fmt.Errorf("%w: %s holds a list of maps or lists", ErrINI, strings.Join(append(path, k), "."))
+				}
+				text, err := iniText(item)
+				if err != nil {
+					return fmt.Errorf("%w: %s: %v", ErrINI, strings.Join(append(path, k), "."), err)
+				}
+				buf.WriteString(k + "[] = " + text + "\n")
+			}
+		default:
+			text, err := iniText(v)
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrINI, strings.Join(append(path, k), "."), err)
+			}
+			buf.WriteString(k + " = " + text + "\n")
+		}
+	}
+	for _, k := range sections {
+		sub := append(append([]string{}, path...), k)
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("[" + strings.Join(sub, ".") + "]\n")
+		if err := encodeINISection(buf, sub, m[k].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iniText renders a scalar, quoting text that would not read back as it
+// is. INI has no escapes, so line breaks cannot be written.
+func iniText(v interface{}) (string, error) {
+	s := scalarText(v)
+	if _, isString := v.(string); !isString {
+		return s, nil
+	}
+	switch {
+	case strings.ContainsAny(s, "\r\n"):
+		return "", errors.New("line breaks cannot be written in INI")
+	case s == strings.TrimSpace(s) && !strings.ContainsAny(s, ";#\"'") && typedText(s) == interface{}(s):
+		return s, nil
+	case !strings.Contains(s, `"`):
+		return `"` + s + `"`, nil
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'", nil
+	}
+	return "", errors.New("text with both quote characters cannot be written in INI")
+}
@@ -0,0 +1,119 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestINICodec(t *testing.T) {
+	t.Setenv("INI_DB_HOST", "db.local")
+
+	data := []byte(`; legacy config
+name = app
+debug = true
+
+[database]
+host = ${INI_DB_HOST:localhost}
+port = 5432 ; inline comment
+zip = 08540
+password = "p;ss # word"
+hosts[] = a
+hosts[] = b
+
+[database.master]
+ratio: 0.5
+pool.max = 16
+`)
+	path := filepath.Join(t.TempDir(), "app.ini")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	p := NewProfile()
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"name", "app"},
+		{"database.host", "db.local"},
+		{"database.port", "5432"},
+		{"database.zip", "08540"},
+		{"database.password", "p;ss # word"},
+		{"database.hosts.1", "b"},
+		{"database.master.ratio", "0.5"},
+		{"database.master.pool.max", "16"},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+
+	var cfg struct {
+		Debug    bool `yaml:"debug"`
+		Database struct {
+			Port int    `yaml:"port"`
+			Zip  string `yaml:"zip"`
+		} `yaml:"database"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, cfg.Debug, true, "debug")
+	assert(t, cfg.Database.Port, 5432, "port")
+	assert(t, cfg.Database.Zip, "08540", "zip")
+
+	t.Run("marshal", func(t *testing.T) {
+		var tree map[string]interface{}
+		if err := (iniCodec{}).Unmarshal(data, &tree); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		out, err := iniCodec{}.Marshal(tree)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		want := `debug = true
+name = app
+
+[database]
+host = ${INI_DB_HOST:localhost}
+hosts[] = a
+hosts[] = b
+password = "p;ss # word"
+port = 5432
+zip = 08540
+
+[database.master]
+ratio = 0.5
+
+[database.master.pool]
+max = 16
+`
+		assert(t, string(out), want, "Marshal")
+		var back map[string]interface{}
+		if err := (iniCodec{}).Unmarshal(out, &back); err != nil {
+			t.Fatalf("failed to read marshaled INI: %v", err)
+		}
+		if !reflect.DeepEqual(back, tree) {
+			t.Errorf("round trip = %v, want %v", back, tree)
+		}
+		if _, err := (iniCodec{}).Marshal(map[string]interface{}{"a": "two\nlines"}); !errors.Is(err, ErrINI) {
+			t.Errorf("expected ErrINI but got %v", err)
+		}
+	})
+
+	for _, bad := range []string{"[open", "novalue", "a = 1\n[a]", "[a]\nb = 1\n[a.b]", "[s]\nb.c = 1\nb = 2"} {
+		var tree map[string]interface{}
+		if err := (iniCodec{}).Unmarshal([]byte(bad), &tree); !errors.Is(err, ErrINI) {
+			t.Errorf("%q: expected ErrINI but got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package dollarYaml
+
+import (
+	"context"
+	"io/fs"
+)
+
+// ConfigReader is the read-only view of a configuration. Libraries should
+// accept a ConfigReader rather than a *YamlProfile so callers can pass any
+// implementation, including test doubles.
+type ConfigReader interface {
+	Get(path string) string
+	GetError(path string) (string, error)
+	Has(path string) bool
+	SubReader(path string) ConfigReader
+	UnmarshalKey(path string, target interface{}) error
+	UnmarshalTo(target interface{}) error
+}
+
+// ContextReader is implemented by readers whose resolution can be bounded
+// and cancelled with a context
+type ContextReader interface {
+	GetContext(ctx context.Context, path string) (string, error)
+	UnmarshalToContext(ctx context.Context, target interface{}) error
+}
+
+// ConfigWriter changes a loaded configuration in place
+type ConfigWriter interface {
+	Set(path string, value interface{}) error
+	Merge(other *YamlProfile) error
+}
+
+// ConfigLoader loads configuration sources into a profile and reports
+// when they are loaded again. It has no Watch: a profile does not know
+// its sources, so watching them is left to the Builder or Layers that
+// built it, and OnChange is how a loader's users see the reloads.
+type ConfigLoader interface {
+	Read(data []byte) error
+	ReadAs(data []byte, format string) error
+	ReadFromPath(path string) error
+	ReadFromFS(fsys fs.FS, name string) error
+	OnChange(fn func(ChangeEvent)) (cancel func())
+}
+
+// Config is the full API of a profile, split into its narrow parts
+type Config interface {
+	ConfigReader
+	ConfigWriter
+	ConfigLoader
+}
+
+var (
+	_ ConfigReader  = (*YamlProfile)(nil)
+	_ ContextReader = (*YamlProfile)(nil)
+	_ ConfigWriter  = (*YamlProfile)(nil)
+	_ ConfigLoader  = (*YamlProfile)(nil)
+	_ Config        = (*YamlProfile)(nil)
+)
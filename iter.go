@@ -0,0 +1,65 @@
+//go:build go1.23
+
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strconv"
+)
+
+// errStopIteration ends a walk when the consumer of an iterator stops
+var errStopIteration = errors.New("stop iteration")
+
+// Entries returns an iterator over every leaf of the config with its path,
+// usable with Get, and its value resolved as by AllSettings, in the order
+// of AllKeys:
+//
+//	for path, value := range p.Entries() {
+//		fmt.Println(path, value)
+//	}
+//
+// Values that fail to resolve keep their placeholder text.
+func (p *YamlProfile) Entries() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		tree := p.settingsOf(context.Background(), p.base, p.root())
+		walkTree("", tree, func(path string, value interface{}) error {
+			if !yield(path, value) {
+				return errStopIteration
+			}
+			return nil
+		})
+	}
+}
+
+// Keys returns an iterator over the resolved keys of the map at path, in
+// sorted order, or the indices of the list at path. An empty path
+// iterates the root; a missing path or a scalar yields nothing.
+func (p *YamlProfile) Keys(path string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		var value interface{} = p.root()
+		rawPath := ""
+		if path != "" {
+			var err error
+			if value, rawPath, err = p.lookup(path); err != nil {
+				return
+			}
+		}
+		switch val := value.(type) {
+		case map[string]interface{}:
+			names := p.settingKeys(context.Background(), joinPath(p.base, rawPath), val)
+			for _, k := range sortedKeys(names) {
+				if !yield(names[k]) {
+					return
+				}
+			}
+		case []interface{}:
+			for i := range val {
+				if !yield(strconv.Itoa(i)) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+//go:build go1.23
+
+package dollarYaml
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestYamlProfile_Entries(t *testing.T) {
+	t.Setenv("ITER_REGION", "eu")
+
+	yamlData := []byte(`
+server:
+  port: 8080
+  host: ${ITER_HOST:localhost}
+regions:
+  ${ITER_REGION:us}: {endpoint: e}
+tags: [a, b]
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var paths []string
+	values := map[string]any{}
+	for path, value := range p.Entries() {
+		paths = append(paths, path)
+		values[path] = value
+	}
+	if !reflect.DeepEqual(paths, p.AllKeys()) {
+		t.Errorf("iterated %v, want the AllKeys order %v", paths, p.AllKeys())
+	}
+	assert(t, values["server.port"], 8080, "server.port")
+	assert(t, values["server.host"], "localhost", "server.host")
+
+	n := 0
+	for range p.Entries() {
+		n++
+		break
+	}
+	assert(t, n, 1, "entries after break")
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"", []string{"regions", "server", "tags"}},
+		{"server", []string{"host", "port"}},
+		{"regions", []string{"eu"}},
+		{"tags", []string{"0", "1"}},
+		{"server.port", nil},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		got := slices.Collect(p.Keys(tt.path))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Keys(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	sub := p.Sub("server")
+	if got := slices.Collect(sub.Keys("")); !reflect.DeepEqual(got, []string{"host", "port"}) {
+		t.Errorf("Sub(server).Keys() = %v", got)
+	}
+}
@@ -0,0 +1,523 @@
+package dollarYaml
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubernetesConfig configures the ${k8s-secret:...} and ${k8s-configmap:...}
+// resolvers. With no fields set the in-cluster service account is used
+// when running in a pod, and the current kubeconfig context otherwise.
+type KubernetesConfig struct {
+	// Kubeconfig is the kubeconfig file to read; defaults to KUBECONFIG or
+	// ~/.kube/config when not running in a cluster
+	Kubeconfig string
+	// Context selects a kubeconfig context instead of current-context
+	Context string
+	// Server and Token override the API server URL and bearer token
+	Server string
+	Token  string
+	// Timeout bounds each request to the API server; defaults to 10 seconds
+	Timeout time.Duration
+	// CacheTTL is how long a fetched object is reused; defaults to 5
+	// minutes, and a negative value disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the client used to reach the API server,
+	// including its TLS configuration
+	HTTPClient *http.Client
+}
+
+// kubernetesResolver reads keys of Secrets and ConfigMaps from the API server
+type kubernetesResolver struct {
+	cfg       KubernetesConfig
+	cache     *ttlCache
+	tokenFile string
+	err       error
+}
+
+func newKubernetesResolver(cfg KubernetesConfig) *kubernetesResolver {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	r := &kubernetesResolver{cfg: cfg, cache: newTTLCache(cfg.CacheTTL)}
+	if cfg.Server == "" {
+		r.err = r.configure()
+	}
+	if r.cfg.HTTPClient == nil {
+		r.cfg.HTTPClient = http.DefaultClient
+	}
+	return r
+}
+
+// configure fills in the server, credentials and client from the pod's
+// service account or from kubeconfig
+func (r *kubernetesResolver) configure() error {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host != "" && port != "" && r.cfg.Kubeconfig == "" {
+		r.cfg.Server = "https://" + net.JoinHostPort(host, port)
+		if r.cfg.Token == "" {
+			r.tokenFile = defaultKubernetesTokenPath
+		}
+		if r.cfg.HTTPClient == nil {
+			ca, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+			if err != nil {
+				return err
+			}
+			client, err := kubernetesClient(ca, nil, nil)
+			if err != nil {
+				return err
+			}
+			r.cfg.HTTPClient = client
+		}
+		return nil
+	}
+	return r.loadKubeconfig()
+}
+
+// kubeconfig is the subset of the kubeconfig format used here
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// loadKubeconfig reads the server and credentials of the selected context.
+// Exec and auth-provider plugins are not supported; use Token instead.
+func (r *kubernetesResolver) loadKubeconfig() error {
+	path := r.cfg.Kubeconfig
+	if path == "" {
+		path = strings.Split(os.Getenv("KUBECONFIG"), string(os.PathListSeparator))[0]
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(home, ".kube", "config")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("no in-cluster config and no kubeconfig: %w", err)
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+
+	name := r.cfg.Context
+	if name == "" {
+		name = kc.CurrentContext
+	}
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == name {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("context %q not found in %s", name, path)
+	}
+
+	var ca []byte
+	insecure := false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		r.cfg.Server = c.Cluster.Server
+		insecure = c.Cluster.InsecureSkipTLSVerify
+		if ca, err = kubeconfigData(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority, dir); err != nil {
+			return err
+		}
+	}
+	if r.cfg.Server == "" {
+		return fmt.Errorf("cluster %q not found in %s", clusterName, path)
+	}
+
+	var cert, key []byte
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		if r.cfg.Token == "" {
+			r.cfg.Token = u.User.Token
+		}
+		if r.cfg.Token == "" && u.User.TokenFile != "" {
+			r.tokenFile = resolveKubeconfigPath(u.User.TokenFile, dir)
+		}
+		if cert, err = kubeconfigData(u.User.ClientCertificateData, u.User.ClientCertificate, dir); err != nil {
+			return err
+		}
+		if key, err = kubeconfigData(u.User.ClientKeyData, u.User.ClientKey, dir); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.HTTPClient == nil {
+		client, err := kubernetesClient(ca, cert, key)
+		if err != nil {
+			return err
+		}
+		if insecure {
+			client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+		}
+		r.cfg.HTTPClient = client
+	}
+	return nil
+}
+
+// kubeconfigData returns inline base64 data or the contents of file
+func kubeconfigData(data, file, dir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(resolveKubeconfigPath(file, dir))
+	}
+	return nil, nil
+}
+
+// resolveKubeconfigPath resolves paths relative to the kubeconfig file
+func resolveKubeconfigPath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// kubernetesClient builds an HTTP client trusting ca and presenting the
+// client certificate, if any
+func kubernetesClient(ca, cert, key []byte) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificates found in cluster CA")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(cert) > 0 && len(key) > 0 {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// resolveSecret returns a key of a Secret, namespace/name/key
+func (r *kubernetesResolver) resolveSecret(ctx context.Context, key string) (string, error) {
+	return r.resolve(ctx, "secrets", key)
+}
+
+// resolveConfigMap returns a key of a ConfigMap, namespace/name/key
+func (r *kubernetesResolver) resolveConfigMap(ctx context.Context, key string) (string, error) {
+	return r.resolve(ctx, "configmaps", key)
+}
+
+func (r *kubernetesResolver) resolve(ctx context.Context, resource, key string) (string, error) {
+	if r.err != nil {
+		return "", fmt.Errorf("kubernetes config: %w", r.err)
+	}
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", fmt.Errorf("kubernetes key %q is not namespace/name/key", key)
+	}
+	namespace, name, field := parts[0], parts[1], parts[2]
+
+	cached, err := r.cache.get(ctx, resource+"/"+namespace+"/"+name, func() (interface{}, error) {
+		return r.object(ctx, resource, namespace, name)
+	})
+	if err != nil {
+		return "", err
+	}
+	data := cached.(map[string]string)
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("%w: key %s in %s %s/%s", ErrValueNotFound, field, resource, namespace, name)
+	}
+	return value, nil
+}
+
+// object fetches a Secret or ConfigMap and returns its decoded data
+func (r *kubernetesResolver) object(ctx context.Context, resource, namespace, name string) (map[string]string, error) {
+	data, _, err := r.fetchObject(ctx, resource, namespace, name)
+	return data, err
+}
+
+// fetchObject fetches a Secret or ConfigMap and returns its decoded data
+// and resource version
+func (r *kubernetesResolver) fetchObject(ctx context.Context, resource, namespace, name string) (map[string]string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	resp, err := r.open(ctx, "/api/v1/namespaces/"+namespace+"/"+resource+"/"+name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, "", fmt.Errorf("%w: %s %s/%s", ErrValueNotFound, resource, namespace, name)
+	default:
+		return nil, "", fmt.Errorf("kubernetes %s %s/%s: %s", resource, namespace, name, resp.Status)
+	}
+
+	var out kubernetesObject
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, "", err
+	}
+	data, err := out.decode(resource)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s/%s: %w", namespace, name, err)
+	}
+	return data, out.Metadata.ResourceVersion, nil
+}
+
+// open sends an authenticated GET for path to the API server
+func (r *kubernetesResolver) open(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(r.cfg.Server, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := r.cfg.Token
+	if r.tokenFile != "" {
+		// Projected service account tokens rotate, so read it every time
+		data, err := os.ReadFile(r.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+	return r.cfg.HTTPClient.Do(req)
+}
+
+// kubernetesObject is the part of a Secret or ConfigMap read here
+type kubernetesObject struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data       map[string]string `json:"data"`
+	BinaryData map[string]string `json:"binaryData"`
+}
+
+// decode returns the data of a Secret or ConfigMap object
+func (out kubernetesObject) decode(resource string) (map[string]string, error) {
+	data := make(map[string]string, len(out.Data)+len(out.BinaryData))
+	encoded := out.BinaryData
+	if resource == "secrets" {
+		// Secret data is base64 encoded, ConfigMap data is plain text
+		encoded = out.Data
+	} else {
+		for k, v := range out.Data {
+			data[k] = v
+		}
+	}
+	for k, v := range encoded {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", k, err)
+		}
+		data[k] = string(decoded)
+	}
+	return data, nil
+}
+
+// configMapSource loads a config from a Kubernetes ConfigMap
+type configMapSource struct {
+	r               *kubernetesResolver
+	namespace, name string
+	key             string
+
+	mu              sync.Mutex
+	resourceVersion string
+}
+
+// ConfigMapSource returns a Source loading the ConfigMap name in
+// namespace through the API server, using the in-cluster service account
+// or kubeconfig as the ${k8s-configmap:...} resolver does. With a key,
+// that entry holds a whole config, decoded with the codec registered for
+// its extension and as YAML by default. Without one, the whole ConfigMap
+// is read as a tree: every entry becomes a path, split at dots, whose
+// value is typed as in YAML, so an entry database.port is read as
+// database.port. The source is a WatchableSource using the watch API,
+// so Builder.Watch reloads the profile when the ConfigMap is updated
+// without mounting it as a volume.
+//
+// Unlike a client-go informer, which this module cannot depend on, the
+// source calls the API server's REST endpoints directly: each Watch opens
+// a single watch request from the version last loaded, with no local
+// cache, resync period or shared informer between sources.
+func ConfigMapSource(cfg KubernetesConfig, namespace, name, key string) Source {
+	return &configMapSource{r: newKubernetesResolver(cfg), namespace: namespace, name: name, key: key}
+}
+
+func (s *configMapSource) Name() string {
+	name := "configmap:" + s.namespace + "/" + s.name
+	if s.key != "" {
+		name += "/" + s.key
+	}
+	return name
+}
+
+func (s *configMapSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	if s.r.err != nil {
+		return nil, fmt.Errorf("kubernetes config: %w", s.r.err)
+	}
+	data, version, err := s.r.fetchObject(ctx, "configmaps", s.namespace, s.name)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	if s.key != "" {
+		value, ok := data[s.key]
+		if !ok {
+			return nil, fmt.Errorf("%w: key %s in configmaps %s/%s", ErrValueNotFound, s.key, s.namespace, s.name)
+		}
+		if tree, err = decodeSource([]byte(value), filepath.Ext(s.key)); err != nil {
+			return nil, err
+		}
+	} else {
+		tree = make(map[string]interface{})
+		for _, k := range sortedKeys(data) {
+			if err := setLeaf(tree, strings.Split(k, "."), typedText(data[k])); err != nil {
+				return nil, fmt.Errorf("configmaps %s/%s: %w", s.namespace, s.name, err)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.resourceVersion = version
+	s.mu.Unlock()
+	return tree, nil
+}
+
+// Watch watches the ConfigMap from the resource version of the last Load
+// and returns once it is modified, deleted or recreated, or once the
+// version is too old to watch from
+func (s *configMapSource) Watch(ctx context.Context) error {
+	if s.r.err != nil {
+		return fmt.Errorf("kubernetes config: %w", s.r.err)
+	}
+	s.mu.Lock()
+	version := s.resourceVersion
+	s.mu.Unlock()
+	if version == "" {
+		// Without a Load to start from, a watch would open with a
+		// synthetic ADDED event, so watch from the current version
+		_, current, err := s.r.fetchObject(ctx, "configmaps", s.namespace, s.name)
+		if err != nil {
+			return err
+		}
+		version = current
+	}
+
+	query := url.Values{
+		"watch":           {"1"},
+		"fieldSelector":   {"metadata.name=" + s.name},
+		"resourceVersion": {version},
+		"timeoutSeconds":  {"300"},
+	}
+	for {
+		resp, err := s.r.open(ctx, "/api/v1/namespaces/"+s.namespace+"/configmaps?"+query.Encode())
+		if err != nil {
+			return err
+		}
+		changed, err := s.readEvents(resp, version)
+		if changed || err != nil {
+			return err
+		}
+		// The server ended the watch after timeoutSeconds; open a new one
+	}
+}
+
+// readEvents reads watch events until the stream ends, reporting whether
+// one of them changed the ConfigMap
+func (s *configMapSource) readEvents(resp *http.Response, version string) (bool, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("kubernetes watch configmaps %s/%s: %s", s.namespace, s.name, resp.Status)
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event struct {
+			Type   string           `json:"type"`
+			Object kubernetesObject `json:"object"`
+		}
+		if err := dec.Decode(&event); err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("kubernetes watch configmaps %s/%s: %w", s.namespace, s.name, err)
+		}
+		switch event.Type {
+		case "ERROR":
+			// Usually 410 Gone: the version is too old, so load again
+			return true, nil
+		case "ADDED", "MODIFIED", "DELETED":
+			if event.Object.Metadata.ResourceVersion != version {
+				return true, nil
+			}
+		}
+	}
+}
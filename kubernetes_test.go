@@ -0,0 +1,284 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newKubernetesServer fakes the core/v1 Secret and ConfigMap endpoints
+func newKubernetesServer(t *testing.T, reads *int32) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(reads, 1)
+		switch r.URL.Path {
+		case "/api/v1/namespaces/prod/secrets/db":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"password": base64.StdEncoding.EncodeToString([]byte("s3cret"))},
+			})
+		case "/api/v1/namespaces/prod/configmaps/app":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data":       map[string]string{"log-level": "debug"},
+				"binaryData": map[string]string{"blob": base64.StdEncoding.EncodeToString([]byte("bin"))},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestYamlProfile_KubernetesScheme(t *testing.T) {
+	var reads int32
+	server := newKubernetesServer(t, &reads)
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("sa-token\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	kubeconfigPath := filepath.Join(dir, "config")
+	kubeconfig := fmt.Sprintf(`
+apiVersion: v1
+kind: Config
+current-context: other
+contexts:
+  - name: other
+    context: {cluster: missing, user: missing}
+  - name: test
+    context: {cluster: test, user: test}
+clusters:
+  - name: test
+    cluster:
+      server: %s
+      certificate-authority-data: %s
+users:
+  - name: test
+    user:
+      tokenFile: token
+`, server.URL, base64.StdEncoding.EncodeToString(ca))
+	if err := os.WriteFile(kubeconfigPath, []byte(kubeconfig), 0o600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	yamlData := []byte(`
+password: ${k8s-secret:prod/db/password}
+level: ${k8s-configmap:prod/app/log-level}
+blob: ${k8s-configmap:prod/app/blob}
+missingKey: ${k8s-secret:prod/db/user:admin}
+missingObject: ${k8s-configmap:prod/other/key:fallback}
+`)
+
+	configs := map[string]KubernetesConfig{
+		"kubeconfig": {Kubeconfig: kubeconfigPath, Context: "test"},
+		"explicit":   {Server: server.URL, Token: "sa-token", HTTPClient: server.Client()},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			p := New(false, WithKubernetes(cfg))
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+
+			tests := []struct {
+				path string
+				want string
+			}{
+				{"password", "s3cret"},
+				{"level", "debug"},
+				{"blob", "bin"},
+				{"missingKey", "admin"},
+				{"missingObject", "fallback"},
+			}
+			for _, tt := range tests {
+				got, err := p.GetError(tt.path)
+				if err != nil {
+					t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+				}
+				assert(t, got, tt.want, tt.path)
+			}
+
+			before := atomic.LoadInt32(&reads)
+			p.Get("password")
+			p.Get("missingKey")
+			if after := atomic.LoadInt32(&reads); after != before {
+				t.Errorf("expected cached lookups, got %d extra reads", after-before)
+			}
+		})
+	}
+}
+
+func TestYamlProfile_KubernetesSchemeErrors(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	p := New(false, WithKubernetes(KubernetesConfig{Kubeconfig: filepath.Join(t.TempDir(), "missing")}))
+	if err := p.Read([]byte("a: ${k8s-secret:ns/name/key}\nb: ${k8s-secret:ns/name}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := p.GetError("a"); err == nil {
+		t.Error("expected error without a kubeconfig")
+	}
+	if _, err := p.GetError("b"); err == nil {
+		t.Error("expected error for key without a field")
+	}
+}
+
+// configMapStore fakes a single ConfigMap with get and watch requests
+type configMapStore struct {
+	mu      sync.Mutex
+	data    map[string]string
+	version int
+	changed chan struct{}
+}
+
+func (s *configMapStore) update(data map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	s.version++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *configMapStore) object() map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]string{"resourceVersion": strconv.Itoa(s.version)},
+		"data":     s.data,
+	}
+}
+
+func (s *configMapStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v1/namespaces/prod/configmaps/app":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(s.object())
+	case "/api/v1/namespaces/prod/configmaps":
+		query := r.URL.Query()
+		if query.Get("watch") != "1" || query.Get("fieldSelector") != "metadata.name=app" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		since, _ := strconv.Atoi(query.Get("resourceVersion"))
+		w.(http.Flusher).Flush()
+		for {
+			s.mu.Lock()
+			version, changed := s.version, s.changed
+			if version > since {
+				json.NewEncoder(w).Encode(map[string]interface{}{"type": "MODIFIED", "object": s.object()})
+				s.mu.Unlock()
+				return
+			}
+			s.mu.Unlock()
+			select {
+			case <-changed:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestConfigMapSource(t *testing.T) {
+	store := &configMapStore{changed: make(chan struct{})}
+	store.update(map[string]string{
+		"application.yaml": "database:\n  host: db1\n",
+		"database.port":    "5432",
+		"name":             "api",
+	})
+	server := httptest.NewServer(store)
+	defer server.Close()
+	cfg := KubernetesConfig{Server: server.URL}
+
+	t.Run("tree", func(t *testing.T) {
+		p, err := NewBuilder().Add(ConfigMapSource(cfg, "prod", "app", "")).Build(context.Background())
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		assert(t, p.Get("name"), "api", "name")
+		port, err := p.GetInt("database.port")
+		if err != nil {
+			t.Fatalf("GetInt failed: %v", err)
+		}
+		assert(t, port, 5432, "typed port")
+		assert(t, p.Get("application.yaml"), "database:\n  host: db1\n", "file entry")
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		b := NewBuilder().Add(ConfigMapSource(cfg, "prod", "app", "application.yaml"))
+		p, err := b.Build(context.Background())
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		assert(t, p.Get("database.host"), "db1", "host")
+
+		reloaded := make(chan ChangeEvent, 1)
+		p.OnChange(func(ev ChangeEvent) { reloaded <- ev })
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- b.Watch(ctx, p) }()
+
+		store.update(map[string]string{"application.yaml": "database:\n  host: db2\n"})
+		select {
+		case ev := <-reloaded:
+			assert(t, ev.Reloaded, true, "Reloaded")
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload after the ConfigMap changed")
+		}
+		assert(t, p.Get("database.host"), "db2", "host after reload")
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("expected Watch to stop with context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("watch before load", func(t *testing.T) {
+		src := ConfigMapSource(cfg, "prod", "app", "").(WatchableSource)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := src.Watch(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected Watch to wait for a change, got %v", err)
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- src.Watch(context.Background()) }()
+		// Update until the watch, which may not have started yet, sees it
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Watch failed: %v", err)
+				}
+				return
+			case <-ticker.C:
+				store.update(map[string]string{"name": "api"})
+			case <-deadline:
+				t.Fatal("Watch did not return after the ConfigMap changed")
+			}
+		}
+	})
+
+	if _, err := NewBuilder().Add(ConfigMapSource(cfg, "prod", "app", "missing.yaml")).Build(context.Background()); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotLayered is returned by Explain for a profile not built by Layers
+var ErrNotLayered = errors.New("profile was not built by Layers")
+
+// Priorities for the usual layers, leaving room for layers in between
+const (
+	PriorityDefaults = 0
+	PriorityFile     = 100
+	PriorityEnv      = 200
+	PriorityFlags    = 300
+)
+
+// Layers assembles a profile from named sources ranked by priority, such
+// as defaults < file < env < flags. A lookup finds the value of the
+// highest priority layer setting it, falling through to lower layers for
+// keys it leaves out; maps are merged key by key and lists as set with
+// WithListMergeStrategy. Layers of equal priority rank in the order they
+// were added. The resulting profile can report where a value came from
+// with Explain.
+type Layers struct {
+	opts    []Option
+	timeout time.Duration
+	layers  []builderSource
+}
+
+// NewLayers creates a Layers whose profile is configured with opts
+func NewLayers(opts ...Option) *Layers {
+	return &Layers{opts: opts, timeout: DefaultSourceTimeout}
+}
+
+// Timeout sets the time allowed for each layer to load
+func (l *Layers) Timeout(timeout time.Duration) *Layers {
+	l.timeout = timeout
+	return l
+}
+
+// Add adds the layer name, read from src, with the given priority
+func (l *Layers) Add(name string, priority int, src Source) *Layers {
+	l.layers = append(l.layers, builderSource{src: src, layer: name, priority: priority})
+	return l
+}
+
+// Build loads every layer concurrently and merges them from the lowest
+// priority to the highest. If any layer fails, the error of the lowest
+// failing layer is returned.
+func (l *Layers) Build(ctx context.Context) (*YamlProfile, error) {
+	return l.builder().Build(ctx)
+}
+
+// Watch keeps p, a profile built by l, up to date as Builder.Watch does
+func (l *Layers) Watch(ctx context.Context, p *YamlProfile) error {
+	return l.builder().Watch(ctx, p)
+}
+
+// builder returns a Builder adding the layers from the lowest priority to
+// the highest
+func (l *Layers) builder() *Builder {
+	sources := cloneSlice(l.layers)
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].priority < sources[j].priority
+	})
+	return &Builder{opts: l.opts, timeout: l.timeout, sources: sources, layered: true}
+}
+
+// layerTree is the tree a layer of a profile built by Layers loaded
+type layerTree struct {
+	name     string
+	priority int
+	tree     map[string]interface{}
+}
+
+// Explanation reports which layer supplied a value
+type Explanation struct {
+	// Path is the path explained
+	Path string
+	// Layer and Priority identify the highest priority layer setting the
+	// path. A map, or a list merged with ListAppend or ListMergeByIndex,
+	// can combine several layers; Layer is the highest of them.
+	Layer    string
+	Priority int
+	// Value is the value as the layer wrote it, placeholders unresolved
+	Value interface{}
+	// Overridden lists the lower layers that also set the path, highest
+	// priority first
+	Overridden []string
+}
+
+// Explain reports which layer of a profile built by Layers supplied the
+// value at path, and which layers it overrode. Values changed since the
+// profile was loaded, with Set or Merge, are explained as loaded.
+func (p *YamlProfile) Explain(path string) (Explanation, error) {
+	p.mu.RLock()
+	layers := p.layers
+	p.mu.RUnlock()
+	if layers == nil {
+		return Explanation{}, ErrNotLayered
+	}
+	_, rawPath, err := p.lookup(path)
+	if err != nil {
+		return Explanation{}, err
+	}
+	segments := splitPath(joinPath(p.base, rawPath))
+
+	e := Explanation{Path: path}
+	found := false
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		value, ok := treeValue(layer.tree, segments)
+		if !ok {
+			continue
+		}
+		if found {
+			e.Overridden = append(e.Overridden, layer.name)
+			continue
+		}
+		e.Layer, e.Priority, e.Value = layer.name, layer.priority, value
+		found = true
+	}
+	if !found {
+		return Explanation{}, ErrValueNotFound
+	}
+	return e, nil
+}
+
+// treeValue returns the value at the raw path segments of tree
+func treeValue(tree interface{}, segments []string) (interface{}, bool) {
+	current := tree
+	for _, key := range segments {
+		switch val := current.(type) {
+		case map[string]interface{}:
+			item, ok := val[key]
+			if !ok {
+				return nil, false
+			}
+			current = item
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(val) {
+				return nil, false
+			}
+			current = val[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// mapSource provides a fixed tree
+type mapSource struct {
+	name string
+	tree map[string]interface{}
+}
+
+// MapSource returns a Source providing tree, such as built-in defaults.
+// Each Load returns a copy, so tree may be reused.
+func MapSource(name string, tree map[string]interface{}) Source {
+	return mapSource{name: name, tree: tree}
+}
+
+func (s mapSource) Name() string { return s.name }
+
+func (s mapSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	tree, _ := copyTree(s.tree).(map[string]interface{})
+	if tree == nil {
+		tree = make(map[string]interface{})
+	}
+	return tree, nil
+}
+
+// flagSource reads the flags set on a command line
+type flagSource struct {
+	flags *flag.FlagSet
+}
+
+// FlagSource returns a Source providing the flags of fs that were set on
+// the command line, so flag defaults never override lower layers. Dots in
+// flag names nest, so -db.port=5433 sets db.port, and values are typed as
+// YAML types them. Parse fs before building.
+func FlagSource(fs *flag.FlagSet) Source {
+	return flagSource{flags: fs}
+}
+
+func (s flagSource) Name() string { return "flags" }
+
+func (s flagSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	var err error
+	s.flags.Visit(func(f *flag.Flag) {
+		var keys []string
+		for _, key := range strings.Split(f.Name, ".") {
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 || err != nil {
+			return
+		}
+		if leafErr := setLeaf(tree, keys, typedText(f.Value.String())); leafErr != nil {
+			err = fmt.Errorf("-%s: %w", f.Name, leafErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
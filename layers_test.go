@@ -0,0 +1,119 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLayers_Explain(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.yaml": "server:\n  host: app.example.com\n  port: 8080\nlog:\n  level: info\n",
+	})
+	t.Setenv("LAYERS_LOG__LEVEL", "warn")
+	t.Setenv("LAYERS_SERVER__PORT", "8081")
+
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags.Int("server.port", 80, "port")
+	flags.Bool("debug", false, "debug")
+	flags.String("unset", "default", "never set")
+	if err := flags.Parse([]string{"-server.port=9090"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	defaults := map[string]interface{}{
+		"server": map[string]interface{}{"port": 80, "timeout": "30s"},
+		"debug":  false,
+	}
+	layers := NewLayers().
+		Add("flags", PriorityFlags, FlagSource(flags)).
+		Add("file", PriorityFile, FileSource(filepath.Join(dir, "app.yaml"))).
+		Add("defaults", PriorityDefaults, MapSource("defaults", defaults)).
+		Add("env", PriorityEnv, FromEnvPrefix("LAYERS_"))
+	p, err := layers.Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	assert(t, p.Get("server.port"), "9090", "server.port")
+	assert(t, p.Get("server.host"), "app.example.com", "server.host")
+	assert(t, p.Get("server.timeout"), "30s", "server.timeout")
+	assert(t, p.Get("log.level"), "warn", "log.level")
+	assert(t, p.Get("debug"), "false", "debug")
+	if _, err := p.GetError("unset"); err == nil {
+		t.Error("flags left unset should not be loaded")
+	}
+
+	tests := []struct {
+		path       string
+		layer      string
+		priority   int
+		value      interface{}
+		overridden []string
+	}{
+		{"server.port", "flags", PriorityFlags, 9090, []string{"env", "file", "defaults"}},
+		{"server.host", "file", PriorityFile, "app.example.com", nil},
+		{"server.timeout", "defaults", PriorityDefaults, "30s", nil},
+		{"log.level", "env", PriorityEnv, "warn", []string{"file"}},
+		{"debug", "defaults", PriorityDefaults, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			e, err := p.Explain(tt.path)
+			if err != nil {
+				t.Fatalf("Explain failed: %v", err)
+			}
+			assert(t, e.Layer, tt.layer, "layer")
+			assert(t, e.Priority, tt.priority, "priority")
+			assert(t, e.Value, tt.value, "value")
+			if !reflect.DeepEqual(e.Overridden, tt.overridden) {
+				t.Errorf("overridden = %v, want %v", e.Overridden, tt.overridden)
+			}
+		})
+	}
+
+	e, err := p.Sub("server").Explain("host")
+	if err != nil {
+		t.Fatalf("Explain through Sub failed: %v", err)
+	}
+	assert(t, e.Layer, "file", "layer through Sub")
+
+	if _, err := p.Explain("missing"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("missing path: err = %v, want ErrValueNotFound", err)
+	}
+	if _, err := p.Clone().Explain("server.port"); err != nil {
+		t.Errorf("Explain on a clone failed: %v", err)
+	}
+	if _, err := NewProfile().Explain("server.port"); !errors.Is(err, ErrNotLayered) {
+		t.Errorf("unlayered profile: err = %v, want ErrNotLayered", err)
+	}
+	defaults["debug"] = true
+	assert(t, p.Get("debug"), "false", "defaults copied on load")
+}
+
+func TestLayers_EqualPriorityKeepsOrder(t *testing.T) {
+	p, err := NewLayers().
+		Add("first", PriorityFile, MapSource("first", map[string]interface{}{"name": "first"})).
+		Add("second", PriorityFile, MapSource("second", map[string]interface{}{"name": "second"})).
+		Build(context.Background())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	assert(t, p.Get("name"), "second", "later layer of equal priority wins")
+}
+
+func TestFlagSource_Conflict(t *testing.T) {
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags.String("db", "", "")
+	flags.String("db.port", "", "")
+	if err := flags.Parse([]string{"-db=x", "-db.port=1"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := FlagSource(flags).Load(context.Background()); err == nil {
+		t.Error("expected an error for a flag nesting under a value")
+	}
+}
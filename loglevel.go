@@ -0,0 +1,67 @@
+package dollarYaml
+
+import (
+	"encoding"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// BindLogLevel applies the value at path, such as logging.level, through
+// set and applies it again whenever it changes after a reload or an env
+// change seen through OnChange. A value that set rejects on reload is
+// skipped and logged in debug mode, keeping the previous level. Calling
+// the returned stop function ends the binding.
+//
+// LevelSetter adapts loggers with a text-settable level, such as
+// *slog.LevelVar or *zap.AtomicLevel. For logrus, parse the level in set:
+//
+//	stop, err := p.BindLogLevel("logging.level", func(level string) error {
+//		l, err := logrus.ParseLevel(level)
+//		if err == nil {
+//			logger.SetLevel(l)
+//		}
+//		return err
+//	})
+func (p *YamlProfile) BindLogLevel(path string, set func(level string) error) (stop func(), err error) {
+	level, err := p.GetError(path)
+	if err != nil {
+		return nil, err
+	}
+	level = strings.TrimSpace(level)
+	if err := set(level); err != nil {
+		return nil, fmt.Errorf("setting log level %q from %s: %w", level, path, err)
+	}
+
+	var mu sync.Mutex
+	current := level
+	stop = p.OnChange(func(ChangeEvent) {
+		level, err := p.GetError(path)
+		if err != nil {
+			p.debugf("Reading log level from %s failed: %v\n", path, err)
+			return
+		}
+		level = strings.TrimSpace(level)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if level == current {
+			return
+		}
+		if err := set(level); err != nil {
+			p.debugf("Setting log level %q from %s failed: %v\n", level, path, err)
+			return
+		}
+		p.debugf("Log level changed from %s to %s\n", current, level)
+		current = level
+	})
+	return stop, nil
+}
+
+// LevelSetter returns a setter for BindLogLevel that passes the level to
+// u.UnmarshalText, as implemented by *slog.LevelVar and *zap.AtomicLevel
+func LevelSetter(u encoding.TextUnmarshaler) func(level string) error {
+	return func(level string) error {
+		return u.UnmarshalText([]byte(level))
+	}
+}
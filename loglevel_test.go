@@ -0,0 +1,81 @@
+package dollarYaml
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testLevel is a text-settable level like slog.LevelVar
+type testLevel struct {
+	level string
+	sets  int
+}
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch level := strings.ToLower(string(text)); level {
+	case "debug", "info", "warn", "error":
+		l.level = level
+		l.sets++
+		return nil
+	}
+	return fmt.Errorf("unknown level %q", text)
+}
+
+func TestYamlProfile_BindLogLevel(t *testing.T) {
+	os.Setenv("LOGLEVEL_LEVEL", "INFO")
+	defer os.Unsetenv("LOGLEVEL_LEVEL")
+
+	p := New(false)
+	if err := p.Read([]byte("logging:\n  level: ${LOGLEVEL_LEVEL:warn}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var level testLevel
+	stop, err := p.BindLogLevel("logging.level", LevelSetter(&level))
+	if err != nil {
+		t.Fatalf("BindLogLevel failed: %v", err)
+	}
+	assert(t, level.level, "info", "initial level")
+
+	// An env change updates the level
+	os.Setenv("LOGLEVEL_LEVEL", "debug")
+	p.notify(ChangeEvent{Env: []string{"LOGLEVEL_LEVEL"}, Paths: []string{"logging.level"}})
+	assert(t, level.level, "debug", "level after env change")
+
+	// Unrelated changes do not set the level again
+	p.notify(ChangeEvent{Env: []string{"OTHER"}})
+	assert(t, level.sets, 2, "sets")
+
+	// An invalid level is skipped
+	os.Setenv("LOGLEVEL_LEVEL", "loud")
+	p.notify(ChangeEvent{Env: []string{"LOGLEVEL_LEVEL"}, Paths: []string{"logging.level"}})
+	assert(t, level.level, "debug", "level after invalid value")
+
+	// A reload picks up the new document
+	if err := p.Read([]byte("logging:\n  level: error\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, level.level, "error", "level after reload")
+
+	// Once stopped, reloads leave the level alone
+	stop()
+	stop()
+	assert(t, len(p.hooks.hooks), 0, "hooks after stop")
+	if err := p.Read([]byte("logging:\n  level: warn\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, level.level, "error", "level after stop")
+
+	if _, err := p.BindLogLevel("logging.missing", LevelSetter(&level)); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+	os.Setenv("LOGLEVEL_LEVEL", "loud")
+	if err := p.Read([]byte("logging:\n  level: ${LOGLEVEL_LEVEL}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := p.BindLogLevel("logging.level", LevelSetter(&testLevel{})); err == nil {
+		t.Error("expected an error for an invalid initial level")
+	}
+}
@@ -0,0 +1,99 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal encodes the effective config as YAML: the whole tree with its
+// placeholders resolved and typed as UnmarshalTo into an untyped target
+// would, for dumping at startup or into support bundles. Resolved secrets
+// appear in clear text. Any placeholder failing to resolve fails Marshal.
+func (p *YamlProfile) Marshal() ([]byte, error) {
+	tree, err := p.resolvedTree(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tree)
+}
+
+// MarshalAs encodes the effective config like Marshal, in format, an
+// extension or MIME type with a registered codec. The root must be a map.
+func (p *YamlProfile) MarshalAs(format string) ([]byte, error) {
+	c, ok := CodecFor(format)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	tree, err := p.resolvedTree(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	m, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s needs a map at the root", ErrInvalidRoot, format)
+	}
+	return c.Marshal(m)
+}
+
+// WriteTo writes the output of Marshal to w, implementing io.WriterTo
+func (p *YamlProfile) WriteTo(w io.Writer) (int64, error) {
+	data, err := p.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// resolvedTree returns the tree with every placeholder resolved and
+// typed for an untyped target
+func (p *YamlProfile) resolvedTree(ctx context.Context) (interface{}, error) {
+	processed, err := p.processValue(ctx, p.base, p.root())
+	if err != nil {
+		return nil, fmt.Errorf("processing environment variables: %w", err)
+	}
+	return p.coerceFor(processed, untypedType), nil
+}
+
+// JSONOption configures ToJSON
+type JSONOption func(*jsonConfig)
+
+type jsonConfig struct {
+	prefix, indent string
+}
+
+// JSONIndent makes ToJSON write one element per line, each line starting
+// with prefix and indented with one or more copies of indent, as
+// json.MarshalIndent does
+func JSONIndent(prefix, indent string) JSONOption {
+	return func(c *jsonConfig) {
+		c.prefix, c.indent = prefix, indent
+	}
+}
+
+// ToJSON encodes the effective config as JSON, resolved as by Marshal, for
+// frontends and JSON-only tools. Unlike MarshalAs it needs no registered
+// codec and accepts a list at the root. HTML characters are not escaped.
+func (p *YamlProfile) ToJSON(opts ...JSONOption) ([]byte, error) {
+	var cfg jsonConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tree, err := p.resolvedTree(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(cfg.prefix, cfg.indent)
+	if err := enc.Encode(tree); err != nil {
+		return nil, fmt.Errorf("encoding JSON: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,139 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// compactJSONCodec is a custom codec used to exercise MarshalAs
+type compactJSONCodec struct{}
+
+func (compactJSONCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	return json.Unmarshal(data, out)
+}
+
+func (compactJSONCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func TestYamlProfile_Marshal(t *testing.T) {
+	t.Setenv("MARSHAL_PORT", "9090")
+	t.Setenv("MARSHAL_ZIP", "08540")
+
+	yamlData := []byte(`
+server:
+  port: ${MARSHAL_PORT:8080}
+  host: ${MARSHAL_HOST:localhost}
+  zip: ${MARSHAL_ZIP|string}
+  url: http://${MARSHAL_HOST:localhost}:${MARSHAL_PORT}
+tags: ["${MARSHAL_TAG:a}", b]
+`)
+	want := `server:
+    host: localhost
+    port: 9090
+    url: http://localhost:9090
+    zip: "08540"
+tags:
+    - a
+    - b
+`
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	got, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	assert(t, string(got), want, "Marshal")
+
+	var buf bytes.Buffer
+	n, err := p.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	assert(t, buf.String(), want, "WriteTo")
+	assert(t, n, int64(len(want)), "bytes written")
+
+	// The output reads back to the same values
+	back := NewProfile()
+	if err := back.Read(got); err != nil {
+		t.Fatalf("failed to read marshaled data: %v", err)
+	}
+	assert(t, back.Get("server.zip"), "08540", "round trip")
+
+	RegisterCodec(compactJSONCodec{}, ".marshal-json")
+	js, err := p.MarshalAs("marshal-json")
+	if err != nil {
+		t.Fatalf("MarshalAs failed: %v", err)
+	}
+	assert(t, string(js), `{"server":{"host":"localhost","port":9090,"url":"http://localhost:9090","zip":"08540"},"tags":["a","b"]}`, "MarshalAs")
+	if _, err := p.MarshalAs("nope"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat but got %v", err)
+	}
+
+	strict := NewProfile(WithStrict(true))
+	if err := strict.Read([]byte("a: ${MARSHAL_UNSET}")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := strict.Marshal(); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved but got %v", err)
+	}
+}
+
+func TestYamlProfile_ToJSON(t *testing.T) {
+	t.Setenv("MARSHAL_PORT", "9090")
+
+	p := NewProfile()
+	if err := p.Read([]byte(`
+server:
+  port: ${MARSHAL_PORT:8080}
+  url: http://${MARSHAL_HOST:localhost}/?a=1&b=<2>
+tags: [a, ~]
+`)); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	got, err := p.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	assert(t, string(got), `{"server":{"port":9090,"url":"http://localhost/?a=1&b=<2>"},"tags":["a",null]}`, "ToJSON")
+
+	got, err = p.ToJSON(JSONIndent("", "  "))
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	want := `{
+  "server": {
+    "port": 9090,
+    "url": "http://localhost/?a=1&b=<2>"
+  },
+  "tags": [
+    "a",
+    null
+  ]
+}`
+	assert(t, string(got), want, "indented")
+
+	list := NewProfile()
+	if err := list.Read([]byte("[{a: 1}, b]")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	got, err = list.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	assert(t, string(got), `[{"a":1},"b"]`, "root list")
+
+	strict := NewProfile(WithStrict(true))
+	if err := strict.Read([]byte("a: ${MARSHAL_UNSET}")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := strict.ToJSON(); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved but got %v", err)
+	}
+}
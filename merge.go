@@ -0,0 +1,156 @@
+package dollarYaml
+
+import "strconv"
+
+// ListMergeStrategy selects how Merge and Builder combine a list with the
+// list it overrides
+type ListMergeStrategy int
+
+const (
+	// ListReplace replaces the earlier list with the later one
+	ListReplace ListMergeStrategy = iota
+	// ListAppend appends the items of the later list to the earlier one
+	ListAppend
+	// ListMergeByIndex merges the items at the same index, deep-merging
+	// maps and keeping the earlier items past the end of the later list
+	ListMergeByIndex
+)
+
+// Tags that override the list merge strategy for a single key in the
+// document being merged in, as in servers: !append [c]. !override also
+// makes a map replace the earlier one instead of being merged into it.
+const (
+	tagOverride = "!override"
+	tagAppend   = "!append"
+	tagMerge    = "!merge"
+)
+
+// isMergeTag reports whether tag directs merging rather than describing
+// the value
+func isMergeTag(tag string) bool {
+	return tag == tagOverride || tag == tagAppend || tag == tagMerge
+}
+
+// Merge deep-merges other into p, for layering environment specific
+// overrides over a base config. Maps are merged key by key, and any other
+// value in other replaces the one in p. Lists are replaced unless
+// WithListMergeStrategy chose otherwise for p, or other tags them with
+// !append, !merge or !override. Placeholders are merged as written and
+// resolve against p's options. Merge notifies OnChange hooks as a reload.
+// The merged tree is built from a copy, so Merge is safe to call while
+// other goroutines read p or Builder.Watch reloads it.
+func (p *YamlProfile) Merge(other *YamlProfile) error {
+	src := other.root()
+	if m, ok := src.(map[string]interface{}); ok && len(m) == 0 {
+		return nil
+	}
+	tags := other.Tags()
+	other.mu.RLock()
+	sources := cloneSlice(other.sources)
+	other.mu.RUnlock()
+
+	m := merger{lists: p.listMerge, tags: tags}
+	err := p.update(func(data interface{}) (interface{}, error) {
+		return m.value(copyTree(data), src, ""), nil
+	}, func() {
+		merged := cloneMap(p.tags)
+		if merged == nil {
+			merged = make(map[string]string)
+		}
+		for k, v := range tags {
+			if !isMergeTag(v) {
+				merged[joinPath(p.base, k)] = v
+			}
+		}
+		p.tags = merged
+		p.sources = append(cloneSlice(p.sources), sources...)
+	})
+	if err != nil {
+		return err
+	}
+	p.notify(ChangeEvent{Reloaded: true})
+	return nil
+}
+
+// MergeYAML reads data as Read would and merges it into p like Merge
+func (p *YamlProfile) MergeYAML(data []byte) error {
+	other := NewProfile()
+	if err := other.Read(data); err != nil {
+		return err
+	}
+	return p.Merge(other)
+}
+
+// MergeFromPath reads the file at path as ReadFromPath would and merges it
+// into p like Merge
+func (p *YamlProfile) MergeFromPath(path string) error {
+	other := NewProfile()
+	if err := other.ReadFromPath(path); err != nil {
+		return err
+	}
+	return p.Merge(other)
+}
+
+// merger deep-merges raw trees. tags holds the custom tags of the tree
+// being merged in, keyed by path.
+type merger struct {
+	lists ListMergeStrategy
+	tags  map[string]string
+}
+
+// merge merges src, the map at path, into dst
+func (m merger) merge(dst, src map[string]interface{}, path string) {
+	for k, v := range src {
+		dst[k] = m.value(dst[k], v, joinKey(path, k))
+	}
+}
+
+// value returns the result of merging src, the value at path, over dst.
+// Maps in dst are updated in place; src is copied so later merges never
+// modify its tree.
+func (m merger) value(dst, src interface{}, path string) interface{} {
+	tag := m.tags[path]
+	if tag == tagOverride {
+		return copyTree(src)
+	}
+	switch s := src.(type) {
+	case map[string]interface{}:
+		if d, ok := dst.(map[string]interface{}); ok && d != nil {
+			m.merge(d, s, path)
+			return d
+		}
+	case []interface{}:
+		d, ok := dst.([]interface{})
+		if !ok {
+			break
+		}
+		switch m.listStrategy(tag) {
+		case ListAppend:
+			out := make([]interface{}, 0, len(d)+len(s))
+			out = append(out, d...)
+			return append(out, copyTree(s).([]interface{})...)
+		case ListMergeByIndex:
+			out := append([]interface{}{}, d...)
+			for i, item := range s {
+				if i < len(out) {
+					out[i] = m.value(out[i], item, joinPath(path, strconv.Itoa(i)))
+				} else {
+					out = append(out, copyTree(item))
+				}
+			}
+			return out
+		}
+	}
+	return copyTree(src)
+}
+
+// listStrategy returns the strategy for a list tagged tag
+func (m merger) listStrategy(tag string) ListMergeStrategy {
+	switch tag {
+	case tagAppend:
+		return ListAppend
+	case tagMerge:
+		return ListMergeByIndex
+	}
+	return m.lists
+}
@@ -0,0 +1,260 @@
+package dollarYaml
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestYamlProfile_Merge(t *testing.T) {
+	t.Setenv("MERGE_PORT", "9090")
+
+	base := []byte(`
+server:
+  host: localhost
+  port: 8080
+  tags: [a, b]
+database:
+  host: db.local
+name: app
+`)
+	override := []byte(`
+server:
+  port: ${MERGE_PORT:8081}
+  tags: [c]
+database: sqlite
+extra: true
+`)
+
+	p := NewProfile()
+	if err := p.Read(base); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	reloads := 0
+	p.OnChange(func(ev ChangeEvent) {
+		if ev.Reloaded {
+			reloads++
+		}
+	})
+	if err := p.MergeYAML(override); err != nil {
+		t.Fatalf("MergeYAML failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"server.host", "localhost"},
+		{"server.port", "9090"},
+		{"server.tags.0", "c"},
+		{"database", "sqlite"},
+		{"name", "app"},
+		{"extra", "true"},
+	}
+	for _, tt := range tests {
+		assert(t, p.Get(tt.path), tt.want, tt.path)
+	}
+	assert(t, p.Has("server.tags.1"), false, "list replaced")
+	assert(t, reloads, 1, "reload events")
+
+	t.Run("profile", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.Read(base); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		other := NewProfile()
+		if err := other.Read(override); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		if err := p.Merge(other); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+		// Later changes to either profile do not leak into the other
+		if err := p.Set("server.tags.0", "z"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		assert(t, other.Get("server.tags.0"), "c", "other unchanged")
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeTemp(t, dir, "prod.yaml", override)
+		p := NewProfile()
+		if err := p.ReadFromPath(writeTemp(t, dir, "base.yaml", base)); err != nil {
+			t.Fatalf("ReadFromPath failed: %v", err)
+		}
+		if err := p.MergeFromPath(path); err != nil {
+			t.Fatalf("MergeFromPath failed: %v", err)
+		}
+		assert(t, p.Get("server.port"), "9090", "server.port")
+		assert(t, p.sources[1], path, "merged source recorded")
+	})
+
+	t.Run("into empty", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.MergeYAML(base); err != nil {
+			t.Fatalf("MergeYAML failed: %v", err)
+		}
+		raw, _ := p.GetRaw("server.tags")
+		if !reflect.DeepEqual(raw, []interface{}{"a", "b"}) {
+			t.Errorf("server.tags = %v", raw)
+		}
+		if err := p.MergeYAML(nil); err != nil {
+			t.Fatalf("MergeYAML failed: %v", err)
+		}
+		assert(t, p.Get("name"), "app", "empty merge is a no-op")
+	})
+}
+
+func writeTemp(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestYamlProfile_MergeListStrategies(t *testing.T) {
+	base := []byte(`
+servers:
+  - {name: a, port: 80}
+  - {name: b, port: 81}
+tags: [x, y]
+limits: {cpu: 1, memory: 512}
+`)
+	override := []byte(`
+servers:
+  - {port: 8080}
+tags: [z]
+`)
+
+	tests := []struct {
+		name     string
+		strategy ListMergeStrategy
+		override []byte
+		want     map[string]interface{}
+	}{
+		{"replace", ListReplace, override, map[string]interface{}{
+			"servers": []interface{}{map[string]interface{}{"port": 8080}},
+			"tags":    []interface{}{"z"},
+		}},
+		{"append", ListAppend, override, map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"name": "a", "port": 80},
+				map[string]interface{}{"name": "b", "port": 81},
+				map[string]interface{}{"port": 8080},
+			},
+			"tags": []interface{}{"x", "y", "z"},
+		}},
+		{"merge by index", ListMergeByIndex, override, map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"name": "a", "port": 8080},
+				map[string]interface{}{"name": "b", "port": 81},
+			},
+			"tags": []interface{}{"z", "y"},
+		}},
+		{"tags win over the option", ListAppend, []byte(`
+servers: !merge
+  - {port: 8080}
+tags: !override [z]
+limits: !override {cpu: 2}
+`), map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"name": "a", "port": 8080},
+				map[string]interface{}{"name": "b", "port": 81},
+			},
+			"tags":   []interface{}{"z"},
+			"limits": map[string]interface{}{"cpu": 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile(WithListMergeStrategy(tt.strategy))
+			if err := p.Read(base); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			if err := p.MergeYAML(tt.override); err != nil {
+				t.Fatalf("MergeYAML failed: %v", err)
+			}
+			for key, want := range tt.want {
+				got, _ := p.GetRaw(key)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+			if len(p.Tags()) != 0 {
+				t.Errorf("merge tags kept: %v", p.Tags())
+			}
+		})
+	}
+
+	t.Run("builder", func(t *testing.T) {
+		p, err := NewBuilder(WithListMergeStrategy(ListAppend)).
+			Add(BytesSource("base", base, "yaml")).
+			Add(BytesSource("override", override, "yaml")).
+			Build(context.Background())
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		got, _ := p.GetRaw("tags")
+		if !reflect.DeepEqual(got, []interface{}{"x", "y", "z"}) {
+			t.Errorf("tags = %v", got)
+		}
+	})
+}
+
+func TestYamlProfile_MergeConcurrent(t *testing.T) {
+	p := NewProfile()
+	if err := p.Read([]byte("server:\n  host: localhost\n  tags: [a]\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var readers, writers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			p.Get("server.host")
+			p.Tags()
+			var cfg map[string]interface{}
+			if err := p.UnmarshalTo(&cfg); err != nil {
+				t.Errorf("UnmarshalTo failed: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			for j := 0; j < 10; j++ {
+				if err := p.MergeYAML([]byte(fmt.Sprintf("server:\n  w%d_%d: !secret %d\n", i, j, j))); err != nil {
+					t.Errorf("MergeYAML failed: %v", err)
+				}
+			}
+		}(i)
+	}
+	writers.Wait()
+	close(stop)
+	readers.Wait()
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 10; j++ {
+			path := fmt.Sprintf("server.w%d_%d", i, j)
+			assert(t, p.Has(path), true, "merged "+path)
+			tag, _ := p.Tag(path)
+			assert(t, tag, "!secret", "tag of "+path)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package dollarYaml
+
+import "fmt"
+
+// MustGet is GetError for startup code that cannot run without the value:
+// it panics when the path is missing or a placeholder in it fails to
+// resolve. The panic value is an error naming the path and wrapping the
+// cause, such as ErrUnresolved with the name of the unset variable.
+func (p *YamlProfile) MustGet(path string) string {
+	v, err := p.GetError(path)
+	if err != nil {
+		panic(mustError(path, err))
+	}
+	return v
+}
+
+// MustGetInt is GetInt, panicking on error like MustGet
+func (p *YamlProfile) MustGetInt(path string) int {
+	v, err := p.GetInt(path)
+	if err != nil {
+		panic(mustError(path, err))
+	}
+	return v
+}
+
+// MustGetBool is GetBool, panicking on error like MustGet
+func (p *YamlProfile) MustGetBool(path string) bool {
+	v, err := p.GetBool(path)
+	if err != nil {
+		panic(mustError(path, err))
+	}
+	return v
+}
+
+// MustUnmarshalTo is UnmarshalTo, panicking on error like MustGet
+func (p *YamlProfile) MustUnmarshalTo(target interface{}) {
+	if err := p.UnmarshalTo(target); err != nil {
+		panic(fmt.Errorf("dollarYaml: unmarshaling config: %w", err))
+	}
+}
+
+func mustError(path string, err error) error {
+	return fmt.Errorf("dollarYaml: reading %s: %w", path, err)
+}
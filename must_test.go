@@ -0,0 +1,57 @@
+package dollarYaml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestYamlProfile_Must(t *testing.T) {
+	yamlData := []byte(`
+name: ${MUST_NAME:app}
+port: ${MUST_PORT:8080}
+debug: ${MUST_DEBUG:true}
+secret: ${MUST_SECRET}
+`)
+	p := NewProfile(WithStrict(true))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	assert(t, p.MustGet("name"), "app", "name")
+	assert(t, p.MustGetInt("port"), 8080, "port")
+	assert(t, p.MustGetBool("debug"), true, "debug")
+
+	tests := []struct {
+		name     string
+		fn       func()
+		wantErr  error
+		contains string
+	}{
+		{"unresolved", func() { p.MustGet("secret") }, ErrUnresolved, "MUST_SECRET"},
+		{"missing", func() { p.MustGet("missing") }, ErrValueNotFound, "missing"},
+		{"not an int", func() { p.MustGetInt("name") }, ErrConversion, "name"},
+		{"not a bool", func() { p.MustGetBool("port") }, ErrConversion, "port"},
+		{"unmarshal", func() {
+			var cfg map[string]interface{}
+			p.MustUnmarshalTo(&cfg)
+		}, ErrUnresolved, "MUST_SECRET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				err, ok := recover().(error)
+				if !ok {
+					t.Fatal("expected a panic with an error")
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v but got %v", tt.wantErr, err)
+				}
+				if !strings.Contains(err.Error(), tt.contains) {
+					t.Errorf("expected %q in %q", tt.contains, err)
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package dollarYaml
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set stores value at path, creating the maps leading to it as needed, so
+// tests and command line overrides can adjust the config after Read.
+// value may be any type yaml can encode; it is stored as the equivalent
+// raw tree, and strings in it may hold placeholders, which resolve when
+// read like those in the file. An index one past the end of a list appends
+// to it, and negative indices count from the end. Set notifies OnChange
+// hooks with path. The maps and lists along path are copied, so Set is
+// safe to call while other goroutines read p or Builder.Watch reloads it.
+func (p *YamlProfile) Set(path string, value interface{}) error {
+	node, err := toTree(value)
+	if err != nil {
+		return fmt.Errorf("setting %s: %w", path, err)
+	}
+	if err := p.mutate(path, node, false); err != nil {
+		return err
+	}
+	p.notify(ChangeEvent{Paths: []string{normalizePath(path)}})
+	return nil
+}
+
+// Delete removes the value at path, shifting later items down when it is
+// a list item. It fails with ErrValueNotFound when path does not exist,
+// and notifies OnChange hooks with path otherwise.
+func (p *YamlProfile) Delete(path string) error {
+	if err := p.mutate(path, nil, true); err != nil {
+		return err
+	}
+	p.notify(ChangeEvent{Paths: []string{normalizePath(path)}})
+	return nil
+}
+
+func (p *YamlProfile) mutate(path string, value interface{}, del bool) error {
+	if path == "" {
+		return fmt.Errorf("%w: empty path", ErrValueNotFound)
+	}
+	segs := splitPath(path)
+	return p.update(func(data interface{}) (interface{}, error) {
+		return p.mutateNode(data, "", segs, value, del)
+	}, nil)
+}
+
+// mutateNode sets or deletes the value at segs below node, the node at
+// rawPath, and returns the node to store in its place. The maps and lists
+// along segs are copied rather than changed.
+func (p *YamlProfile) mutateNode(node interface{}, rawPath string, segs []string, value interface{}, del bool) (interface{}, error) {
+	key, last := segs[0], len(segs) == 1
+	if node == nil {
+		if del {
+			return nil, fmt.Errorf("%w: %s", ErrValueNotFound, key)
+		}
+		node = map[string]interface{}{}
+	}
+
+	switch val := node.(type) {
+	case map[string]interface{}:
+		rawKey := key
+		child, ok := val[key]
+		if !ok {
+			if rawKey, ok = p.matchKey(val, rawPath, key); ok {
+				child = val[rawKey]
+			} else {
+				rawKey = key
+			}
+		}
+		if !ok && del {
+			return nil, fmt.Errorf("%w: %s", ErrValueNotFound, key)
+		}
+		val = cloneMap(val)
+		if val == nil {
+			val = map[string]interface{}{}
+		}
+		if last {
+			if del {
+				delete(val, rawKey)
+			} else {
+				val[rawKey] = value
+			}
+			return val, nil
+		}
+		updated, err := p.mutateNode(child, joinKey(rawPath, rawKey), segs[1:], value, del)
+		if err != nil {
+			return nil, err
+		}
+		val[rawKey] = updated
+		return val, nil
+	case []interface{}:
+		if last && !del && key == strconv.Itoa(len(val)) {
+			return append(cloneSlice(val), value), nil
+		}
+		idx, err := listIndex(key, len(val))
+		if err != nil {
+			return nil, err
+		}
+		val = cloneSlice(val)
+		key = strconv.Itoa(idx)
+		if last {
+			if del {
+				list := make([]interface{}, 0, len(val)-1)
+				return append(append(list, val[:idx]...), val[idx+1:]...), nil
+			}
+			val[idx] = value
+			return val, nil
+		}
+		updated, err := p.mutateNode(val[idx], joinPath(rawPath, key), segs[1:], value, del)
+		if err != nil {
+			return nil, err
+		}
+		val[idx] = updated
+		return val, nil
+	}
+	return nil, ErrLevelMismatch
+}
+
+// toTree converts value into a raw tree as Read would produce for its yaml
+// encoding
+func toTree(value interface{}) (interface{}, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return normalizeTree(tree), nil
+}
@@ -0,0 +1,188 @@
+package dollarYaml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestYamlProfile_Set(t *testing.T) {
+	t.Setenv("SET_HOST", "db.local")
+
+	yamlData := []byte(`
+server:
+  port: 8080
+  tags: [a, b]
+name: app
+empty:
+`)
+	type limits struct {
+		Memory string `yaml:"memory"`
+		CPUs   int    `yaml:"cpus"`
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		value interface{}
+		get   string
+		want  string
+	}{
+		{"replace scalar", "server.port", 9090, "server.port", "9090"},
+		{"new key", "server.host", "${SET_HOST:localhost}", "server.host", "db.local"},
+		{"intermediate maps", "database.master.host", "m1", "database.master.host", "m1"},
+		{"into null", "empty.value", true, "empty.value", "true"},
+		{"list item", "server.tags[1]", "z", "server.tags.1", "z"},
+		{"list append", "server.tags.2", "c", "server.tags.2", "c"},
+		{"struct", "limits", limits{Memory: "512Mi", CPUs: 2}, "limits.cpus", "2"},
+		{"string slice", "hosts", []string{"h1", "h2"}, "hosts.1", "h2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile()
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			if err := p.Set(tt.path, tt.value); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			assert(t, p.Get(tt.get), tt.want, tt.get)
+		})
+	}
+
+	t.Run("decodes", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.Read(yamlData); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		var events []ChangeEvent
+		p.OnChange(func(ev ChangeEvent) { events = append(events, ev) })
+		if err := p.Set("server.port", "${SET_PORT:7070}"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		var cfg struct {
+			Server struct {
+				Port int `yaml:"port"`
+			} `yaml:"server"`
+		}
+		if err := p.UnmarshalTo(&cfg); err != nil {
+			t.Fatalf("UnmarshalTo failed: %v", err)
+		}
+		assert(t, cfg.Server.Port, 7070, "decoded port")
+		if !reflect.DeepEqual(events, []ChangeEvent{{Paths: []string{"server.port"}}}) {
+			t.Errorf("events = %v", events)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.Read(yamlData); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		if err := p.Set("name.first", "x"); !errors.Is(err, ErrLevelMismatch) {
+			t.Errorf("expected ErrLevelMismatch but got %v", err)
+		}
+		if err := p.Set("server.tags.5", "x"); !errors.Is(err, ErrValueNotFound) {
+			t.Errorf("expected ErrValueNotFound but got %v", err)
+		}
+		if err := p.Set("server.tags.x", "x"); !errors.Is(err, ErrLevelMismatch) {
+			t.Errorf("expected ErrLevelMismatch but got %v", err)
+		}
+	})
+
+	t.Run("empty profile", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.Set("a.b", 1); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		assert(t, p.Get("a.b"), "1", "a.b")
+	})
+}
+
+func TestYamlProfile_Delete(t *testing.T) {
+	yamlData := []byte(`
+server:
+  port: 8080
+  tags: [a, b, c]
+  ${DEL_KEY:extra}: x
+name: app
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	tags, _ := p.GetRaw("server.tags")
+
+	for _, path := range []string{"server.port", "server.tags.1", "server.extra", "name"} {
+		if err := p.Delete(path); err != nil {
+			t.Fatalf("Delete(%q) failed: %v", path, err)
+		}
+		if p.Has(path) && path != "server.tags.1" {
+			t.Errorf("%s still exists", path)
+		}
+	}
+	got, _ := p.GetRaw("")
+	want := map[string]interface{}{
+		"server": map[string]interface{}{"tags": []interface{}{"a", "c"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	assert(t, reflect.DeepEqual(tags, []interface{}{"a", "b", "c"}), true, "earlier copy unchanged")
+
+	if err := p.Delete("missing"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+	if err := p.Delete("server.tags.9"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+}
+
+func TestYamlProfile_SetConcurrent(t *testing.T) {
+	p := NewProfile()
+	if err := p.Read([]byte("server:\n  host: localhost\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var readers, writers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			p.Get("server.host")
+			var cfg map[string]interface{}
+			if err := p.UnmarshalTo(&cfg); err != nil {
+				t.Errorf("UnmarshalTo failed: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			for j := 0; j < 25; j++ {
+				if err := p.Set(fmt.Sprintf("server.w%d.k%d", i, j), j); err != nil {
+					t.Errorf("Set failed: %v", err)
+				}
+			}
+		}(i)
+	}
+	writers.Wait()
+	close(stop)
+	readers.Wait()
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 25; j++ {
+			assert(t, p.Has(fmt.Sprintf("server.w%d.k%d", i, j)), true, "set value kept")
+		}
+	}
+}
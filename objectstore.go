@@ -0,0 +1,57 @@
+package dollarYaml
+
+import (
+	"context"
+	pathpkg "path"
+	"time"
+)
+
+// ReadFromS3 reads the object key in bucket from Amazon S3 and loads it
+// as ReadFromPath loads files, choosing the codec by the extension of
+// key. Requests are signed with the default AWS credential chain unless
+// cfg sets Credentials, and cfg.Endpoint addresses S3-compatible stores
+// such as MinIO path-style. The object's Last-Modified time becomes the
+// SourceTime.
+func (p *YamlProfile) ReadFromS3(ctx context.Context, cfg AWSConfig, bucket, key string) error {
+	data, modified, err := newAWSResolver(cfg).getObject(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+	return p.readObject(data, "s3://"+bucket+"/"+key, modified)
+}
+
+// ReadFromGCS reads object from the Google Cloud Storage bucket and loads
+// it as ReadFromPath loads files, authenticating with Application Default
+// Credentials unless cfg sets Token. The object's update time becomes
+// the SourceTime.
+func (p *YamlProfile) ReadFromGCS(ctx context.Context, cfg GCPConfig, bucket, object string) error {
+	data, modified, err := newGCPResolver(cfg).getObject(ctx, bucket, object)
+	if err != nil {
+		return err
+	}
+	return p.readObject(data, "gs://"+bucket+"/"+object, modified)
+}
+
+// ReadFromAzureBlob reads blob from container in an Azure storage account
+// and loads it as ReadFromPath loads files, authenticating like
+// azidentity's DefaultAzureCredential for Azure Storage unless cfg sets
+// Token, which must then return storage tokens. The blob's Last-Modified
+// time becomes the SourceTime.
+func (p *YamlProfile) ReadFromAzureBlob(ctx context.Context, cfg AzureConfig, account, container, blob string) error {
+	r := newAzureResolver(cfg)
+	r.resource = azureStorageResource
+	data, modified, err := r.getBlob(ctx, account, container, blob)
+	if err != nil {
+		return err
+	}
+	return p.readObject(data, "https://"+account+".blob.core.windows.net/"+container+"/"+blob, modified)
+}
+
+// readObject loads an object downloaded from source, decoding it by the
+// extension of its name
+func (p *YamlProfile) readObject(data []byte, source string, modified time.Time) error {
+	return p.readFile(data, pathpkg.Ext(source), func() {
+		p.sources = []string{source}
+		p.sourceTime = modified
+	})
+}
@@ -0,0 +1,95 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_ReadFromObjectStores(t *testing.T) {
+	modified := time.Date(2026, 4, 2, 8, 0, 0, 0, time.UTC)
+	objects := map[string]string{
+		"/configs/app/v3.yaml":                  "database:\n  port: 5432\n",
+		"/storage/v1/b/configs/o/app%2Fv3.json": `{"database": {"port": 6543}}`,
+		"/account/configs/app/v3.toml":          "[database]\nport = 7654\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		switch {
+		case strings.HasPrefix(r.URL.EscapedPath(), "/configs/"):
+			if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		case strings.HasPrefix(r.URL.EscapedPath(), "/storage/"):
+			if auth != "Bearer gcp-token" || r.URL.Query().Get("alt") != "media" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		default:
+			if auth != "Bearer azure-token" || r.Header.Get("x-ms-version") == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		body, ok := objects[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	awsCfg := AWSConfig{
+		Region:   "eu-west-1",
+		Endpoint: server.URL,
+		Credentials: func(context.Context) (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		},
+	}
+	gcpCfg := GCPConfig{
+		StorageEndpoint: server.URL,
+		Token: func(context.Context) (GCPToken, error) {
+			return GCPToken{AccessToken: "gcp-token"}, nil
+		},
+	}
+	azureCfg := AzureConfig{
+		BlobEndpoint: server.URL + "/{account}",
+		Token: func(context.Context) (AzureToken, error) {
+			return AzureToken{AccessToken: "azure-token"}, nil
+		},
+	}
+
+	tests := []struct {
+		name   string
+		read   func(p *YamlProfile, object string) error
+		object string
+		want   string
+	}{
+		{"s3", func(p *YamlProfile, key string) error { return p.ReadFromS3(ctx, awsCfg, "configs", key) }, "app/v3.yaml", "5432"},
+		{"gcs", func(p *YamlProfile, object string) error { return p.ReadFromGCS(ctx, gcpCfg, "configs", object) }, "app/v3.json", "6543"},
+		{"azure", func(p *YamlProfile, blob string) error {
+			return p.ReadFromAzureBlob(ctx, azureCfg, "account", "configs", blob)
+		}, "app/v3.toml", "7654"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile()
+			if err := tt.read(p, tt.object); err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			assert(t, p.Get("database.port"), tt.want, "port")
+			assert(t, p.SourceTime().Equal(modified), true, "SourceTime")
+			if err := tt.read(NewProfile(), "missing.yaml"); !errors.Is(err, ErrValueNotFound) {
+				t.Errorf("expected ErrValueNotFound but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package dollarYaml
+
+import "time"
+
+// Option configures a YamlProfile
+type Option func(*YamlProfile)
+
+// WithDebug enables debug output describing how values are resolved
+func WithDebug(enabled bool) Option {
+	return func(p *YamlProfile) {
+		p.debug = enabled
+	}
+}
+
+// DefaultMaxPathDepth is the deepest path accepted by Get when no
+// WithMaxPathDepth option is given
+const DefaultMaxPathDepth = 64
+
+// WithMaxPathDepth limits the number of segments accepted in a lookup path.
+// Zero keeps DefaultMaxPathDepth and a negative value disables the limit.
+func WithMaxPathDepth(depth int) Option {
+	return func(p *YamlProfile) {
+		p.maxPathDepth = depth
+	}
+}
+
+// WithStrict makes placeholders whose variable is unset and that have no
+// default fail with ErrUnresolved instead of resolving to an empty string
+func WithStrict(strict bool) Option {
+	return func(p *YamlProfile) {
+		p.strict = strict
+	}
+}
+
+// WithPercentVars additionally resolves Windows-style %NAME% and
+// %NAME:default% placeholders, using the same rules as ${} placeholders
+func WithPercentVars(enabled bool) Option {
+	return func(p *YamlProfile) {
+		p.percentVars = enabled
+	}
+}
+
+// WithExecResolver enables ${exec:command} placeholders, which run command
+// and use its stdout as the value. This executes arbitrary programs named
+// in the config, so only enable it for trusted config files.
+func WithExecResolver(enabled bool) Option {
+	return func(p *YamlProfile) {
+		if !enabled {
+			delete(p.schemes, "exec")
+			return
+		}
+		p.setScheme("exec", scheme{resolver: keyResolver(p.resolveExec), rawKey: true})
+	}
+}
+
+// WithExecTimeout bounds the run time of each ${exec:...} command
+func WithExecTimeout(timeout time.Duration) Option {
+	return func(p *YamlProfile) {
+		p.execTimeout = timeout
+	}
+}
+
+// WithPathResolvers restricts the placeholders at path and below to the
+// named resolvers, e.g. WithPathResolvers("secrets", "file") keeps env vars
+// out of the secrets subtree. Plain ${NAME} placeholders are named env.
+// Restrictions from options and $resolvers directives combine, so a
+// config file can narrow but never widen what the code allows.
+func WithPathResolvers(path string, resolvers ...string) Option {
+	return func(p *YamlProfile) {
+		if p.pathResolvers == nil {
+			p.pathResolvers = make(map[string][]string)
+		}
+		p.pathResolvers[path] = resolvers
+	}
+}
+
+// WithMaxAge sets how old the loaded config may get before CheckFresh
+// reports it as stale
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(p *YamlProfile) {
+		p.maxAge = maxAge
+	}
+}
+
+// WithMaxSourceAge sets how old the source of the loaded config, by its
+// SourceTime, may get before CheckFresh reports it as stale
+func WithMaxSourceAge(maxAge time.Duration) Option {
+	return func(p *YamlProfile) {
+		p.maxSourceAge = maxAge
+	}
+}
+
+// WithStaleHandler sets fn to be called by WatchFreshness with the
+// ErrStale error once the config becomes stale
+func WithStaleHandler(fn func(err error)) Option {
+	return func(p *YamlProfile) {
+		p.staleHandler = fn
+	}
+}
+
+// WithVault enables ${vault:path#field} placeholders backed by HashiCorp
+// Vault, e.g. ${vault:secret/data/app#password} for a KV v2 mount
+func WithVault(cfg VaultConfig) Option {
+	return func(p *YamlProfile) {
+		p.setScheme("vault", scheme{resolver: keyResolver(newVaultResolver(cfg).resolve)})
+	}
+}
+
+// WithAWS enables ${aws-sm:name[#jsonKey]} placeholders for Secrets Manager
+// and ${aws-ssm:/path/param} placeholders for SSM Parameter Store, signing
+// requests with credentials from the default AWS chain
+func WithAWS(cfg AWSConfig) Option {
+	return func(p *YamlProfile) {
+		r := newAWSResolver(cfg)
+		p.setScheme("aws-sm", scheme{resolver: keyResolver(r.resolveSecret), splitKey: splitAWSSecretKey})
+		p.setScheme("aws-ssm", scheme{resolver: keyResolver(r.resolveParameter)})
+	}
+}
+
+// WithGCP enables ${gcp-sm:projects/p/secrets/name/versions/latest}
+// placeholders backed by GCP Secret Manager, authenticating with
+// Application Default Credentials
+func WithGCP(cfg GCPConfig) Option {
+	return func(p *YamlProfile) {
+		p.setScheme("gcp-sm", scheme{resolver: keyResolver(newGCPResolver(cfg).resolve)})
+	}
+}
+
+// WithAzure enables ${azure-kv:vaultName/secretName} placeholders backed by
+// Azure Key Vault, authenticating like azidentity's DefaultAzureCredential
+func WithAzure(cfg AzureConfig) Option {
+	return func(p *YamlProfile) {
+		p.setScheme("azure-kv", scheme{resolver: keyResolver(newAzureResolver(cfg).resolve)})
+	}
+}
+
+// WithKubernetes enables ${k8s-secret:namespace/name/key} and
+// ${k8s-configmap:namespace/name/key} placeholders that read Secrets and
+// ConfigMaps from the API server, using in-cluster config or kubeconfig
+func WithKubernetes(cfg KubernetesConfig) Option {
+	return func(p *YamlProfile) {
+		r := newKubernetesResolver(cfg)
+		p.setScheme("k8s-secret", scheme{resolver: keyResolver(r.resolveSecret)})
+		p.setScheme("k8s-configmap", scheme{resolver: keyResolver(r.resolveConfigMap)})
+	}
+}
+
+// WithConsul enables ${consul:key} placeholders that read values from
+// Consul KV, e.g. ${consul:config/app/db_host:localhost}
+func WithConsul(cfg ConsulConfig) Option {
+	return func(p *YamlProfile) {
+		p.setScheme("consul", scheme{resolver: keyResolver(newConsulResolver(cfg).resolve)})
+	}
+}
+
+// WithEnvFile resolves env placeholders from the KEY=VALUE file at path
+// before falling back to the process environment. PollEnv re-reads the
+// file, so values written to it at runtime are picked up.
+func WithEnvFile(path string) Option {
+	return func(p *YamlProfile) {
+		p.envFile = &envFile{path: path}
+		if err := p.envFile.load(); err != nil {
+			p.debugf("Reading env file %s failed: %v\n", path, err)
+		}
+	}
+}
+
+// WithEtcd enables ${etcd:/key} placeholders that read values from etcd v3
+func WithEtcd(cfg EtcdConfig) Option {
+	return func(p *YamlProfile) {
+		p.setScheme("etcd", scheme{resolver: keyResolver(newEtcdResolver(cfg).resolve)})
+	}
+}
+
+// WithResolver registers r for ${name:key} placeholders, see RegisterResolver
+func WithResolver(name string, r Resolver) Option {
+	return func(p *YamlProfile) {
+		p.RegisterResolver(name, r)
+	}
+}
+
+// WithFilter registers f for ${NAME|name} placeholders, see RegisterFilter
+func WithFilter(name string, f Filter) Option {
+	return func(p *YamlProfile) {
+		p.RegisterFilter(name, f)
+	}
+}
+
+// WithKnownFields makes UnmarshalTo fail with a *DecodeError listing the
+// config keys that map to no field of the target, instead of silently
+// dropping them
+func WithKnownFields(enabled bool) Option {
+	return func(p *YamlProfile) {
+		p.knownFields = enabled
+	}
+}
+
+// WithZeroCopy makes GetRaw and the other methods returning maps or lists
+// hand out the profile's own values instead of deep copies, avoiding the
+// copy for large trees. The returned values are read-only views: writing
+// to them changes the profile for every reader, which VerifySeal reports.
+func WithZeroCopy(enabled bool) Option {
+	return func(p *YamlProfile) {
+		p.zeroCopy = enabled
+	}
+}
+
+// WithTypeCoercion controls whether UnmarshalTo guesses the type of
+// resolved placeholders, turning "8080" into an int or "true" into a bool.
+// When disabled, placeholders decode as strings into untyped targets such
+// as map[string]interface{}, and typed fields get the text converted by
+// yaml the same way as a plain value written in the file. Type hints such
+// as ${PORT|int} apply either way. Coercion is enabled by default.
+func WithTypeCoercion(enabled bool) Option {
+	return func(p *YamlProfile) {
+		p.noCoercion = !enabled
+	}
+}
+
+// WithRecursiveExpansion resolves placeholders found in the values of env
+// vars, so DB_URL=postgres://${DB_USER}@db expands DB_USER too. Values may
+// nest up to maxDepth levels before resolution fails with
+// ErrExpansionTooDeep, and a value referring back to a variable being
+// expanded fails with ErrCircularReference. Use $${ for a literal ${ in an
+// env value. A maxDepth of zero, the default, leaves env values as they are.
+func WithRecursiveExpansion(maxDepth int) Option {
+	return func(p *YamlProfile) {
+		p.maxExpansion = maxDepth
+	}
+}
+
+// WithEnvAllowlist restricts placeholders to the env vars matching one of
+// patterns, globs such as "APP_*" in path.Match syntax. Placeholders reading
+// any other variable fail with ErrEnvNotAllowed, even if they have a
+// default. Use it when config files come from untrusted users.
+func WithEnvAllowlist(patterns []string) Option {
+	return func(p *YamlProfile) {
+		p.envAllow = append([]string{}, patterns...)
+	}
+}
+
+// WithEnvDenylist makes placeholders reading an env var that matches one of
+// patterns fail with ErrEnvNotAllowed. The denylist wins over the
+// allowlist.
+func WithEnvDenylist(patterns []string) Option {
+	return func(p *YamlProfile) {
+		p.envDeny = append([]string{}, patterns...)
+	}
+}
+
+// WithListMergeStrategy sets how Merge and Builder combine a list with the
+// list it overrides: replaced, the default, appended to, or merged item by
+// item. Tag a list with !override, !append or !merge in the document being
+// merged in to choose differently for that key alone.
+func WithListMergeStrategy(strategy ListMergeStrategy) Option {
+	return func(p *YamlProfile) {
+		p.listMerge = strategy
+	}
+}
+
+// WithCaseInsensitive makes path lookups match keys regardless of case, so
+// Get("Database.Host") reads database.host. Exact matches are preferred;
+// decoding with UnmarshalTo is unaffected.
+func WithCaseInsensitive(enabled bool) Option {
+	return func(p *YamlProfile) {
+		p.caseInsensitive = enabled
+	}
+}
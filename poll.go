@@ -0,0 +1,187 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeEvent describes a change to a loaded config
+type ChangeEvent struct {
+	// Env lists the environment variables whose value changed, sorted
+	Env []string
+	// Paths lists the paths whose placeholders reference those variables
+	// and therefore resolve to new values, sorted
+	Paths []string
+	// Reloaded is set when the whole config was read again, in which case
+	// Env and Paths are empty and any value may have changed
+	Reloaded bool
+}
+
+// changeHooks holds the OnChange callbacks, shared with derived profiles
+type changeHooks struct {
+	mu    sync.Mutex
+	next  uint64
+	hooks []changeHook
+}
+
+// changeHook is a callback registered with OnChange
+type changeHook struct {
+	id uint64
+	fn func(ChangeEvent)
+}
+
+// OnChange registers fn to be called after a change is detected: an env
+// change found by PollEnv, or a config read again into a loaded profile.
+// The returned function unregisters fn; calling it again does nothing.
+func (p *YamlProfile) OnChange(fn func(ChangeEvent)) (cancel func()) {
+	h := p.hooks
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.next++
+	id := h.next
+	h.hooks = append(h.hooks, changeHook{id: id, fn: fn})
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for i, hook := range h.hooks {
+			if hook.id == id {
+				h.hooks = append(h.hooks[:i:i], h.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify calls the registered OnChange hooks with ev
+func (p *YamlProfile) notify(ev ChangeEvent) {
+	p.hooks.mu.Lock()
+	hooks := append([]changeHook{}, p.hooks.hooks...)
+	p.hooks.mu.Unlock()
+	for _, hook := range hooks {
+		hook.fn(ev)
+	}
+}
+
+// envFile holds the variables read from the file set with WithEnvFile
+type envFile struct {
+	path string
+
+	mu   sync.RWMutex
+	vars map[string]string
+}
+
+// load re-reads the file. Entries are KEY=VALUE separated by newlines or
+// NUL bytes, the format of /proc/<pid>/environ; blank lines and lines
+// starting with # are skipped.
+func (f *envFile) load() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	vars := make(map[string]string)
+	for _, entry := range bytes.FieldsFunc(data, func(r rune) bool { return r == '\n' || r == 0 }) {
+		line := strings.TrimSpace(string(entry))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			vars[strings.TrimSpace(key)] = value
+		}
+	}
+	f.mu.Lock()
+	f.vars = vars
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *envFile) lookup(name string) (string, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	val, ok := f.vars[name]
+	return val, ok
+}
+
+// PollEnv re-reads the environment variables referenced by the config
+// every interval until ctx is done. When values change, the OnChange hooks
+// are called with the affected paths; since placeholders resolve lazily,
+// later Get and UnmarshalTo calls already see the new values. With
+// WithEnvFile the file is re-read on every poll as well, for platforms
+// that rewrite env-backed files at runtime. PollEnv blocks, so run it in
+// its own goroutine.
+func (p *YamlProfile) PollEnv(ctx context.Context, interval time.Duration) error {
+	last := p.envValues()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if p.envFile != nil {
+			if err := p.envFile.load(); err != nil {
+				p.debugf("Reloading env file %s failed: %v\n", p.envFile.path, err)
+				continue
+			}
+		}
+		current := p.envValues()
+		if ev, ok := p.envChange(last, current); ok {
+			p.notify(ev)
+		}
+		last = current
+	}
+}
+
+// envValues returns the current value of every referenced env var, with
+// unset variables missing from the map
+func (p *YamlProfile) envValues() map[string]string {
+	values := make(map[string]string)
+	for _, name := range p.referencedEnv() {
+		if val, ok := p.lookupEnv(name); ok {
+			values[name] = val
+		}
+	}
+	return values
+}
+
+// envChange compares two sets of env values and reports the variables
+// and paths affected
+func (p *YamlProfile) envChange(old, current map[string]string) (ChangeEvent, bool) {
+	changed := make(map[string]bool)
+	for name, val := range current {
+		if prev, ok := old[name]; !ok || prev != val {
+			changed[name] = true
+		}
+	}
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			changed[name] = true
+		}
+	}
+	if len(changed) == 0 {
+		return ChangeEvent{}, false
+	}
+
+	var ev ChangeEvent
+	for name := range changed {
+		ev.Env = append(ev.Env, name)
+	}
+	p.walkPlaceholders(func(path, expr, prefix string) {
+		used := make(map[string]bool)
+		p.collectPlaceholderEnv(expr, prefix, used)
+		for name := range used {
+			if changed[name] {
+				ev.Paths = append(ev.Paths, path)
+				return
+			}
+		}
+	})
+	sort.Strings(ev.Env)
+	sort.Strings(ev.Paths)
+	return ev, true
+}
@@ -0,0 +1,82 @@
+package dollarYaml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_PollEnv(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), "environ")
+	if err := os.WriteFile(envPath, []byte("POLL_HOST=db1\x00POLL_PORT=5432\x00"), 0o644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	os.Setenv("POLL_LEVEL", "info")
+	defer os.Unsetenv("POLL_LEVEL")
+
+	p := New(false, WithEnvFile(envPath))
+	yamlData := []byte(`
+db:
+  host: ${POLL_HOST}
+  port: ${POLL_PORT}
+log:
+  level: ${POLL_LEVEL:debug}
+  format: json
+`)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, p.Get("db.host"), "db1", "host from env file")
+	assert(t, p.Get("log.level"), "info", "level from process env")
+
+	events := make(chan ChangeEvent, 10)
+	p.OnChange(func(ev ChangeEvent) { events <- ev })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.PollEnv(ctx, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	if err := os.WriteFile(envPath, []byte("POLL_HOST=db2\nPOLL_PORT=5432\n"), 0o644); err != nil {
+		t.Fatalf("failed to rewrite env file: %v", err)
+	}
+	select {
+	case ev := <-events:
+		want := ChangeEvent{Env: []string{"POLL_HOST"}, Paths: []string{"db.host"}}
+		if !reflect.DeepEqual(ev, want) {
+			t.Errorf("got event %+v, want %+v", ev, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change event after rewriting env file")
+	}
+	assert(t, p.Get("db.host"), "db2", "host after change")
+
+	os.Unsetenv("POLL_LEVEL")
+	select {
+	case ev := <-events:
+		want := ChangeEvent{Env: []string{"POLL_LEVEL"}, Paths: []string{"log.level"}}
+		if !reflect.DeepEqual(ev, want) {
+			t.Errorf("got event %+v, want %+v", ev, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change event after unsetting env var")
+	}
+	assert(t, p.Get("log.level"), "debug", "level falls back to default")
+}
+
+func TestYamlProfile_OnChangeCancel(t *testing.T) {
+	p := NewProfile()
+	var first, second int
+	cancel := p.OnChange(func(ChangeEvent) { first++ })
+	p.OnChange(func(ChangeEvent) { second++ })
+
+	p.notify(ChangeEvent{Reloaded: true})
+	cancel()
+	cancel()
+	p.notify(ChangeEvent{Reloaded: true})
+	assert(t, first, 1, "cancelled hook calls")
+	assert(t, second, 2, "remaining hook calls")
+}
@@ -1,11 +1,19 @@
 package dollarYaml
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,20 +21,82 @@ import (
 var (
 	ErrValueNotFound = errors.New("value not found")
 	ErrLevelMismatch = errors.New("level does not match")
+	ErrPathTooDeep   = errors.New("path exceeds maximum depth")
+	ErrUnresolved    = errors.New("unresolved placeholder")
+	ErrInvalidRoot   = errors.New("document root must be a mapping or a sequence")
+	ErrDuplicateKey  = errors.New("duplicate key")
 )
 
 // YamlProfile represents a YAML configuration with environment variable support
 type YamlProfile struct {
-	data  map[string]interface{}
-	debug bool
+	// mu guards the state replaced by load, so a reload running in the
+	// background, such as Builder.Watch, never races with readers. It is
+	// shared with derived profiles, and nil in a zero YamlProfile.
+	mu              *profileLock
+	data            interface{}
+	base            string
+	envPrefix       string
+	baseResolvers   []string
+	pathResolvers   map[string][]string
+	raw             []byte
+	env             map[string]string
+	debug           bool
+	strict          bool
+	percentVars     bool
+	maxPathDepth    int
+	schemes         map[string]scheme
+	execTimeout     time.Duration
+	generated       *valueCache
+	tags            map[string]string
+	loadedAt        time.Time
+	sourceTime      time.Time
+	maxAge          time.Duration
+	maxSourceAge    time.Duration
+	staleHandler    func(error)
+	envFile         *envFile
+	hooks           *changeHooks
+	knownFields     bool
+	filters         map[string]Filter
+	zeroCopy        bool
+	noCoercion      bool
+	envAllow        []string
+	envDeny         []string
+	sources         []string
+	maxExpansion    int
+	listMerge       ListMergeStrategy
+	seal            map[string][sha256.Size]byte
+	caseInsensitive bool
+	dotenv          map[string]string
+	remote          remoteState
+	layers          []layerTree
+	envSources      []string
+	// revision counts the trees installed, so update can tell whether the
+	// tree it changed is still current
+	revision uint64
 }
 
-// New creates a new YamlProfile instance with debug option
-func New(debug bool) *YamlProfile {
-	return &YamlProfile{
-		data:  make(map[string]interface{}),
-		debug: debug,
+// NewProfile creates a new YamlProfile configured with opts. Debug output
+// is enabled with WithDebug.
+func NewProfile(opts ...Option) *YamlProfile {
+	p := &YamlProfile{
+		mu:        &profileLock{},
+		data:      make(map[string]interface{}),
+		generated: newValueCache(),
+		hooks:     &changeHooks{},
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// New creates a new YamlProfile instance with debug option. It is kept
+// so existing callers keep compiling; debug is applied before opts, so a
+// WithDebug option overrides it.
+//
+// Deprecated: use NewProfile, passing WithDebug(true) for debug output.
+func New(debug bool, opts ...Option) *YamlProfile {
+	return NewProfile(append([]Option{WithDebug(debug)}, opts...)...)
 }
 
 // SetDebug enables or disables debug logging
@@ -41,205 +111,717 @@ func (p *YamlProfile) debugf(format string, args ...interface{}) {
 	}
 }
 
-// Read unmarshals YAML data into YamlProfile
+// Read unmarshals YAML data into YamlProfile. A stream of several
+// documents separated by --- is merged in order, as Merge would merge
+// them, so later documents override earlier ones.
 func (p *YamlProfile) Read(data []byte) error {
-	var result map[string]interface{}
-	if err := yaml.Unmarshal(data, &result); err != nil {
+	result, tags, err := p.parseYAML(data)
+	if err != nil {
 		return err
 	}
-	p.data = result
+	p.load(data, result, tags)
 	return nil
 }
 
-// ReadFromPath reads and unmarshals YAML from a file path
+// parseYAML decodes a YAML stream as Read does, merging its documents in
+// order, and returns the tree and its custom tags
+func (p *YamlProfile) parseYAML(data []byte) (interface{}, map[string]string, error) {
+	var result interface{} = map[string]interface{}(nil)
+	tags := make(map[string]string)
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for first := true; ; {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, err
+		}
+		docTags := make(map[string]string)
+		collectTags(&doc, "", docTags)
+		tree, err := decodeDocument(&doc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tree == nil {
+			continue
+		}
+		if first {
+			result, tags, first = tree, docTags, false
+			continue
+		}
+		m := merger{lists: p.listMerge, tags: docTags}
+		result = m.value(result, tree, "")
+		for k, v := range docTags {
+			if !isMergeTag(v) {
+				tags[k] = v
+			}
+		}
+	}
+	return result, tags, nil
+}
+
+// decodeDocument decodes a YAML document into a map, or a list for
+// documents whose top level is a sequence. Empty documents give nil.
+func decodeDocument(doc *yaml.Node) (interface{}, error) {
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	switch doc.Content[0].Kind {
+	case yaml.SequenceNode:
+		var list []interface{}
+		if err := doc.Decode(&list); err != nil {
+			return nil, err
+		}
+		return normalizeTree(list), nil
+	case yaml.MappingNode:
+		var m map[string]interface{}
+		if err := doc.Decode(&m); err != nil {
+			return nil, err
+		}
+		return normalizeTree(m), nil
+	}
+	var scalar interface{}
+	if err := doc.Decode(&scalar); err != nil {
+		return nil, err
+	}
+	if scalar != nil {
+		return nil, ErrInvalidRoot
+	}
+	return nil, nil
+}
+
+// load replaces the profile's tree with one decoded from data. The root
+// is a map, or a list for documents whose top level is a sequence.
+func (p *YamlProfile) load(data []byte, result interface{}, tags map[string]string) {
+	p.loadWith(data, result, tags, nil)
+}
+
+// loadWith is load, calling set, if not nil, while the new state is being
+// installed so fields describing the source are replaced along with it
+func (p *YamlProfile) loadWith(data []byte, result interface{}, tags map[string]string, set func()) {
+	result = normalizeTree(result)
+	p.mu.Lock()
+	reloaded := !p.loadedAt.IsZero()
+	p.data = result
+	p.revision++
+	p.tags = tags
+	p.raw = data
+	p.generated = newValueCache()
+	p.loadedAt = time.Now()
+	p.sourceTime = time.Time{}
+	p.sources = nil
+	p.remote = remoteState{}
+	p.layers = nil
+	p.envSources = nil
+	p.seal = nil
+	if set != nil {
+		set()
+	}
+	p.mu.Unlock()
+	if reloaded {
+		p.notify(ChangeEvent{Reloaded: true})
+	}
+}
+
+// update replaces the tree with the result of change, for Set, Delete and
+// Merge. change is given the current tree, which it must not modify, and
+// runs without the lock so it may resolve placeholders; it runs again if
+// a reload or another update installs a tree meanwhile. set, if not nil,
+// is called while the new tree is installed.
+func (p *YamlProfile) update(change func(data interface{}) (interface{}, error), set func()) error {
+	for {
+		p.mu.RLock()
+		data, revision := p.data, p.revision
+		p.mu.RUnlock()
+		updated, err := change(data)
+		if err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		if p.revision != revision {
+			p.mu.Unlock()
+			continue
+		}
+		p.data = updated
+		p.revision++
+		if set != nil {
+			set()
+		}
+		p.mu.Unlock()
+		return nil
+	}
+}
+
+// profileLock is a RWMutex whose methods do nothing on a nil lock, so a
+// zero YamlProfile stays usable
+type profileLock struct {
+	mu sync.RWMutex
+}
+
+func (l *profileLock) Lock() {
+	if l != nil {
+		l.mu.Lock()
+	}
+}
+
+func (l *profileLock) Unlock() {
+	if l != nil {
+		l.mu.Unlock()
+	}
+}
+
+func (l *profileLock) RLock() {
+	if l != nil {
+		l.mu.RLock()
+	}
+}
+
+func (l *profileLock) RUnlock() {
+	if l != nil {
+		l.mu.RUnlock()
+	}
+}
+
+// root returns the loaded tree. A reload replaces the tree instead of
+// changing it, so the tree returned can be walked without the lock.
+func (p *YamlProfile) root() interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.data
+}
+
+// cache returns the cache of generated values of the loaded tree
+func (p *YamlProfile) cache() *valueCache {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.generated
+}
+
+// normalizeTree converts maps with non-string keys, which YAML produces
+// for keys such as 1 or true, into map[string]interface{} so every map in
+// the tree is walked, resolved and decoded the same way
+func normalizeTree(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeTree(item)
+		}
+		return val
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalizeTree(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeTree(item)
+		}
+		return val
+	}
+	return v
+}
+
+// ReadContext is Read with a context. Placeholders resolve lazily, so the
+// context only guards the read itself; pass one to GetContext or
+// UnmarshalToContext to bound resolution.
+func (p *YamlProfile) ReadContext(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.Read(data)
+}
+
+// ReadFromPath reads and unmarshals a file, choosing the codec registered
+// for its extension and falling back to YAML.
+//
+// A map holding an $include key, as in $include: ./common.yaml, is
+// replaced by the tree of the file it names, or of several files merged in
+// order when it names a list, and its other keys are merged over that
+// tree. A value tagged !include, as in db: !include ./db.yaml, is replaced
+// by the tree of the file it names. Relative names resolve against the
+// directory of the including file, and included files are listed after
+// path in the profile's sources. A file that includes itself, directly or
+// through others, fails with ErrIncludeCycle.
 func (p *YamlProfile) ReadFromPath(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading file: %w", err)
 	}
-	return p.Read(data)
+	var modified time.Time
+	if info, err := os.Stat(path); err == nil {
+		modified = info.ModTime()
+	}
+	return p.readIncluding(data, path, osIncluder(p.listMerge), modified)
+}
+
+// ReadFromFS reads the file name in fsys as ReadFromPath reads files on
+// disk, for configs embedded with go:embed or kept in other file systems
+// such as zip archives or fstest.MapFS. The modification time reported
+// by fsys, if any, becomes the SourceTime. Includes are read from fsys.
+func (p *YamlProfile) ReadFromFS(fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+	var modified time.Time
+	if info, err := fs.Stat(fsys, name); err == nil {
+		modified = info.ModTime()
+	}
+	return p.readIncluding(data, name, fsIncluder(fsys, p.listMerge), modified)
+}
+
+// readFile loads the contents of a file with extension ext, decoding them
+// with the codec registered for ext and falling back to YAML. set is
+// passed to loadWith to describe the source.
+func (p *YamlProfile) readFile(data []byte, ext string, set func()) error {
+	result, tags, err := p.parseFile(data, ext)
+	if err != nil {
+		return err
+	}
+	p.loadWith(data, result, tags, set)
+	return nil
+}
+
+// parseFile decodes the contents of a file as readFile does
+func (p *YamlProfile) parseFile(data []byte, ext string) (interface{}, map[string]string, error) {
+	if _, ok := CodecFor(ext); ok {
+		return p.parseAs(data, ext)
+	}
+	return p.parseYAML(data)
 }
 
 // UnmarshalTo unmarshals the YamlProfile into a target struct
 // It first processes any environment variables in the configuration
 // then unmarshals the processed configuration into the target struct
 func (p *YamlProfile) UnmarshalTo(target interface{}) error {
+	return p.UnmarshalToContext(context.Background(), target)
+}
+
+// UnmarshalToContext is UnmarshalTo with a context that bounds and cancels
+// the resolution of remote placeholders
+func (p *YamlProfile) UnmarshalToContext(ctx context.Context, target interface{}) error {
+	if p.knownFields {
+		report, err := p.unmarshalReport(ctx, target)
+		if err != nil {
+			return err
+		}
+		if !report.OK() {
+			return &DecodeError{Report: report}
+		}
+		return nil
+	}
+
 	// Create a copy of the profile to process environment variables
-	processed := make(map[string]interface{})
-	if err := p.processEnvVars(p.data, processed); err != nil {
+	processed, err := p.processValue(ctx, p.base, p.root())
+	if err != nil {
 		return fmt.Errorf("processing environment variables: %w", err)
 	}
+	return p.decode(p.coerceFor(processed, reflect.TypeOf(target)), target)
+}
 
-	p.debugf("Processed config before marshal: %#v\n", processed)
-
-	// Convert processed map to YAML bytes
-	data, err := yaml.Marshal(processed)
+// processEnvVars recursively processes environment variables in the configuration
+func (p *YamlProfile) processEnvVars(ctx context.Context, path string, src map[string]interface{}, dest map[string]interface{}) error {
+	keys, err := p.resolveKeys(ctx, path, src)
 	if err != nil {
-		return fmt.Errorf("marshaling processed config: %w", err)
+		return err
 	}
-
-	p.debugf("Marshaled YAML:\n%s\n", string(data))
-
-	// Unmarshal into target struct
-	if err := yaml.Unmarshal(data, target); err != nil {
-		return fmt.Errorf("unmarshaling to target: %w", err)
+	for k, name := range keys {
+		processed, err := p.processValue(ctx, joinKey(path, k), src[k])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		dest[name] = processed
 	}
-
 	return nil
 }
 
-// processEnvVars recursively processes environment variables in the configuration
-func (p *YamlProfile) processEnvVars(src map[string]interface{}, dest map[string]interface{}) error {
-	for k, v := range src {
-		switch val := v.(type) {
-		case string:
-			// Process environment variables in strings
-			if strings.HasPrefix(val, "${") && strings.HasSuffix(val, "}") {
-				processed, _ := p.resolveValue(val)
-				// Try to convert to appropriate type if the value looks like a number or boolean
-				if num, err := strconv.Atoi(processed); err == nil {
-					dest[k] = num
-					p.debugf("Converted %s to int: %v\n", processed, num)
-				} else if fnum, err := strconv.ParseFloat(processed, 64); err == nil {
-					if float64(int(fnum)) == fnum {
-						dest[k] = int(fnum)
-						p.debugf("Converted %s to int from float: %v\n", processed, int(fnum))
-					} else {
-						dest[k] = fnum
-						p.debugf("Converted %s to float: %v\n", processed, fnum)
-					}
-				} else if strings.EqualFold(processed, "true") || strings.EqualFold(processed, "false") {
-					b := strings.EqualFold(processed, "true")
-					dest[k] = b
-					p.debugf("Converted %s to bool: %v\n", processed, b)
-				} else {
-					dest[k] = processed
-					p.debugf("Kept as string: %s\n", processed)
-				}
-			} else {
-				dest[k] = val
-			}
-		case map[string]interface{}:
-			// Recursively process nested maps
-			nestedDest := make(map[string]interface{})
-			if err := p.processEnvVars(val, nestedDest); err != nil {
-				return err
-			}
-			dest[k] = nestedDest
-		case []interface{}:
-			// Process arrays
-			processed := make([]interface{}, len(val))
-			for i, item := range val {
-				switch itemVal := item.(type) {
-				case string:
-					if strings.HasPrefix(itemVal, "${") && strings.HasSuffix(itemVal, "}") {
-						pval, _ := p.resolveValue(itemVal)
-						// Try to convert array items as well
-						if num, err := strconv.Atoi(pval); err == nil {
-							processed[i] = num
-							p.debugf("Array item converted %s to int: %v\n", pval, num)
-						} else if fnum, err := strconv.ParseFloat(pval, 64); err == nil {
-							if float64(int(fnum)) == fnum {
-								processed[i] = int(fnum)
-								p.debugf("Array item converted %s to int from float: %v\n", pval, int(fnum))
-							} else {
-								processed[i] = fnum
-								p.debugf("Array item converted %s to float: %v\n", pval, fnum)
-							}
-						} else if strings.EqualFold(pval, "true") || strings.EqualFold(pval, "false") {
-							b := strings.EqualFold(pval, "true")
-							processed[i] = b
-							p.debugf("Array item converted %s to bool: %v\n", pval, b)
-						} else {
-							processed[i] = pval
-							p.debugf("Array item kept as string: %s\n", pval)
-						}
-					} else {
-						processed[i] = itemVal
-					}
-				case map[string]interface{}:
-					nestedDest := make(map[string]interface{})
-					if err := p.processEnvVars(itemVal, nestedDest); err != nil {
-						return err
-					}
-					processed[i] = nestedDest
-				default:
-					processed[i] = item
-				}
-			}
-			dest[k] = processed
-		case float64:
-			// Convert float64 to int if it's a whole number
-			if float64(int(val)) == val {
-				dest[k] = int(val)
-				p.debugf("Converted float64 %v to int: %v\n", val, int(val))
-			} else {
-				dest[k] = val
+// processValue resolves the placeholders in the node at path, recursing
+// into maps and lists at any depth. Resolved placeholders are returned as
+// resolvedText; coerceFor turns them into typed values.
+func (p *YamlProfile) processValue(ctx context.Context, path string, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		// Process environment variables in strings
+		if !p.hasPlaceholders(val) {
+			return val, nil
+		}
+		processed, err := p.resolveString(ctx, path, val)
+		if err != nil {
+			return nil, err
+		}
+		return filteredValue(processed), nil
+	case map[string]interface{}:
+		// Recursively process nested maps
+		nestedDest := make(map[string]interface{})
+		if err := p.processEnvVars(ctx, path, val, nestedDest); err != nil {
+			return nil, err
+		}
+		return nestedDest, nil
+	case []interface{}:
+		// Process arrays
+		processed := make([]interface{}, len(val))
+		for i, item := range val {
+			itemVal, err := p.processValue(ctx, joinPath(path, strconv.Itoa(i)), item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
 			}
-		default:
-			dest[k] = v
+			processed[i] = itemVal
+		}
+		return processed, nil
+	case float64:
+		// Convert float64 to int if it's a whole number
+		if float64(int(val)) == val {
+			p.debugf("Converted float64 %v to int: %v\n", val, int(val))
+			return int(val), nil
 		}
+		return val, nil
+	default:
+		return v, nil
 	}
-	return nil
+}
+
+// coerce converts a resolved placeholder to an int, float or bool when the
+// text looks like one, so it decodes into typed struct fields
+func (p *YamlProfile) coerce(processed string) interface{} {
+	// Try to convert to appropriate type if the value looks like a number or boolean
+	if num, err := strconv.Atoi(processed); err == nil {
+		p.debugf("Converted %s to int: %v\n", processed, num)
+		return num
+	} else if fnum, err := strconv.ParseFloat(processed, 64); err == nil {
+		if float64(int(fnum)) == fnum {
+			p.debugf("Converted %s to int from float: %v\n", processed, int(fnum))
+			return int(fnum)
+		}
+		p.debugf("Converted %s to float: %v\n", processed, fnum)
+		return fnum
+	} else if strings.EqualFold(processed, "true") || strings.EqualFold(processed, "false") {
+		b := strings.EqualFold(processed, "true")
+		p.debugf("Converted %s to bool: %v\n", processed, b)
+		return b
+	}
+	p.debugf("Kept as string: %s\n", processed)
+	return processed
 }
 
 // Get retrieves a value by path, returning empty string if not found
 func (p *YamlProfile) Get(path string) string {
-	val, _ := p.GetError(path)
+	val, _ := p.get(context.Background(), path)
 	return val
 }
 
 // GetError retrieves a value by path with error handling
 func (p *YamlProfile) GetError(path string) (string, error) {
-	return p.get(path)
+	val, err := p.get(context.Background(), path)
+	return val, p.suggest(err)
+}
+
+// GetContext retrieves a value by path, passing ctx to the resolvers so
+// remote lookups can be cancelled or given a deadline
+func (p *YamlProfile) GetContext(ctx context.Context, path string) (string, error) {
+	val, err := p.get(ctx, path)
+	return val, p.suggest(err)
 }
 
-func (p *YamlProfile) get(path string) (string, error) {
-	paths := strings.Split(path, ".")
-	var current interface{} = p.data
+// GetPath retrieves a value by its path segments, each taken as a literal
+// key or index, so keys containing dots or brackets need no escaping:
+//
+//	p.GetPath("annotations", "prometheus.io/scrape")
+func (p *YamlProfile) GetPath(segments ...string) (string, error) {
+	path := ""
+	for _, seg := range segments {
+		path = joinKey(path, seg)
+	}
+	val, err := p.get(context.Background(), path)
+	return val, p.suggest(err)
+}
+
+func (p *YamlProfile) get(ctx context.Context, path string) (string, error) {
+	value, rawPath, err := p.lookup(path)
+	if err != nil {
+		return "", err
+	}
+	return p.resolveValue(ctx, joinPath(p.base, rawPath), value)
+}
+
+// lookup walks the raw tree and returns the unresolved node at path along
+// with its path as written in the tree, which differs from path where a
+// key is a placeholder or an index is negative. Failures are *PathError.
+func (p *YamlProfile) lookup(path string) (interface{}, string, error) {
+	paths := splitPath(path)
+	if maxDepth := p.pathDepthLimit(); maxDepth > 0 && len(paths) > maxDepth {
+		return nil, "", fmt.Errorf("%w: %d segments, limit is %d", ErrPathTooDeep, len(paths), maxDepth)
+	}
+	var current interface{} = p.root()
+	rawPath := ""
 
 	for i, key := range paths {
+		if list, ok := current.([]interface{}); ok {
+			if key == lengthKey {
+				current = len(list)
+				rawPath = joinKey(rawPath, key)
+				continue
+			}
+			idx, err := listIndex(key, len(list))
+			if err != nil {
+				return nil, "", p.pathError(path, paths[:i], key, err)
+			}
+			current = list[idx]
+			rawPath = joinPath(rawPath, strconv.Itoa(idx))
+			continue
+		}
+
 		currentMap, ok := current.(map[string]interface{})
 		if !ok {
-			return "", ErrLevelMismatch
+			return nil, "", p.pathError(path, paths[:i], key, ErrLevelMismatch)
 		}
 
+		rawKey := key
 		value, ok := currentMap[key]
 		if !ok {
-			return "", fmt.Errorf("%w: %s", ErrValueNotFound, key)
+			rawKey, ok = p.matchKey(currentMap, rawPath, key)
+			value = currentMap[rawKey]
 		}
-
-		isLastElement := i == len(paths)-1
-		if isLastElement {
-			return p.resolveValue(value)
+		if !ok {
+			return nil, "", p.pathError(path, paths[:i], key, ErrValueNotFound)
 		}
 
 		current = value
+		rawPath = joinKey(rawPath, rawKey)
 	}
 
-	return "", ErrValueNotFound
+	return current, rawPath, nil
 }
 
-// resolveValue handles the conversion and environment variable resolution
-func (p *YamlProfile) resolveValue(value interface{}) (string, error) {
-	// Handle non-string values
-	if str, ok := value.(string); ok {
-		if !strings.HasPrefix(str, "${") || !strings.HasSuffix(str, "}") {
-			return str, nil
+// lengthKey is the pseudo-key giving the length of a list, as in
+// servers.length
+const lengthKey = "length"
+
+// listIndex parses key as an index into a list of n items. Negative
+// indices count from the end, so -1 is the last item.
+func listIndex(key string, n int) (int, error) {
+	idx, err := strconv.Atoi(key)
+	if err != nil {
+		return 0, ErrLevelMismatch
+	}
+	if idx < 0 {
+		idx += n
+	}
+	if idx < 0 || idx >= n {
+		return 0, fmt.Errorf("%w: index %s out of range for %d items", ErrValueNotFound, key, n)
+	}
+	return idx, nil
+}
+
+// matchKey finds the placeholder key of m, the map at rawPath, that
+// resolves to key. Keys that fail to resolve never match. Under
+// WithCaseInsensitive a key differing from key only in case matches too,
+// the first in sorted order winning when several do.
+func (p *YamlProfile) matchKey(m map[string]interface{}, rawPath, key string) (string, bool) {
+	folded := ""
+	for k := range m {
+		name := k
+		if p.hasPlaceholders(k) {
+			resolved, err := p.resolveValue(context.Background(), joinPath(p.base, joinKey(rawPath, k)), k)
+			if err != nil {
+				continue
+			}
+			if resolved == key {
+				return k, true
+			}
+			name = resolved
+		}
+		if p.caseInsensitive && strings.EqualFold(name, key) && (folded == "" || k < folded) {
+			folded = k
+		}
+	}
+	return folded, folded != ""
+}
+
+// resolveKeys resolves the placeholder keys of src, the map at path,
+// returning the key each entry decodes under. Two entries resolving to
+// the same key are an error.
+func (p *YamlProfile) resolveKeys(ctx context.Context, path string, src map[string]interface{}) (map[string]string, error) {
+	keys := make(map[string]string, len(src))
+	owners := make(map[string]string, len(src))
+	for k := range src {
+		if isDirective(k) {
+			continue
+		}
+		name := k
+		if p.hasPlaceholders(k) {
+			resolved, err := p.resolveValue(ctx, joinKey(path, k), k)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", k, err)
+			}
+			name = resolved
+		}
+		if other, ok := owners[name]; ok {
+			return nil, fmt.Errorf("%w: %s and %s both resolve to %q", ErrDuplicateKey, other, k, name)
 		}
+		owners[name] = k
+		keys[k] = name
+	}
+	return keys, nil
+}
 
-		// Strip ${} markers
-		envStr := str[2 : len(str)-1]
-		if colonIdx := strings.Index(envStr, ":"); colonIdx != -1 {
-			envName := envStr[:colonIdx]
-			if envValue := os.Getenv(envName); envValue != "" {
-				return envValue, nil
+// splitPath splits a lookup path into its segments. List indices may be
+// written as dot segments (steps.0.name) or in brackets (steps[0].name, or
+// [0].name when the document root is a list). Brackets also hold keys
+// containing dots, taken literally, as in annotations.[prometheus.io/scrape]
+// or annotations['prometheus.io/scrape'] when the key contains a bracket.
+func splitPath(path string) []string {
+	var segments []string
+	var cur strings.Builder
+	bracketed := false
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '.':
+			if !bracketed || cur.Len() > 0 {
+				segments = append(segments, cur.String())
+			}
+			cur.Reset()
+			bracketed = false
+		case c == '[':
+			key, n, ok := bracketKey(path[i:])
+			if !ok {
+				cur.WriteString(path[i:])
+				i = len(path)
+				continue
+			}
+			if cur.Len() > 0 {
+				segments = append(segments, cur.String())
+				cur.Reset()
+			}
+			segments = append(segments, key)
+			bracketed = true
+			i += n - 1
+		default:
+			if bracketed {
+				// Text right after a bracket starts a new segment
+				bracketed = false
 			}
-			return envStr[colonIdx+1:], nil
+			cur.WriteByte(c)
 		}
+	}
+	if !bracketed || cur.Len() > 0 {
+		segments = append(segments, cur.String())
+	}
+	return segments
+}
+
+// bracketKey parses the bracketed segment s starts with, returning its
+// key and length. Quoted keys may contain brackets.
+func bracketKey(s string) (string, int, bool) {
+	if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+		if end := strings.Index(s[2:], string(s[1])+"]"); end >= 0 {
+			return s[2 : 2+end], end + 4, true
+		}
+		return "", 0, false
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return "", 0, false
+	}
+	return s[1:end], end + 1, true
+}
 
-		return os.Getenv(envStr), nil
+// joinKey appends key to path, bracketing keys that splitPath would
+// otherwise split
+func joinKey(path, key string) string {
+	switch {
+	case strings.Contains(key, "]"):
+		if strings.Contains(key, "'") {
+			key = `["` + key + `"]`
+		} else {
+			key = "['" + key + "']"
+		}
+	case strings.ContainsAny(key, ".["):
+		key = "[" + key + "]"
+	}
+	return joinPath(path, key)
+}
+
+// normalizePath rewrites bracketed indices as dot segments, the form used
+// for absolute paths. Keys containing dots stay bracketed.
+func normalizePath(path string) string {
+	normalized := ""
+	for _, seg := range splitPath(path) {
+		normalized = joinKey(normalized, seg)
+	}
+	return normalized
+}
+
+// parsePlaceholder splits a ${NAME:default} placeholder into its parts
+func parsePlaceholder(str string) (name, defaultValue string, hasDefault bool) {
+	// Strip ${} markers
+	envStr := str[2 : len(str)-1]
+	if colonIdx := strings.Index(envStr, ":"); colonIdx != -1 {
+		return envStr[:colonIdx], envStr[colonIdx+1:], true
+	}
+	return envStr, "", false
+}
+
+// pathDepthLimit returns the effective maximum path depth, 0 meaning unlimited
+func (p *YamlProfile) pathDepthLimit() int {
+	switch {
+	case p.maxPathDepth < 0:
+		return 0
+	case p.maxPathDepth == 0:
+		return DefaultMaxPathDepth
+	}
+	return p.maxPathDepth
+}
+
+// resolveValue handles the conversion and environment variable resolution
+// at path, the absolute dot-path of the value in the source tree
+func (p *YamlProfile) resolveValue(ctx context.Context, path string, value interface{}) (string, error) {
+	// Handle non-string values
+	if str, ok := value.(string); ok {
+		resolved, err := p.resolveString(ctx, path, str)
+		if err != nil {
+			return "", err
+		}
+		return filteredText(resolved), nil
 	}
 
 	return fmt.Sprint(value), nil
 }
+
+// resolveString resolves str at path if it is a placeholder, returning a
+// string or a filter result as described by applyFilters. Placeholders
+// embedded in other text are interpolated into a string.
+func (p *YamlProfile) resolveString(ctx context.Context, path, str string) (interface{}, error) {
+	expr, whole := p.placeholderExpr(str)
+	parts, embedded := parseTemplate(str)
+	if !whole && !embedded {
+		return str, nil
+	}
+	st := newResolveState(ctx, path)
+	st.scope = p.scopeFor(path)
+	return p.resolveTemplate(expr, whole, parts, st)
+}
+
+// resolveTemplate resolves the whole-value placeholder expr, or else the
+// parts of a template as returned by parseTemplate
+func (p *YamlProfile) resolveTemplate(expr string, whole bool, parts []templatePart, st *resolveState) (interface{}, error) {
+	if whole {
+		return p.resolveExpr(expr, st)
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		if part.expr == "" {
+			b.WriteString(part.text)
+			continue
+		}
+		value, err := p.resolvePlaceholder(part.expr, st)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
@@ -1,9 +1,14 @@
 package dollarYaml
 
 import (
+	"context"
 	"errors"
+	"io/fs"
 	"os"
+	"reflect"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestYamlProfile_Read(t *testing.T) {
@@ -175,6 +180,88 @@ test:
 	}
 }
 
+func TestYamlProfile_MultiDocument(t *testing.T) {
+	t.Setenv("MULTI_PORT", "9090")
+
+	yamlData := []byte(`
+server:
+  host: localhost
+  port: 8080
+servers: [a, b]
+---
+# an empty document is skipped
+---
+server:
+  port: ${MULTI_PORT}
+servers: !append [c]
+secret: !vault db/password
+---
+name: last
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"server.host", "localhost"},
+		{"server.port", "9090"},
+		{"servers.2", "c"},
+		{"name", "last"},
+	}
+	for _, tt := range tests {
+		assert(t, p.Get(tt.path), tt.want, tt.path)
+	}
+	n, _ := p.Len("servers")
+	assert(t, n, 3, "appended list")
+	tags := p.Tags()
+	assert(t, tags["secret"], "!vault", "tag from a later document")
+	assert(t, tags["servers"], "", "merge tags are not kept")
+
+	t.Run("lists", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.Read([]byte("[a, b]\n---\n[c]\n")); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		assert(t, p.Get("[0]"), "c", "later list replaces")
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.Read([]byte("a: 1\n---\nplain scalar\n")); !errors.Is(err, ErrInvalidRoot) {
+			t.Errorf("expected ErrInvalidRoot but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_ReadFromFS(t *testing.T) {
+	t.Setenv("TEST_FS_ENV", "from env")
+
+	modTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"config/app.yaml": {Data: []byte("app:\n  name: fs\n  env: ${TEST_FS_ENV:default}\n"), ModTime: modTime},
+		"config/app.toml": {Data: []byte("not yaml: ["), ModTime: modTime},
+	}
+
+	p := NewProfile()
+	if err := p.ReadFromFS(fsys, "config/app.yaml"); err != nil {
+		t.Fatalf("ReadFromFS failed: %v", err)
+	}
+	assert(t, p.Get("app.name"), "fs", "app.name")
+	assert(t, p.Get("app.env"), "from env", "app.env")
+	assert(t, p.SourceTime().Equal(modTime), true, "source time")
+
+	if err := p.ReadFromFS(fsys, "config/missing.yaml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist but got %v", err)
+	}
+	if err := p.ReadFromFS(fsys, "config/app.toml"); err == nil {
+		t.Error("expected an error for an unparsable file")
+	}
+}
+
 func TestYamlProfile_Get_Types(t *testing.T) {
 	yamlData := []byte(`
 values:
@@ -577,3 +664,328 @@ func assert(t *testing.T, got, want interface{}, msg string) {
 		t.Errorf("%s = %v, want %v", msg, got, want)
 	}
 }
+
+func TestYamlProfile_RootList(t *testing.T) {
+	yamlData := []byte(`
+- name: build
+  image: ${BUILD_IMAGE:golang:1.21}
+  args: [go, build, ./...]
+- name: test
+  timeout: ${TEST_TIMEOUT:300}
+`)
+	os.Setenv("BUILD_IMAGE", "golang:1.22")
+	defer os.Unsetenv("BUILD_IMAGE")
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		errType error
+	}{
+		{path: "[0].name", want: "build"},
+		{path: "[0].image", want: "golang:1.22"},
+		{path: "0.name", want: "build"},
+		{path: "[0].args[2]", want: "./..."},
+		{path: "[1].timeout", want: "300"},
+		{path: "[2].name", errType: ErrValueNotFound},
+		{path: "name", errType: ErrLevelMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := p.GetError(tt.path)
+			if tt.errType != nil {
+				if !errors.Is(err, tt.errType) {
+					t.Errorf("expected error %v but got %v", tt.errType, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetError failed: %v", err)
+			}
+			assert(t, got, tt.want, tt.path)
+		})
+	}
+
+	var steps []struct {
+		Name    string   `yaml:"name"`
+		Image   string   `yaml:"image"`
+		Args    []string `yaml:"args"`
+		Timeout int      `yaml:"timeout"`
+	}
+	if err := p.UnmarshalTo(&steps); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, len(steps), 2, "steps length")
+	assert(t, steps[0].Image, "golang:1.22", "step 0 image")
+	assert(t, steps[1].Timeout, 300, "step 1 timeout")
+
+	subs, err := p.SubSlice("")
+	if err != nil {
+		t.Fatalf("SubSlice failed: %v", err)
+	}
+	assert(t, subs[1].Get("timeout"), "300", "root SubSlice element")
+
+	if err := p.Read([]byte("just a scalar")); !errors.Is(err, ErrInvalidRoot) {
+		t.Errorf("expected ErrInvalidRoot but got %v", err)
+	}
+}
+
+func TestYamlProfile_ContextResolution(t *testing.T) {
+	// blocking waits for the context like a remote resolver would
+	blocking := ResolverFunc(func(ctx context.Context, scheme, key string) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "late", nil
+		}
+	})
+	p := New(false, WithResolver("slow", blocking))
+	if err := p.ReadContext(context.Background(), []byte("fast: ${CTX_FAST:ok}\nslow: ${slow:key}\n")); err != nil {
+		t.Fatalf("ReadContext failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	got, err := p.GetContext(ctx, "fast")
+	if err != nil {
+		t.Fatalf("GetContext failed: %v", err)
+	}
+	assert(t, got, "ok", "fast value")
+
+	start := time.Now()
+	if _, err := p.GetContext(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetContext did not honour the deadline, took %v", elapsed)
+	}
+
+	var cfg map[string]string
+	if err := p.UnmarshalToContext(ctx, &cfg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded from UnmarshalToContext, got %v", err)
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if err := p.ReadContext(cancelled, []byte("a: 1\n")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled from ReadContext, got %v", err)
+	}
+}
+
+func TestYamlProfile_PlaceholderKeys(t *testing.T) {
+	t.Setenv("KEYS_REGION", "eu-west-1")
+
+	yamlData := []byte(`
+regions:
+  ${KEYS_REGION:us-east-1}:
+    endpoint: ${KEYS_ENDPOINT:https://eu.example.com}
+  ${KEYS_BACKUP:us-west-2}:
+    endpoint: https://backup.example.com
+  static:
+    endpoint: https://static.example.com
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	assert(t, p.Get("regions.eu-west-1.endpoint"), "https://eu.example.com", "resolved key lookup")
+	assert(t, p.Get("regions.us-west-2.endpoint"), "https://backup.example.com", "default key lookup")
+	assert(t, p.Get("regions.static.endpoint"), "https://static.example.com", "literal key lookup")
+
+	var cfg struct {
+		Regions map[string]struct {
+			Endpoint string `yaml:"endpoint"`
+		} `yaml:"regions"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, len(cfg.Regions), 3, "regions")
+	assert(t, cfg.Regions["eu-west-1"].Endpoint, "https://eu.example.com", "eu-west-1")
+	assert(t, cfg.Regions["us-west-2"].Endpoint, "https://backup.example.com", "us-west-2")
+
+	found := false
+	for _, name := range p.referencedEnv() {
+		found = found || name == "KEYS_BACKUP"
+	}
+	assert(t, found, true, "key placeholders are referenced env")
+
+	t.Run("duplicate keys", func(t *testing.T) {
+		p := New(false)
+		if err := p.Read([]byte("a:\n  ${KEYS_REGION}: 1\n  eu-west-1: 2\n")); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		var out map[string]interface{}
+		if err := p.UnmarshalTo(&out); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected ErrDuplicateKey but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_DottedKeys(t *testing.T) {
+	t.Setenv("KEYS_SCRAPE", "true")
+
+	yamlData := []byte(`
+annotations:
+  prometheus.io/scrape: ${KEYS_SCRAPE:false}
+  prometheus.io/port: "9090"
+  "odd]key": x
+  plain: y
+hosts:
+  - example.com: {port: 443}
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"annotations.[prometheus.io/scrape]", "true"},
+		{"annotations[prometheus.io/port]", "9090"},
+		{"annotations['prometheus.io/port']", "9090"},
+		{"annotations['odd]key']", "x"},
+		{"annotations.plain", "y"},
+		{"hosts[0].[example.com].port", "443"},
+		{"hosts.-1.[example.com].port", "443"},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+	if _, err := p.GetError("annotations.prometheus.io/port"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound for an unbracketed dotted key but got %v", err)
+	}
+
+	got, err := p.GetPath("annotations", "prometheus.io/scrape")
+	if err != nil {
+		t.Fatalf("GetPath failed: %v", err)
+	}
+	assert(t, got, "true", "GetPath")
+	if got, _ := p.GetPath("hosts", "0", "example.com", "port"); got != "443" {
+		t.Errorf("GetPath through a list = %q, want 443", got)
+	}
+
+	t.Run("reported paths", func(t *testing.T) {
+		keys := p.AllKeys()
+		want := []string{
+			"annotations.['odd]key']",
+			"annotations.plain",
+			"annotations.[prometheus.io/port]",
+			"annotations.[prometheus.io/scrape]",
+			"hosts.0.[example.com].port",
+		}
+		if !reflect.DeepEqual(keys, want) {
+			t.Fatalf("AllKeys() = %q, want %q", keys, want)
+		}
+		for _, key := range keys {
+			assert(t, p.Has(key), true, key)
+		}
+
+		results, err := p.Query("$..port")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		assert(t, len(results), 1, "query results")
+		assert(t, p.Get(results[0].Path), "443", results[0].Path)
+	})
+
+	t.Run("mutation", func(t *testing.T) {
+		p := p.Clone()
+		if err := p.Set("annotations.[example.com/team]", "core"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if got, _ := p.GetPath("annotations", "example.com/team"); got != "core" {
+			t.Errorf("set value = %q, want core", got)
+		}
+		if err := p.Delete("annotations[prometheus.io/port]"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		assert(t, p.Has("annotations.[prometheus.io/port]"), false, "deleted key")
+	})
+}
+
+func TestYamlProfile_CaseInsensitive(t *testing.T) {
+	t.Setenv("KEYS_SECTION", "Cache")
+
+	yamlData := []byte(`
+database:
+  host: db.local
+  Port: 5432
+${KEYS_SECTION}:
+  size: 10
+mode: lower
+Mode: upper
+`)
+	p := NewProfile(WithCaseInsensitive(true))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"Database.Host", "db.local"},
+		{"DATABASE.port", "5432"},
+		{"cache.SIZE", "10"},
+		{"mode", "lower"},
+		{"Mode", "upper"},
+		{"MODE", "upper"},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+
+	if err := p.Set("Database.HOST", "db.remote"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	assert(t, p.Get("database.host"), "db.remote", "set through a differently cased path")
+	n, _ := p.Len("database")
+	assert(t, n, 2, "no key added by Set")
+
+	sensitive := NewProfile()
+	if err := sensitive.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if _, err := sensitive.GetError("Database.Host"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound by default but got %v", err)
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *YamlProfile
+		want bool
+	}{
+		{"options form", NewProfile(), false},
+		{"options form with debug", NewProfile(WithDebug(true)), true},
+		{"boolean form", New(true), true},
+		{"boolean form overridden by option", New(true, WithDebug(false)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert(t, tt.p.debug, tt.want, "debug")
+			if err := tt.p.Read([]byte("a: ${NEW_PROFILE_A:1}")); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			assert(t, tt.p.Get("a"), "1", "a")
+		})
+	}
+}
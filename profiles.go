@@ -0,0 +1,63 @@
+package dollarYaml
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProfilesEnv names the env var listing the active profiles, separated by
+// commas, when ReadWithProfiles is given none
+const ProfilesEnv = "APP_PROFILES"
+
+// ReadWithProfiles reads name from baseDir and overlays a file for each
+// active profile in order, as Spring Boot layers application.yaml and
+// application-{profile}.yaml. With no profiles given, they are taken from
+// the ProfilesEnv env var.
+//
+// A name without an extension looks for name.yaml, then name.yml, and the
+// profile files keep the extension found; a name such as config.json is
+// used as is, with profile files named config-{profile}.json. The base
+// file must exist, while missing profile files are skipped. The files
+// are merged as ReadGlob merges them.
+func (p *YamlProfile) ReadWithProfiles(baseDir, name string, profiles ...string) error {
+	if len(profiles) == 0 {
+		env, _ := p.lookupEnv(ProfilesEnv)
+		profiles = strings.Split(env, ",")
+	}
+
+	stem, ext := name, filepath.Ext(name)
+	if ext != "" {
+		stem = strings.TrimSuffix(name, ext)
+	} else {
+		ext = ".yaml"
+		if !fileExists(filepath.Join(baseDir, stem+ext)) && fileExists(filepath.Join(baseDir, stem+".yml")) {
+			ext = ".yml"
+		}
+	}
+	base := filepath.Join(baseDir, stem+ext)
+	if !fileExists(base) {
+		return fmt.Errorf("reading profiles: %w: %s", fs.ErrNotExist, base)
+	}
+
+	files := []string{base}
+	for _, profile := range profiles {
+		profile = strings.TrimSpace(profile)
+		if profile == "" {
+			continue
+		}
+		file := filepath.Join(baseDir, stem+"-"+profile+ext)
+		if fileExists(file) {
+			files = append(files, file)
+		}
+	}
+	return p.readFiles(files)
+}
+
+// fileExists reports whether path names something other than a directory
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
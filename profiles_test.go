@@ -0,0 +1,85 @@
+package dollarYaml
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestYamlProfile_ReadWithProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"application.yaml":       "server:\n  host: localhost\n  port: 8080\nlogging: info\n",
+		"application-prod.yaml":  "server:\n  host: prod.example.com\nlogging: warn\n",
+		"application-debug.yaml": "logging: debug\n",
+		"config.json":            `{"name": "base", "size": 1}`,
+		"config-test.json":       `{"name": "test"}`,
+		"legacy.yml":             "name: legacy\n",
+		"legacy-prod.yml":        "name: legacy-prod\n",
+	})
+
+	tests := []struct {
+		name     string
+		file     string
+		profiles []string
+		env      string
+		want     map[string]string
+		sources  int
+	}{
+		{
+			name:     "explicit profiles in order",
+			file:     "application",
+			profiles: []string{"prod", "debug"},
+			want:     map[string]string{"server.host": "prod.example.com", "server.port": "8080", "logging": "debug"},
+			sources:  3,
+		},
+		{
+			name:    "profiles from env",
+			file:    "application",
+			env:     "debug, prod",
+			want:    map[string]string{"server.host": "prod.example.com", "logging": "warn"},
+			sources: 3,
+		},
+		{
+			name:     "missing profile file skipped",
+			file:     "application",
+			profiles: []string{"staging"},
+			want:     map[string]string{"server.host": "localhost", "logging": "info"},
+			sources:  1,
+		},
+		{
+			name:     "extension kept",
+			file:     "config.json",
+			profiles: []string{"test"},
+			want:     map[string]string{"name": "test", "size": "1"},
+			sources:  2,
+		},
+		{
+			name:     "yml found",
+			file:     "legacy",
+			profiles: []string{"prod"},
+			want:     map[string]string{"name": "legacy-prod"},
+			sources:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ProfilesEnv, tt.env)
+			p := NewProfile()
+			if err := p.ReadWithProfiles(dir, tt.file, tt.profiles...); err != nil {
+				t.Fatalf("ReadWithProfiles failed: %v", err)
+			}
+			for path, want := range tt.want {
+				assert(t, p.Get(path), want, path)
+			}
+			assert(t, len(p.sources), tt.sources, "sources")
+		})
+	}
+
+	p := NewProfile()
+	err := p.ReadWithProfiles(dir, "missing", "prod")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing base file: err = %v, want fs.ErrNotExist", err)
+	}
+	assert(t, p.sources == nil, true, "profile untouched")
+}
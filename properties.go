@@ -0,0 +1,179 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrProperties = errors.New("invalid properties")
+
+func init() {
+	RegisterCodec(propertiesCodec{}, ".properties", "text/x-java-properties")
+}
+
+// ReadProperties loads Java properties data. Dotted keys become nested
+// maps; placeholders in values are resolved as in YAML.
+func (p *YamlProfile) ReadProperties(data []byte) error {
+	return p.ReadAs(data, ".properties")
+}
+
+// propertiesCodec is the built-in codec for Java properties files, read as
+// java.util.Properties.load reads them: keys end at the first unescaped
+// =, : or blank, a backslash ending a line continues it, and \uXXXX and
+// other escapes are decoded. Dotted keys become nested maps, so
+// database.port=5432 is read as database.port. Values are typed as in
+// YAML.
+type propertiesCodec struct{}
+
+func (propertiesCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	root := map[string]interface{}{}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// Join continuation lines, dropping their leading blanks
+		for continued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if continued(line) {
+			line = line[:len(line)-1]
+		}
+
+		key, value, err := splitProperty(line)
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrProperties, start, err)
+		}
+		if err := setLeaf(root, strings.Split(key, "."), typedText(value)); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrProperties, start, err)
+		}
+	}
+	*out = root
+	return nil
+}
+
+// continued reports whether line ends with an odd number of backslashes
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a logical line into its decoded key and value
+func splitProperty(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	rawKey, rest := line[:end], strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	key, err := unescapeProperty(rawKey)
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescapeProperty(rest)
+	return key, value, err
+}
+
+// unescapeProperty decodes the escapes of a key or value
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", errors.New("short \\u escape")
+			}
+			code, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape \\u%s", s[i+1:i+5])
+			}
+			// Surrogate pairs arrive as two escapes
+			r := rune(code)
+			if r >= 0xd800 && r < 0xdc00 && strings.HasPrefix(s[i+5:], `\u`) && i+11 <= len(s) {
+				if low, err := strconv.ParseUint(s[i+7:i+11], 16, 16); err == nil && low >= 0xdc00 && low < 0xe000 {
+					r = (r-0xd800)<<10 + (rune(low) - 0xdc00) + 0x10000
+					i += 6
+				}
+			}
+			b.WriteRune(r)
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+func (propertiesCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	flat := map[string]string{}
+	flattenProperties("", data, flat)
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(propertiesText(k, true) + "=" + propertiesText(flat[k], false) + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// flattenProperties adds the leaves of v to flat under dotted keys, list
+// items under their index. Empty maps and lists have no leaves and are
+// left out.
+func flattenProperties(prefix string, v interface{}, flat map[string]string) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			flattenProperties(join(k), item, flat)
+		}
+	case []interface{}:
+		for i, item := range val {
+			flattenProperties(join(strconv.Itoa(i)), item, flat)
+		}
+	default:
+		flat[prefix] = scalarText(val)
+	}
+}
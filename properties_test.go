@@ -0,0 +1,76 @@
+package dollarYaml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPropertiesCodec(t *testing.T) {
+	t.Setenv("PROPS_DB_HOST", "db.local")
+
+	data := []byte(`# comment
+! also a comment
+database.host = ${PROPS_DB_HOST:localhost}
+database.port:5432
+database.url   jdbc:postgresql://db/app
+app.name=My \
+    Service
+app.greeting=héllo 😀\tworld
+key\ with\ spaces=value
+empty=
+`)
+	p := NewProfile()
+	if err := p.ReadProperties(data); err != nil {
+		t.Fatalf("ReadProperties failed: %v", err)
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"database.host", "db.local"},
+		{"database.port", "5432"},
+		{"database.url", "jdbc:postgresql://db/app"},
+		{"app.name", "My Service"},
+		{"app.greeting", "héllo \U0001F600\tworld"},
+		{"[key with spaces]", "value"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+	port, err := p.GetInt("database.port")
+	if err != nil {
+		t.Fatalf("GetInt failed: %v", err)
+	}
+	assert(t, port, 5432, "typed port")
+
+	t.Run("marshal", func(t *testing.T) {
+		var tree map[string]interface{}
+		if err := (propertiesCodec{}).Unmarshal(data, &tree); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		out, err := propertiesCodec{}.Marshal(tree)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var back map[string]interface{}
+		if err := (propertiesCodec{}).Unmarshal(out, &back); err != nil {
+			t.Fatalf("failed to read marshaled properties: %v", err)
+		}
+		if !reflect.DeepEqual(back, tree) {
+			t.Errorf("round trip = %v, want %v", back, tree)
+		}
+	})
+
+	for _, bad := range []string{"a=1\na.b=2", "a.b=1\na=2", `a=\u12`} {
+		var tree map[string]interface{}
+		if err := (propertiesCodec{}).Unmarshal([]byte(bad), &tree); !errors.Is(err, ErrProperties) {
+			t.Errorf("%q: expected ErrProperties but got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,552 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// QueryResult is a value selected by Query and the path to read it with Get
+type QueryResult struct {
+	Path  string
+	Value interface{}
+}
+
+// Query selects values from the effective config, resolved as by
+// AllSettings, with a JSONPath subset:
+//
+//	$.servers[0].host          child keys and list indices
+//	$.servers[-1]              negative indices count from the end
+//	$.servers[1:3], [::2]      slices with optional step
+//	$.servers[0,2], ['a','b']  unions of indices or quoted keys
+//	$.servers[*].host, $.*     wildcards
+//	$..host                    recursive descent
+//	$.servers[?(@.port > 80)]  filters comparing with ==, !=, <, <=, >, >=
+//	$.servers[?(@.tls)]        filters testing that a key exists
+//
+// Filters may combine comparisons with && and ||, && binding tighter.
+// The leading $ is optional. Results come in document order, map keys
+// sorted; a query matching nothing returns no results and no error.
+func (p *YamlProfile) Query(expr string) ([]QueryResult, error) {
+	sels, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := p.resolvedTree(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	nodes := []QueryResult{{Value: tree}}
+	for _, sel := range sels {
+		var next []QueryResult
+		for _, n := range nodes {
+			next = sel.apply(n, next)
+		}
+		nodes = next
+	}
+	return nodes, nil
+}
+
+// selector is one step of a query, adding the nodes it selects from n to
+// out
+type selector interface {
+	apply(n QueryResult, out []QueryResult) []QueryResult
+}
+
+// childSelector selects map keys by name, or every child when wildcard
+type childSelector struct {
+	names    []string
+	wildcard bool
+}
+
+func (s childSelector) apply(n QueryResult, out []QueryResult) []QueryResult {
+	if s.wildcard {
+		return appendChildren(n, out)
+	}
+	m, ok := n.Value.(map[string]interface{})
+	if !ok {
+		return out
+	}
+	for _, name := range s.names {
+		if v, ok := m[name]; ok {
+			out = append(out, QueryResult{Path: joinKey(n.Path, name), Value: v})
+		}
+	}
+	return out
+}
+
+// indexSelector selects list items by index, counting negative indices
+// from the end
+type indexSelector struct {
+	indices []int
+}
+
+func (s indexSelector) apply(n QueryResult, out []QueryResult) []QueryResult {
+	list, ok := n.Value.([]interface{})
+	if !ok {
+		return out
+	}
+	for _, idx := range s.indices {
+		if idx < 0 {
+			idx += len(list)
+		}
+		if idx >= 0 && idx < len(list) {
+			out = append(out, listItem(n, list, idx))
+		}
+	}
+	return out
+}
+
+// sliceSelector selects list items from start up to end by step, with
+// Python slice semantics
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+func (s sliceSelector) apply(n QueryResult, out []QueryResult) []QueryResult {
+	list, ok := n.Value.([]interface{})
+	if !ok {
+		return out
+	}
+	bound := func(v *int, def int) int {
+		if v == nil {
+			return def
+		}
+		i := *v
+		if i < 0 {
+			i += len(list)
+		}
+		if i < -1 {
+			i = -1
+		}
+		if i > len(list) {
+			i = len(list)
+		}
+		return i
+	}
+	if s.step > 0 {
+		for i := bound(s.start, 0); i < bound(s.end, len(list)); i += s.step {
+			if i >= 0 {
+				out = append(out, listItem(n, list, i))
+			}
+		}
+		return out
+	}
+	start := bound(s.start, len(list)-1)
+	if start >= len(list) {
+		start = len(list) - 1
+	}
+	for i := start; i > bound(s.end, -1); i += s.step {
+		out = append(out, listItem(n, list, i))
+	}
+	return out
+}
+
+// filterSelector selects the children for which cond holds
+type filterSelector struct {
+	cond filterExpr
+}
+
+func (s filterSelector) apply(n QueryResult, out []QueryResult) []QueryResult {
+	for _, child := range appendChildren(n, nil) {
+		if s.cond.eval(child.Value) {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// descendantSelector applies sel to n and to every node below it
+type descendantSelector struct {
+	sel selector
+}
+
+func (s descendantSelector) apply(n QueryResult, out []QueryResult) []QueryResult {
+	out = s.sel.apply(n, out)
+	for _, child := range appendChildren(n, nil) {
+		out = s.apply(child, out)
+	}
+	return out
+}
+
+// appendChildren adds the values of a map, in key order, or the items of
+// a list to out
+func appendChildren(n QueryResult, out []QueryResult) []QueryResult {
+	switch val := n.Value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, QueryResult{Path: joinKey(n.Path, k), Value: val[k]})
+		}
+	case []interface{}:
+		for i := range val {
+			out = append(out, listItem(n, val, i))
+		}
+	}
+	return out
+}
+
+func listItem(n QueryResult, list []interface{}, i int) QueryResult {
+	return QueryResult{Path: joinPath(n.Path, strconv.Itoa(i)), Value: list[i]}
+}
+
+// parseQuery compiles expr into its selectors
+func parseQuery(expr string) ([]selector, error) {
+	rest := strings.TrimSpace(expr)
+	rest = strings.TrimPrefix(rest, "$")
+	var sels []selector
+	for first := true; rest != ""; first = false {
+		descend := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			descend = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case !first && !strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("%w: unexpected %q in %s", ErrInvalidQuery, rest, expr)
+		}
+
+		var sel selector
+		var err error
+		if strings.HasPrefix(rest, "[") {
+			end := closingBracket(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unclosed [ in %s", ErrInvalidQuery, expr)
+			}
+			sel, err = parseBracket(strings.TrimSpace(rest[1:end]))
+			rest = rest[end+1:]
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			if name == "" {
+				err = errors.New("empty key")
+			} else if name == "*" {
+				sel = childSelector{wildcard: true}
+			} else {
+				sel = childSelector{names: []string{name}}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v in %s", ErrInvalidQuery, err, expr)
+		}
+		if descend {
+			sel = descendantSelector{sel: sel}
+		}
+		sels = append(sels, sel)
+	}
+	return sels, nil
+}
+
+// closingBracket returns the index of the ] closing the [ that s starts
+// with, skipping quoted text and nested brackets
+func closingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseBracket parses the inside of [...]
+func parseBracket(s string) (selector, error) {
+	switch {
+	case s == "*":
+		return childSelector{wildcard: true}, nil
+	case strings.HasPrefix(s, "?"):
+		body := strings.TrimSpace(s[1:])
+		if strings.HasPrefix(body, "(") && strings.HasSuffix(body, ")") {
+			body = body[1 : len(body)-1]
+		}
+		cond, err := parseFilter(body)
+		if err != nil {
+			return nil, err
+		}
+		return filterSelector{cond: cond}, nil
+	case len(splitOutsideQuotes(s, ":")) > 1:
+		return parseSlice(s)
+	}
+
+	parts := splitOutsideQuotes(s, ",")
+	var names []string
+	var indices []int
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if name, ok := unquote(part); ok {
+			names = append(names, name)
+			continue
+		}
+		idx, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an index nor a quoted key", part)
+		}
+		indices = append(indices, idx)
+	}
+	if names != nil && indices != nil {
+		return nil, fmt.Errorf("cannot mix keys and indices in [%s]", s)
+	}
+	if names != nil {
+		return childSelector{names: names}, nil
+	}
+	return indexSelector{indices: indices}, nil
+}
+
+// parseSlice parses start:end:step with every part optional
+func parseSlice(s string) (selector, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("slice %q has too many parts", s)
+	}
+	sel := sliceSelector{step: 1}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("slice %q: %q is not an integer", s, part)
+		}
+		switch i {
+		case 0:
+			sel.start = &v
+		case 1:
+			sel.end = &v
+		case 2:
+			if v == 0 {
+				return nil, fmt.Errorf("slice %q has a zero step", s)
+			}
+			sel.step = v
+		}
+	}
+	return sel, nil
+}
+
+// filterExpr is a filter condition: comparisons joined by && within each
+// alternative, and alternatives joined by ||
+type filterExpr [][]comparison
+
+func (f filterExpr) eval(v interface{}) bool {
+	for _, all := range f {
+		ok := true
+		for _, c := range all {
+			if !c.eval(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// comparison compares the value at path below @ with a literal, or tests
+// that the path exists when op is empty
+type comparison struct {
+	path    []string
+	op      string
+	literal interface{}
+}
+
+var comparisonOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseFilter(s string) (filterExpr, error) {
+	var f filterExpr
+	for _, alt := range splitOutsideQuotes(s, "||") {
+		var all []comparison
+		for _, term := range splitOutsideQuotes(alt, "&&") {
+			c, err := parseComparison(strings.TrimSpace(term))
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, c)
+		}
+		f = append(f, all)
+	}
+	return f, nil
+}
+
+func parseComparison(s string) (comparison, error) {
+	for _, op := range comparisonOps {
+		parts := splitOutsideQuotes(s, op)
+		if len(parts) != 2 {
+			continue
+		}
+		left, right := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !strings.HasPrefix(left, "@") {
+			return comparison{}, fmt.Errorf("filter %q must compare @ with a value", s)
+		}
+		lit, err := parseLiteral(right)
+		if err != nil {
+			return comparison{}, err
+		}
+		return comparison{path: filterPath(left), op: op, literal: lit}, nil
+	}
+	if !strings.HasPrefix(s, "@") {
+		return comparison{}, fmt.Errorf("filter %q must start with @", s)
+	}
+	return comparison{path: filterPath(s)}, nil
+}
+
+// filterPath splits @.a.b[0] into its segments
+func filterPath(s string) []string {
+	rest := strings.TrimPrefix(strings.TrimPrefix(s, "@"), ".")
+	if rest == "" {
+		return nil
+	}
+	return splitPath(rest)
+}
+
+func parseLiteral(s string) (interface{}, error) {
+	if str, ok := unquote(s); ok {
+		return str, nil
+	}
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("%q is not a number, quoted string, bool or null", s)
+}
+
+func (c comparison) eval(v interface{}) bool {
+	for _, seg := range c.path {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = val[seg]; !ok {
+				return false
+			}
+		case []interface{}:
+			idx, err := listIndex(seg, len(val))
+			if err != nil {
+				return false
+			}
+			v = val[idx]
+		default:
+			return false
+		}
+	}
+	if c.op == "" {
+		return true
+	}
+
+	if a, ok := toNumber(v); ok {
+		if b, ok := toNumber(c.literal); ok {
+			return compareOrdered(a, b, c.op)
+		}
+	}
+	if a, ok := v.(string); ok {
+		if b, ok := c.literal.(string); ok {
+			return compareOrdered(a, b, c.op)
+		}
+	}
+	switch c.op {
+	case "==":
+		return v == c.literal
+	case "!=":
+		return v != c.literal
+	}
+	return false
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func compareOrdered[T float64 | string](a, b T, op string) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// unquote returns the text of a single or double quoted string
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], true
+	}
+	return "", false
+}
+
+// splitOutsideQuotes splits s around sep where sep is not quoted
+func splitOutsideQuotes(s, sep string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
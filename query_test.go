@@ -0,0 +1,81 @@
+package dollarYaml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_Query(t *testing.T) {
+	t.Setenv("QUERY_PORT", "8443")
+
+	yamlData := []byte(`
+servers:
+  - {name: a, port: 80, tls: false}
+  - {name: b, port: "${QUERY_PORT:443}", tls: true}
+  - {name: c, port: 8080}
+database:
+  name: main
+  replica: {name: r1}
+tags: [x, y, z, w]
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		expr      string
+		wantPaths []string
+		wantVals  []interface{}
+	}{
+		{"$.servers[0].name", []string{"servers.0.name"}, []interface{}{"a"}},
+		{"servers[-1].port", []string{"servers.2.port"}, []interface{}{8080}},
+		{"$.servers[*].port", []string{"servers.0.port", "servers.1.port", "servers.2.port"}, []interface{}{80, 8443, 8080}},
+		{"$.tags[1:3]", []string{"tags.1", "tags.2"}, []interface{}{"y", "z"}},
+		{"$.tags[::2]", []string{"tags.0", "tags.2"}, []interface{}{"x", "z"}},
+		{"$.tags[::-1]", []string{"tags.3", "tags.2", "tags.1", "tags.0"}, []interface{}{"w", "z", "y", "x"}},
+		{"$.tags[-2:]", []string{"tags.2", "tags.3"}, []interface{}{"z", "w"}},
+		{"$.tags[0,3]", []string{"tags.0", "tags.3"}, []interface{}{"x", "w"}},
+		{"$.database['name','missing']", []string{"database.name"}, []interface{}{"main"}},
+		{"$..name", []string{"database.name", "database.replica.name", "servers.0.name", "servers.1.name", "servers.2.name"}, []interface{}{"main", "r1", "a", "b", "c"}},
+		{"$.servers[?(@.port > 1000)].name", []string{"servers.1.name", "servers.2.name"}, []interface{}{"b", "c"}},
+		{"$.servers[?(@.tls == true || @.name == 'a')].name", []string{"servers.0.name", "servers.1.name"}, []interface{}{"a", "b"}},
+		{"$.servers[?(@.port >= 80 && @.port < 1000)].name", []string{"servers.0.name"}, []interface{}{"a"}},
+		{"$.database[?(@.name)]", []string{"database.replica"}, []interface{}{map[string]interface{}{"name": "r1"}}},
+		{"$.tags[?(@ != 'x')]", []string{"tags.1", "tags.2", "tags.3"}, []interface{}{"y", "z", "w"}},
+		{"$.missing.key", nil, nil},
+		{"$.tags[9]", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			results, err := p.Query(tt.expr)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			var paths []string
+			var vals []interface{}
+			for _, r := range results {
+				paths = append(paths, r.Path)
+				vals = append(vals, r.Value)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("paths = %v, want %v", paths, tt.wantPaths)
+			}
+			if !reflect.DeepEqual(vals, tt.wantVals) {
+				t.Errorf("values = %#v, want %#v", vals, tt.wantVals)
+			}
+			for _, path := range paths {
+				if !p.Has(path) {
+					t.Errorf("result path %s is not found by Has", path)
+				}
+			}
+		})
+	}
+
+	for _, expr := range []string{"$.tags[", "$.tags[a]", "$.tags[1:2:0]", "$.tags[?(x > 1)]", "$.servers[0]name", "$.tags['a',1]", "$.tags[?(@ > bad)]"} {
+		if _, err := p.Query(expr); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Query(%q): expected ErrInvalidQuery but got %v", expr, err)
+		}
+	}
+}
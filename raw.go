@@ -0,0 +1,78 @@
+package dollarYaml
+
+import "fmt"
+
+// GetRaw returns the node at path as loaded, with placeholders left
+// unresolved: a string, number, bool, nil, map[string]interface{} or
+// []interface{}. An empty path returns the whole tree. Maps and lists are
+// deep copies the caller may modify freely, unless WithZeroCopy is set.
+func (p *YamlProfile) GetRaw(path string) (interface{}, error) {
+	if path == "" {
+		return p.handOut(p.root()), nil
+	}
+	value, _, err := p.lookup(path)
+	if err != nil {
+		return nil, p.suggest(err)
+	}
+	return p.handOut(value), nil
+}
+
+// handOut prepares a raw tree value for returning to a caller. Maps and
+// lists are copied so callers cannot mutate the profile, except under
+// WithZeroCopy where the shared value is returned as a read-only view.
+func (p *YamlProfile) handOut(v interface{}) interface{} {
+	if p.zeroCopy {
+		return v
+	}
+	return copyTree(v)
+}
+
+// Has reports whether path exists in the tree, whatever its value. Unlike
+// checking Get for an empty string, it tells a missing key from one that
+// is empty, null or a placeholder resolving to empty.
+func (p *YamlProfile) Has(path string) bool {
+	_, _, err := p.lookup(path)
+	return err == nil
+}
+
+// IsNil reports whether path exists and holds an explicit null, written
+// as ~, null or nothing at all after the key. Placeholders are not
+// resolved, so a placeholder resolving to empty is not nil.
+func (p *YamlProfile) IsNil(path string) bool {
+	value, _, err := p.lookup(path)
+	return err == nil && value == nil
+}
+
+// Len returns the number of items of the list at path, or of keys of the
+// map at path, so lists can be iterated through the path API:
+//
+//	n, _ := p.Len("servers")
+//	for i := 0; i < n; i++ {
+//		host := p.Get(fmt.Sprintf("servers.%d.host", i))
+//	}
+//
+// Lookups also accept servers.length, and negative indices such as
+// servers.-1.host counting from the end. Scalars fail with
+// ErrLevelMismatch.
+func (p *YamlProfile) Len(path string) (int, error) {
+	var value interface{} = p.root()
+	if path != "" {
+		var err error
+		if value, _, err = p.lookup(path); err != nil {
+			return 0, err
+		}
+	}
+	switch val := value.(type) {
+	case []interface{}:
+		return len(val), nil
+	case map[string]interface{}:
+		n := 0
+		for k := range val {
+			if !isDirective(k) {
+				n++
+			}
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%w: %s is not a list or map", ErrLevelMismatch, path)
+}
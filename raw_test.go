@@ -0,0 +1,171 @@
+package dollarYaml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_GetRaw(t *testing.T) {
+	yamlData := []byte(`
+server:
+  host: ${RAW_HOST:localhost}
+  port: 8080
+  tags: [a, b]
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	p.Seal()
+
+	raw, err := p.GetRaw("server")
+	if err != nil {
+		t.Fatalf("GetRaw failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"host": "${RAW_HOST:localhost}",
+		"port": 8080,
+		"tags": []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(raw, want) {
+		t.Errorf("got %v, want %v", raw, want)
+	}
+
+	// Writing into the returned copy leaves the profile untouched
+	server := raw.(map[string]interface{})
+	server["port"] = 1
+	server["tags"].([]interface{})[0] = "z"
+	root, _ := p.GetRaw("")
+	root.(map[string]interface{})["server"] = nil
+	if err := p.VerifySeal(); err != nil {
+		t.Errorf("profile changed through a returned copy: %v", err)
+	}
+	assert(t, p.Get("server.tags.0"), "a", "server.tags.0")
+
+	host, err := p.GetRaw("server.host")
+	if err != nil {
+		t.Fatalf("GetRaw failed: %v", err)
+	}
+	assert(t, host, "${RAW_HOST:localhost}", "raw scalar")
+
+	if _, err := p.GetRaw("server.missing"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+
+	t.Run("zero copy", func(t *testing.T) {
+		p := New(false, WithZeroCopy(true))
+		if err := p.Read(yamlData); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		raw, err := p.GetRaw("server")
+		if err != nil {
+			t.Fatalf("GetRaw failed: %v", err)
+		}
+		raw.(map[string]interface{})["port"] = 9090
+		assert(t, p.Get("server.port"), "9090", "shared view")
+	})
+}
+
+func TestYamlProfile_HasIsNil(t *testing.T) {
+	yamlData := []byte(`
+name: app
+empty: ""
+unset: ${HAS_UNSET}
+nothing:
+tilde: ~
+server:
+  ports: [80, null]
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		has   bool
+		isNil bool
+	}{
+		{"name", true, false},
+		{"empty", true, false},
+		{"unset", true, false},
+		{"nothing", true, true},
+		{"tilde", true, true},
+		{"server", true, false},
+		{"server.ports.1", true, true},
+		{"server.ports.2", false, false},
+		{"missing", false, false},
+		{"name.child", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert(t, p.Has(tt.path), tt.has, "Has")
+			assert(t, p.IsNil(tt.path), tt.isNil, "IsNil")
+		})
+	}
+}
+
+func TestYamlProfile_ListAddressing(t *testing.T) {
+	yamlData := []byte(`
+servers:
+  - {host: a, port: 80}
+  - {host: b, port: 81}
+  - {host: "${LIST_HOST:c}", port: 82}
+labels: {app: web, $envPrefix: APP_}
+name: app
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"servers.-1.host", "c", nil},
+		{"servers[-2].port", "81", nil},
+		{"servers.-3.host", "a", nil},
+		{"servers.-4.host", "", ErrValueNotFound},
+		{"servers.length", "3", nil},
+		{"servers.length.x", "", ErrLevelMismatch},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("Get(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+
+	lens := []struct {
+		path    string
+		want    int
+		wantErr error
+	}{
+		{"servers", 3, nil},
+		{"servers.0", 2, nil},
+		{"labels", 1, nil},
+		{"", 3, nil},
+		{"name", 0, ErrLevelMismatch},
+		{"missing", 0, ErrValueNotFound},
+	}
+	for _, tt := range lens {
+		got, err := p.Len(tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("Len(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+		}
+		assert(t, got, tt.want, "Len("+tt.path+")")
+	}
+
+	if err := p.Set("servers.-1.port", 90); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	assert(t, p.Get("servers.2.port"), "90", "set through a negative index")
+	if err := p.Delete("servers.-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	assert(t, p.Get("servers.length"), "2", "length after delete")
+}
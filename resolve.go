@@ -0,0 +1,282 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrCircularReference = errors.New("circular reference")
+	ErrExpansionTooDeep  = errors.New("placeholder expansion too deep")
+)
+
+// CycleError reports a placeholder whose expansion refers back to itself.
+// Chain lists the references in expansion order, starting and ending with
+// the name that closes the cycle.
+type CycleError struct {
+	Chain []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrCircularReference, strings.Join(e.Chain, " -> "))
+}
+
+func (e *CycleError) Unwrap() error {
+	return ErrCircularReference
+}
+
+// resolveState tracks the references currently being expanded so nested
+// expansion can detect cycles instead of recursing forever
+type resolveState struct {
+	ctx   context.Context
+	path  string
+	scope scope
+	stack []string
+	depth int
+}
+
+func newResolveState(ctx context.Context, path string) *resolveState {
+	return &resolveState{ctx: ctx, path: path}
+}
+
+// enter pushes name onto the expansion stack, failing if it is already there
+func (s *resolveState) enter(name string) error {
+	for i, n := range s.stack {
+		if n == name {
+			chain := append(append([]string{}, s.stack[i:]...), name)
+			return &CycleError{Chain: chain}
+		}
+	}
+	s.stack = append(s.stack, name)
+	return nil
+}
+
+// leave pops the innermost reference from the expansion stack
+func (s *resolveState) leave() {
+	s.stack = s.stack[:len(s.stack)-1]
+}
+
+// resolvePlaceholder resolves a single ${NAME:default} placeholder and
+// its filters, rendering a list result as text
+func (p *YamlProfile) resolvePlaceholder(str string, st *resolveState) (string, error) {
+	value, err := p.resolveExpr(str, st)
+	if err != nil {
+		return "", err
+	}
+	return filteredText(value), nil
+}
+
+// resolveExpr resolves a placeholder and runs its filter chain, returning
+// a string or a filter result as described by applyFilters
+func (p *YamlProfile) resolveExpr(str string, st *resolveState) (interface{}, error) {
+	base, calls := p.splitFilters(str)
+	value, err := p.resolveSingle(base, st)
+	if err != nil {
+		return nil, err
+	}
+	if len(calls) == 0 {
+		return value, nil
+	}
+	return applyFilters(value, calls)
+}
+
+// resolveSingle resolves a placeholder without filters
+func (p *YamlProfile) resolveSingle(str string, st *resolveState) (string, error) {
+	envName, defaultValue, hasDefault := parsePlaceholder(str)
+	if sch, ok := p.scheme(envName); ok && (hasDefault || sch.bare) {
+		if err := st.scope.checkResolver(envName, st.path); err != nil {
+			return "", err
+		}
+		return p.resolveScheme(envName, sch, defaultValue, hasDefault, st)
+	}
+	if err := st.scope.checkResolver("env", st.path); err != nil {
+		return "", err
+	}
+	envName = st.scope.envPrefix + envName
+	if err := p.checkEnv(envName); err != nil {
+		return "", err
+	}
+	if err := st.enter(envName); err != nil {
+		return "", err
+	}
+	defer st.leave()
+
+	envValue, _ := p.lookupEnv(envName)
+	if hasDefault && strings.HasPrefix(defaultValue, "+") {
+		// ${NAME:+text} gives text only when NAME is set, as in the shell
+		if envValue == "" {
+			return "", nil
+		}
+		return p.expandDefault(defaultValue[1:], st)
+	}
+	if envValue == "" && hasDefault {
+		return p.expandDefault(defaultValue, st)
+	}
+	if envValue == "" && p.strict {
+		return "", fmt.Errorf("%w: %s is not set and has no default", ErrUnresolved, envName)
+	}
+	if p.maxExpansion > 0 {
+		return p.expandEnvValue(envName, envValue, st)
+	}
+	return envValue, nil
+}
+
+// expandEnvValue resolves the placeholders in the value of env var name,
+// as enabled by WithRecursiveExpansion. name stays on the expansion stack
+// meanwhile, so a value referring back to it fails with a CycleError.
+func (p *YamlProfile) expandEnvValue(name, value string, st *resolveState) (string, error) {
+	expr, whole := p.placeholderExpr(value)
+	parts, embedded := parseTemplate(value)
+	if !whole && !embedded {
+		return value, nil
+	}
+	if st.depth >= p.maxExpansion {
+		return "", fmt.Errorf("%w: %s exceeds %d levels", ErrExpansionTooDeep, name, p.maxExpansion)
+	}
+	st.depth++
+	defer func() { st.depth-- }()
+
+	expanded, err := p.resolveTemplate(expr, whole, parts, st)
+	if err != nil {
+		return "", err
+	}
+	return filteredText(expanded), nil
+}
+
+// isPlaceholder reports whether str is a whole-value placeholder
+func (p *YamlProfile) isPlaceholder(str string) bool {
+	_, ok := p.placeholderExpr(str)
+	return ok
+}
+
+// hasPlaceholders reports whether str needs resolving: it is a whole-value
+// placeholder or text with placeholders or $${ escapes embedded in it
+func (p *YamlProfile) hasPlaceholders(str string) bool {
+	if p.isPlaceholder(str) {
+		return true
+	}
+	_, ok := parseTemplate(str)
+	return ok
+}
+
+// placeholderExpr returns str in ${NAME:default} form if it is a
+// placeholder. With WithPercentVars enabled, %NAME:default% placeholders
+// are recognised too and rewritten into the ${} form.
+func (p *YamlProfile) placeholderExpr(str string) (string, bool) {
+	if strings.HasPrefix(str, "${") && strings.HasSuffix(str, "}") {
+		if parts, _ := parseTemplate(str); len(parts) == 1 && parts[0].expr == str {
+			return str, true
+		}
+	}
+	if p.percentVars && len(str) > 2 && str[0] == '%' && str[len(str)-1] == '%' {
+		inner := str[1 : len(str)-1]
+		name := inner
+		if colonIdx := strings.Index(inner, ":"); colonIdx != -1 {
+			name = inner[:colonIdx]
+		}
+		if isEnvName(name) && !strings.Contains(inner, "%") {
+			return "${" + inner + "}", true
+		}
+	}
+	return "", false
+}
+
+// templatePart is a run of literal text or a placeholder inside a value
+type templatePart struct {
+	text string
+	// expr is the ${...} placeholder, empty for literal text
+	expr string
+}
+
+// parseTemplate splits str into literal text and ${...} placeholders, as
+// found in URLs or multi-line block scalars holding JSON or PEM templates.
+// Inside a placeholder braces are balanced, so a default may itself hold
+// JSON. $${ is a literal ${. An unterminated ${ is kept as text. ok
+// reports whether str held any placeholder or escape.
+func parseTemplate(str string) (parts []templatePart, ok bool) {
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, templatePart{text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(str); {
+		if strings.HasPrefix(str[i:], "$${") {
+			text.WriteString("${")
+			i += 3
+			ok = true
+			continue
+		}
+		if !strings.HasPrefix(str[i:], "${") {
+			text.WriteByte(str[i])
+			i++
+			continue
+		}
+		end := -1
+		depth := 0
+		for j := i + 2; j < len(str); j++ {
+			if str[j] == '{' {
+				depth++
+			} else if str[j] == '}' {
+				if depth == 0 {
+					end = j
+					break
+				}
+				depth--
+			}
+		}
+		if end == -1 {
+			text.WriteString(str[i:])
+			break
+		}
+		flush()
+		parts = append(parts, templatePart{expr: str[i : end+1]})
+		i = end + 1
+		ok = true
+	}
+	flush()
+	return parts, ok
+}
+
+// isEnvName reports whether name is usable as an environment variable name
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// expandDefault expands $VAR and ${VAR[:default]} references inside the
+// default segment of a placeholder. A literal dollar sign is written as $$.
+func (p *YamlProfile) expandDefault(str string, st *resolveState) (string, error) {
+	if !strings.Contains(str, "$") {
+		return str, nil
+	}
+	var firstErr error
+	expanded := os.Expand(str, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		val, err := p.resolvePlaceholder("${"+name+"}", st)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return val
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return expanded, nil
+}
@@ -0,0 +1,363 @@
+package dollarYaml
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestResolveState_Cycle(t *testing.T) {
+	st := newResolveState(context.Background(), "")
+	for _, name := range []string{"A", "B", "C"} {
+		if err := st.enter(name); err != nil {
+			t.Fatalf("enter(%s) failed: %v", name, err)
+		}
+	}
+
+	err := st.enter("B")
+	if !errors.Is(err, ErrCircularReference) {
+		t.Fatalf("expected ErrCircularReference but got %v", err)
+	}
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected *CycleError but got %T", err)
+	}
+	if want := []string{"B", "C", "B"}; !reflect.DeepEqual(cycle.Chain, want) {
+		t.Errorf("Chain = %v, want %v", cycle.Chain, want)
+	}
+	if err.Error() != "circular reference: B -> C -> B" {
+		t.Errorf("unexpected message %q", err)
+	}
+
+	st.leave()
+	if err := st.enter("C"); err != nil {
+		t.Errorf("re-entering after leave failed: %v", err)
+	}
+}
+
+func TestYamlProfile_DefaultExpansion(t *testing.T) {
+	yamlData := []byte(`
+server:
+  port: ${DEF_PORT:$DEF_FALLBACK_PORT}
+  host: ${DEF_HOST:${DEF_FALLBACK_HOST:localhost}}
+  url: ${DEF_URL:http://${DEF_FALLBACK_HOST:localhost}:$DEF_FALLBACK_PORT/api}
+  price: ${DEF_PRICE:$$5}
+`)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		path string
+		want string
+	}{
+		{
+			name: "bare variable in default",
+			env:  map[string]string{"DEF_FALLBACK_PORT": "9090"},
+			path: "server.port",
+			want: "9090",
+		},
+		{
+			name: "primary variable wins over default",
+			env:  map[string]string{"DEF_PORT": "80", "DEF_FALLBACK_PORT": "9090"},
+			path: "server.port",
+			want: "80",
+		},
+		{
+			name: "nested placeholder default",
+			path: "server.host",
+			want: "localhost",
+		},
+		{
+			name: "nested placeholder resolved from env",
+			env:  map[string]string{"DEF_FALLBACK_HOST": "fallback.local"},
+			path: "server.host",
+			want: "fallback.local",
+		},
+		{
+			name: "mixed text and references",
+			env:  map[string]string{"DEF_FALLBACK_PORT": "8080"},
+			path: "server.url",
+			want: "http://localhost:8080/api",
+		},
+		{
+			name: "escaped dollar sign",
+			path: "server.price",
+			want: "$5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			p := New(false)
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			got, err := p.GetError(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYamlProfile_PercentVars(t *testing.T) {
+	yamlData := []byte(`
+service:
+  home: '%PCT_HOME%'
+  logs: '%PCT_LOGS:C:\logs%'
+  ratio: 50%
+  literal: '%not a var%'
+  dollar: ${PCT_HOME:fallback}
+`)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		env     map[string]string
+		path    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "disabled by default",
+			env:  map[string]string{"PCT_HOME": `D:\svc`},
+			path: "service.home",
+			want: "%PCT_HOME%",
+		},
+		{
+			name: "resolves from env",
+			opts: []Option{WithPercentVars(true)},
+			env:  map[string]string{"PCT_HOME": `D:\svc`},
+			path: "service.home",
+			want: `D:\svc`,
+		},
+		{
+			name: "falls back to default",
+			opts: []Option{WithPercentVars(true)},
+			path: "service.logs",
+			want: `C:\logs`,
+		},
+		{
+			name: "trailing percent is literal",
+			opts: []Option{WithPercentVars(true)},
+			path: "service.ratio",
+			want: "50%",
+		},
+		{
+			name: "invalid name is literal",
+			opts: []Option{WithPercentVars(true)},
+			path: "service.literal",
+			want: "%not a var%",
+		},
+		{
+			name:    "strict mode rejects unset variable",
+			opts:    []Option{WithPercentVars(true), WithStrict(true)},
+			path:    "service.home",
+			wantErr: ErrUnresolved,
+		},
+		{
+			name: "strict mode allows placeholders with defaults",
+			opts: []Option{WithStrict(true)},
+			path: "service.dollar",
+			want: "fallback",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			p := New(false, tt.opts...)
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			got, err := p.GetError(tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v but got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("strict mode fails UnmarshalTo", func(t *testing.T) {
+		p := New(false, WithPercentVars(true), WithStrict(true))
+		if err := p.Read(yamlData); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		var config map[string]interface{}
+		if err := p.UnmarshalTo(&config); !errors.Is(err, ErrUnresolved) {
+			t.Errorf("expected ErrUnresolved but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_AlternativeValue(t *testing.T) {
+	yamlData := []byte(`
+ssl: ${ALT_SSL:+?sslmode=require}
+level: ${ALT_DEBUG:+debug}
+host: ${ALT_DEBUG:+${ALT_HOST:localhost}}
+`)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		path string
+		want string
+	}{
+		{"set", map[string]string{"ALT_SSL": "1"}, "ssl", "?sslmode=require"},
+		{"unset", nil, "ssl", ""},
+		{"empty counts as unset", map[string]string{"ALT_DEBUG": ""}, "level", ""},
+		{"nested reference", map[string]string{"ALT_DEBUG": "yes", "ALT_HOST": "debug.local"}, "host", "debug.local"},
+		{"nested default", map[string]string{"ALT_DEBUG": "yes"}, "host", "localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			// Unset values are intentional with :+, so strict mode accepts them
+			p := New(false, WithStrict(true))
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			got, err := p.GetError(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert(t, got, tt.want, tt.path)
+		})
+	}
+}
+
+func TestYamlProfile_Interpolation(t *testing.T) {
+	t.Setenv("INTERP_HOST", "db.local")
+	t.Setenv("INTERP_PASS", `p"w`)
+	t.Setenv("INTERP_SSL", "1")
+	t.Setenv("INTERP_KEY", "MIIBOgIBAAJBAK")
+
+	yamlData := []byte(`
+url: postgres://${INTERP_HOST}:${INTERP_PORT:5432}/app${INTERP_SSL:+?sslmode=require}
+json: |
+  {"host": "${INTERP_HOST}", "password": ${INTERP_PASS|quote}}
+pem: >
+  -----BEGIN KEY-----
+  ${INTERP_KEY}
+  -----END KEY-----
+escaped: literal $${INTERP_HOST} and ${INTERP_HOST}
+unterminated: cost ${INTERP_HOST
+whole: '${INTERP_JSON:{"a": {"b": 1}}}'
+pair: ${INTERP_HOST}-${INTERP_HOST}
+version: ${INTERP_MAJOR:1}.${INTERP_MINOR:10}
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"url", "postgres://db.local:5432/app?sslmode=require"},
+		{"json", "{\"host\": \"db.local\", \"password\": \"p\\\"w\"}\n"},
+		{"pem", "-----BEGIN KEY----- MIIBOgIBAAJBAK -----END KEY-----\n"},
+		{"escaped", "literal ${INTERP_HOST} and db.local"},
+		{"unterminated", "cost ${INTERP_HOST"},
+		{"whole", `{"a": {"b": 1}}`},
+		{"pair", "db.local-db.local"},
+		{"version", "1.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := p.GetError(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var cfg struct {
+		URL     string `yaml:"url"`
+		Version string `yaml:"version"`
+	}
+	if err := p.UnmarshalTo(&cfg); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, cfg.URL, "postgres://db.local:5432/app?sslmode=require", "decoded url")
+	assert(t, cfg.Version, "1.10", "decoded version")
+
+	names := p.ExpandConventionalEnv("url")
+	if len(names) != 3 || names[0] != "INTERP_HOST" || names[1] != "INTERP_PORT" || names[2] != "INTERP_SSL" {
+		t.Errorf("got referenced env %v", names)
+	}
+}
+
+func TestYamlProfile_RecursiveExpansion(t *testing.T) {
+	yamlData := []byte(`
+url: ${REC_URL}
+dsn: jdbc:${REC_URL}
+`)
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		env      map[string]string
+		want     string
+		wantErr  error
+	}{
+		{"disabled", 0, map[string]string{"REC_URL": "postgres://${REC_USER}@db"}, "postgres://${REC_USER}@db", nil},
+		{"expanded", 2, map[string]string{"REC_URL": "postgres://${REC_USER}@db", "REC_USER": "app"}, "postgres://app@db", nil},
+		{"default in value", 2, map[string]string{"REC_URL": "postgres://${REC_USER:admin}@db"}, "postgres://admin@db", nil},
+		{"nested", 2, map[string]string{"REC_URL": "${REC_USER}@db", "REC_USER": "${REC_NAME}", "REC_NAME": "app"}, "app@db", nil},
+		{"escaped", 2, map[string]string{"REC_URL": "$${REC_USER}"}, "${REC_USER}", nil},
+		{"too deep", 1, map[string]string{"REC_URL": "${REC_USER}@db", "REC_USER": "${REC_NAME}", "REC_NAME": "app"}, "", ErrExpansionTooDeep},
+		{"cycle", 5, map[string]string{"REC_URL": "${REC_USER}", "REC_USER": "${REC_URL}"}, "", ErrCircularReference},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			p := NewProfile(WithRecursiveExpansion(tt.maxDepth))
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			got, err := p.GetError("url")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v but got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert(t, got, tt.want, "url")
+			assert(t, p.Get("dsn"), "jdbc:"+tt.want, "dsn")
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package dollarYaml
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolveHostname returns the host name reported by the kernel
+func resolveHostname(context.Context, string) (string, error) {
+	return os.Hostname()
+}
+
+// resolvePID returns the current process ID
+func resolvePID(context.Context, string) (string, error) {
+	return strconv.Itoa(os.Getpid()), nil
+}
+
+// resolveCwd returns the current working directory
+func resolveCwd(context.Context, string) (string, error) {
+	return os.Getwd()
+}
+
+// resolveUser returns the name of the user running the process, falling
+// back to the USER and USERNAME env vars where the user database is not
+// available, as in scratch containers
+func resolveUser(context.Context, string) (string, error) {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username, nil
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if val := os.Getenv(name); val != "" {
+			return val, nil
+		}
+	}
+	return "", ErrValueNotFound
+}
+
+// resolveNow formats the load time of the config with a Go time layout,
+// optionally followed by @ and a time zone name, e.g. 2006-01-02@UTC. Every
+// ${now:...} placeholder uses the time of the last Read, so all of them
+// agree with each other until the config is reloaded.
+func (p *YamlProfile) resolveNow(_ context.Context, key string) (string, error) {
+	layout, zone := key, ""
+	if atIdx := strings.LastIndex(key, "@"); atIdx != -1 {
+		layout, zone = key[:atIdx], key[atIdx+1:]
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	now := p.LoadedAt()
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if zone != "" {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return "", fmt.Errorf("loading time zone: %w", err)
+		}
+		now = now.In(loc)
+	}
+	return now.Format(layout), nil
+}
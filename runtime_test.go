@@ -0,0 +1,69 @@
+package dollarYaml
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_RuntimeVariables(t *testing.T) {
+	yamlData := []byte(`
+instance:
+  host: ${hostname}
+  pid: ${pid}
+  dir: ${cwd}
+  user: ${user}
+  named: ${hostname:ignored}
+`)
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	hostname, _ := os.Hostname()
+	cwd, _ := os.Getwd()
+	assert(t, p.Get("instance.host"), hostname, "instance.host")
+	assert(t, p.Get("instance.pid"), strconv.Itoa(os.Getpid()), "instance.pid")
+	assert(t, p.Get("instance.dir"), cwd, "instance.dir")
+	assert(t, p.Get("instance.named"), hostname, "instance.named")
+	if p.Get("instance.user") == "" {
+		t.Error("expected a user name")
+	}
+
+	var config struct {
+		Instance struct {
+			PID int `yaml:"pid"`
+		} `yaml:"instance"`
+	}
+	if err := p.UnmarshalTo(&config); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, config.Instance.PID, os.Getpid(), "decoded pid")
+}
+
+func TestYamlProfile_NowPlaceholder(t *testing.T) {
+	yamlData := []byte(`
+build:
+  date: ${now:2006-01-02@UTC}
+  stamp: ${now:2006-01-02T15:04:05Z07:00@UTC}
+  partition: ${now:2006/01/02}
+  default: ${now:}
+  badZone: ${now:2006@Mars/Olympus}
+`)
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	loaded := p.LoadedAt()
+
+	assert(t, p.Get("build.date"), loaded.UTC().Format("2006-01-02"), "build.date")
+	assert(t, p.Get("build.stamp"), loaded.UTC().Format(time.RFC3339), "build.stamp")
+	assert(t, p.Get("build.partition"), loaded.Format("2006/01/02"), "build.partition")
+	assert(t, p.Get("build.default"), loaded.Format(time.RFC3339), "build.default")
+	if _, err := p.GetError("build.badZone"); err == nil {
+		t.Error("expected error for unknown time zone")
+	}
+}
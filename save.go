@@ -0,0 +1,108 @@
+package dollarYaml
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SaveOption configures SaveToPath
+type SaveOption func(*saveConfig)
+
+type saveConfig struct {
+	resolved bool
+	mode     os.FileMode
+}
+
+// SaveResolved makes SaveToPath write the effective config, as Marshal
+// does, instead of the tree with its placeholders
+func SaveResolved(enabled bool) SaveOption {
+	return func(c *saveConfig) {
+		c.resolved = enabled
+	}
+}
+
+// SaveMode sets the permissions of the written file. By default an
+// existing file keeps its mode and a new one gets 0644.
+func SaveMode(mode os.FileMode) SaveOption {
+	return func(c *saveConfig) {
+		c.mode = mode
+	}
+}
+
+// SaveToPath writes the config to path, encoded with the codec registered
+// for its extension and falling back to YAML, as ReadFromPath reads it.
+// Placeholders are written as they are unless SaveResolved is given, and
+// changes made with Set, Delete or Merge are included; comments and
+// formatting of the file read are not kept. The file is replaced
+// atomically: the data goes to a temporary file in the same directory,
+// which is then renamed over path, so readers never see a partial write.
+func (p *YamlProfile) SaveToPath(path string, opts ...SaveOption) error {
+	cfg := saveConfig{mode: 0o644}
+	if info, err := os.Stat(path); err == nil {
+		cfg.mode = info.Mode().Perm()
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	tree := p.root()
+	if cfg.resolved {
+		var err error
+		if tree, err = p.resolvedTree(context.Background()); err != nil {
+			return err
+		}
+	}
+	data, err := encodeFor(filepath.Ext(path), tree)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+	return writeFileAtomic(path, data, cfg.mode)
+}
+
+// encodeFor encodes tree with the codec registered for ext, or as YAML
+func encodeFor(ext string, tree interface{}) ([]byte, error) {
+	c, ok := CodecFor(ext)
+	if !ok || ext == "" {
+		return yaml.Marshal(tree)
+	}
+	m, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s needs a map at the root", ErrInvalidRoot, ext)
+	}
+	return c.Marshal(m)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path
+func writeFileAtomic(path string, data []byte, mode os.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", f.Name(), err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", f.Name(), err)
+	}
+	if err = f.Chmod(mode); err != nil {
+		return fmt.Errorf("setting mode of %s: %w", f.Name(), err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", f.Name(), err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
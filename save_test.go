@@ -0,0 +1,99 @@
+package dollarYaml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestYamlProfile_SaveToPath(t *testing.T) {
+	t.Setenv("SAVE_PORT", "9090")
+
+	yamlData := []byte(`
+# comments are not kept
+server:
+  port: ${SAVE_PORT:8080}
+name: app
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if err := p.Set("server.host", "example.com"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		file string
+		opts []SaveOption
+		want string
+		mode os.FileMode
+	}{
+		{"raw", "raw.yaml", nil, "name: app\nserver:\n    host: example.com\n    port: ${SAVE_PORT:8080}\n", 0o644},
+		{"resolved", "resolved.yml", []SaveOption{SaveResolved(true), SaveMode(0o600)}, "name: app\nserver:\n    host: example.com\n    port: 9090\n", 0o600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := p.SaveToPath(path, tt.opts...); err != nil {
+				t.Fatalf("SaveToPath failed: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert(t, string(data), tt.want, "file content")
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert(t, info.Mode().Perm(), tt.mode, "file mode")
+		})
+	}
+
+	t.Run("codec", func(t *testing.T) {
+		RegisterCodec(lineCodec{}, ".props")
+		flat := NewProfile()
+		if err := flat.Read([]byte("name: ${SAVE_NAME:app}\n")); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		path := filepath.Join(dir, "config.props")
+		if err := flat.SaveToPath(path, SaveResolved(true)); err != nil {
+			t.Fatalf("SaveToPath failed: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		assert(t, string(data), "name=app\n", "file content")
+	})
+
+	t.Run("keeps mode and leaves no temporary files", func(t *testing.T) {
+		path := filepath.Join(dir, "existing.yaml")
+		if err := os.WriteFile(path, yamlData, 0o640); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.SaveToPath(path); err != nil {
+			t.Fatalf("SaveToPath failed: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert(t, info.Mode().Perm(), os.FileMode(0o640), "existing mode kept")
+
+		back := NewProfile()
+		if err := back.ReadFromPath(path); err != nil {
+			t.Fatalf("ReadFromPath failed: %v", err)
+		}
+		assert(t, back.Get("server.port"), "9090", "round trip")
+
+		matches, _ := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+		assert(t, len(matches), 0, "temporary files")
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		if err := p.SaveToPath(filepath.Join(dir, "missing", "config.yaml")); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrExecDisabled          = errors.New("exec resolver is disabled")
+	ErrResolverNotConfigured = errors.New("resolver is not configured")
+)
+
+// DefaultExecTimeout bounds ${exec:...} commands when no WithExecTimeout
+// option is given
+const DefaultExecTimeout = 10 * time.Second
+
+// Resolver supplies the values of ${scheme:key} placeholders. Resolve
+// returns an error wrapping ErrValueNotFound when the source has no value
+// for key, which lets a default or strict mode take over.
+type Resolver interface {
+	Resolve(ctx context.Context, scheme, key string) (string, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface
+type ResolverFunc func(ctx context.Context, scheme, key string) (string, error)
+
+// Resolve calls f(ctx, scheme, key)
+func (f ResolverFunc) Resolve(ctx context.Context, scheme, key string) (string, error) {
+	return f(ctx, scheme, key)
+}
+
+// keyResolver adapts the built-in resolvers, which only need the key
+type keyResolver func(ctx context.Context, key string) (string, error)
+
+func (f keyResolver) Resolve(ctx context.Context, _, key string) (string, error) {
+	return f(ctx, key)
+}
+
+// scheme is a Resolver together with how its placeholders are parsed
+type scheme struct {
+	resolver Resolver
+	// rawKey passes everything after "scheme:" as the key, for schemes
+	// whose keys contain colons; such placeholders take no default
+	rawKey bool
+	// bare schemes take no key, as in ${uuid}; any text after "scheme:"
+	// is the default
+	bare bool
+	// splitKey overrides how the text after "scheme:" is split into the
+	// key and default, for keys that contain colons
+	splitKey func(rest string) (key, defaultValue string, hasDefault bool)
+	// generated schemes produce new values on every call, so results are
+	// cached per path until the next Read to keep them stable
+	generated bool
+}
+
+// split separates the text after "scheme:" into the key and default
+func (sch scheme) split(rest string, hasRest bool) (key, defaultValue string, hasDefault bool) {
+	switch {
+	case sch.bare:
+		return "", rest, hasRest
+	case sch.splitKey != nil:
+		return sch.splitKey(rest)
+	case !sch.rawKey:
+		if colonIdx := strings.Index(rest, ":"); colonIdx != -1 {
+			return rest[:colonIdx], rest[colonIdx+1:], true
+		}
+	}
+	return rest, "", false
+}
+
+// builtinSchemes are available to every profile. Each entry builds the
+// scheme for a given profile so resolvers can use its env source.
+var builtinSchemes = map[string]func(p *YamlProfile) scheme{
+	"file": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveFile)}
+	},
+	"exec": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveExecDisabled), rawKey: true}
+	},
+	"base64": func(p *YamlProfile) scheme {
+		return scheme{resolver: keyResolver(p.resolveBase64)}
+	},
+	"base64enc": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveBase64Encode), rawKey: true}
+	},
+	"json": func(p *YamlProfile) scheme {
+		return scheme{resolver: keyResolver(p.resolveJSON)}
+	},
+	"uuid": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveUUID), bare: true, generated: true}
+	},
+	"random": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveRandom), rawKey: true, generated: true}
+	},
+	"hostname": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveHostname), bare: true}
+	},
+	"pid": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolvePID), bare: true}
+	},
+	"cwd": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveCwd), bare: true}
+	},
+	"user": func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(resolveUser), bare: true}
+	},
+	"now": func(p *YamlProfile) scheme {
+		return scheme{resolver: keyResolver(p.resolveNow), rawKey: true}
+	},
+	"vault":         unconfigured("vault", "WithVault"),
+	"aws-sm":        unconfigured("aws-sm", "WithAWS"),
+	"aws-ssm":       unconfigured("aws-ssm", "WithAWS"),
+	"gcp-sm":        unconfigured("gcp-sm", "WithGCP"),
+	"azure-kv":      unconfigured("azure-kv", "WithAzure"),
+	"k8s-secret":    unconfigured("k8s-secret", "WithKubernetes"),
+	"k8s-configmap": unconfigured("k8s-configmap", "WithKubernetes"),
+	"consul":        unconfigured("consul", "WithConsul"),
+	"etcd":          unconfigured("etcd", "WithEtcd"),
+}
+
+// unconfigured reserves the name of an opt-in scheme so its placeholders
+// fail clearly instead of being read as an env var with a default
+func unconfigured(name, option string) func(*YamlProfile) scheme {
+	return func(*YamlProfile) scheme {
+		return scheme{resolver: keyResolver(func(context.Context, string) (string, error) {
+			return "", fmt.Errorf("%w: %s placeholders need the %s option", ErrResolverNotConfigured, name, option)
+		})}
+	}
+}
+
+// scheme returns the resolver registered for name, preferring resolvers
+// enabled on the profile over the built-in ones
+func (p *YamlProfile) scheme(name string) (scheme, bool) {
+	if sch, ok := p.schemes[name]; ok {
+		return sch, true
+	}
+	if build, ok := builtinSchemes[name]; ok {
+		return build(p), true
+	}
+	return scheme{}, false
+}
+
+// RegisterResolver makes r resolve ${name:key} placeholders in this
+// profile and the profiles derived from it, replacing any built-in
+// resolver of the same name. The text after the first colon following the
+// key is the default, as in ${name:key:default}. Register resolvers
+// before resolving values; registration is not synchronized with lookups.
+func (p *YamlProfile) RegisterResolver(name string, r Resolver) {
+	p.setScheme(name, scheme{resolver: r})
+}
+
+// setScheme registers a resolver on the profile
+func (p *YamlProfile) setScheme(name string, sch scheme) {
+	if p.schemes == nil {
+		p.schemes = make(map[string]scheme)
+	}
+	p.schemes[name] = sch
+}
+
+// resolveScheme resolves the part of a placeholder after "scheme:"
+func (p *YamlProfile) resolveScheme(name string, sch scheme, rest string, hasRest bool, st *resolveState) (string, error) {
+	key, defaultValue, hasDefault := sch.split(rest, hasRest)
+	if err := st.ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := st.enter(strings.TrimSuffix(name+":"+key, ":")); err != nil {
+		return "", err
+	}
+	defer st.leave()
+
+	if cache := p.cache(); sch.generated && cache != nil {
+		return cache.get(st.path+"\x00"+name+":"+key, func() (string, error) {
+			return sch.resolver.Resolve(st.ctx, name, key)
+		})
+	}
+
+	value, err := sch.resolver.Resolve(st.ctx, name, key)
+	switch {
+	case err == nil:
+		return value, nil
+	case !errors.Is(err, ErrValueNotFound):
+		return "", fmt.Errorf("resolving %s:%s: %w", name, key, err)
+	case hasDefault:
+		return p.expandDefault(defaultValue, st)
+	case p.strict:
+		return "", fmt.Errorf("%w: %s:%s: %v", ErrUnresolved, name, key, err)
+	}
+	return "", nil
+}
+
+// resolveFile reads a file such as a mounted Docker or Kubernetes secret,
+// trimming the trailing newline most tools write
+func resolveFile(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %v", ErrValueNotFound, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveExecDisabled rejects ${exec:...} placeholders unless the profile
+// was created with WithExecResolver(true)
+func resolveExecDisabled(_ context.Context, command string) (string, error) {
+	return "", fmt.Errorf("%w: %s", ErrExecDisabled, command)
+}
+
+// resolveExec runs command and returns its stdout without the trailing
+// newline. The command is split on whitespace and run directly, not
+// through a shell.
+func (p *YamlProfile) resolveExec(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+
+	timeout := p.execTimeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("running %s: %w", args[0], ctx.Err())
+		}
+		return "", fmt.Errorf("running %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	p.debugf("Resolved exec placeholder with %s\n", args[0])
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// resolveBase64 decodes the base64 value of the env var name, as used for
+// binary or multi-line secrets injected through the environment
+func (p *YamlProfile) resolveBase64(_ context.Context, name string) (string, error) {
+	if err := p.checkEnv(name); err != nil {
+		return "", err
+	}
+	encoded, _ := p.lookupEnv(name)
+	if encoded == "" {
+		return "", fmt.Errorf("%w: %s", ErrValueNotFound, name)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return string(decoded), nil
+}
+
+// resolveBase64Encode returns the base64 encoding of a literal
+func resolveBase64Encode(_ context.Context, literal string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(literal)), nil
+}
+
+// resolveJSON parses the env var named by the first segment of key as JSON
+// and extracts the value at the remaining dot-path, e.g.
+// VCAP_SERVICES.p-mysql.0.credentials.uri. Strings are returned as-is and
+// other values in their JSON encoding.
+func (p *YamlProfile) resolveJSON(_ context.Context, key string) (string, error) {
+	name, path := key, ""
+	if dotIdx := strings.Index(key, "."); dotIdx != -1 {
+		name, path = key[:dotIdx], key[dotIdx+1:]
+	}
+	if err := p.checkEnv(name); err != nil {
+		return "", err
+	}
+	blob, _ := p.lookupEnv(name)
+	if blob == "" {
+		return "", fmt.Errorf("%w: %s", ErrValueNotFound, name)
+	}
+
+	var current interface{}
+	if err := json.Unmarshal([]byte(blob), &current); err != nil {
+		return "", fmt.Errorf("parsing %s as JSON: %w", name, err)
+	}
+	if path != "" {
+		for _, segment := range splitPath(path) {
+			switch node := current.(type) {
+			case map[string]interface{}:
+				value, ok := node[segment]
+				if !ok {
+					return "", fmt.Errorf("%w: %s in %s", ErrValueNotFound, path, name)
+				}
+				current = value
+			case []interface{}:
+				idx, err := strconv.Atoi(segment)
+				if err != nil || idx < 0 || idx >= len(node) {
+					return "", fmt.Errorf("%w: %s in %s", ErrValueNotFound, path, name)
+				}
+				current = node[idx]
+			default:
+				return "", fmt.Errorf("%w: %s in %s", ErrLevelMismatch, path, name)
+			}
+		}
+	}
+
+	switch value := current.(type) {
+	case string:
+		return value, nil
+	case nil:
+		return "", fmt.Errorf("%w: %s in %s is null", ErrValueNotFound, path, name)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+}
@@ -0,0 +1,274 @@
+package dollarYaml
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_FileScheme(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	yamlData := []byte(`
+db:
+  password: ${file:` + secret + `}
+  fallback: ${file:` + missing + `:changeme}
+  missing: ${file:` + missing + `}
+`)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		path    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "reads file and trims newline",
+			path: "db.password",
+			want: "s3cret",
+		},
+		{
+			name: "missing file uses default",
+			path: "db.fallback",
+			want: "changeme",
+		},
+		{
+			name: "missing file without default is empty",
+			path: "db.missing",
+			want: "",
+		},
+		{
+			name:    "missing file without default fails in strict mode",
+			opts:    []Option{WithStrict(true)},
+			path:    "db.missing",
+			wantErr: ErrUnresolved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(false, tt.opts...)
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			got, err := p.GetError(tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v but got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYamlProfile_ExecScheme(t *testing.T) {
+	yamlData := []byte(`
+db:
+  password: ${exec:echo from-command}
+  slow: ${exec:sleep 5}
+  failing: ${exec:false}
+`)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		path    string
+		want    string
+		wantErr error
+		failure bool
+	}{
+		{
+			name:    "disabled by default",
+			path:    "db.password",
+			wantErr: ErrExecDisabled,
+		},
+		{
+			name: "runs command when enabled",
+			opts: []Option{WithExecResolver(true)},
+			path: "db.password",
+			want: "from-command",
+		},
+		{
+			name:    "command exceeding timeout fails",
+			opts:    []Option{WithExecResolver(true), WithExecTimeout(50 * time.Millisecond)},
+			path:    "db.slow",
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:    "failing command returns error",
+			opts:    []Option{WithExecResolver(true)},
+			path:    "db.failing",
+			failure: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(false, tt.opts...)
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			got, err := p.GetError(tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v but got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if tt.failure {
+				if err == nil {
+					t.Errorf("expected error but got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYamlProfile_Base64Schemes(t *testing.T) {
+	pem := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"
+	os.Setenv("B64_TLS_CERT", base64.StdEncoding.EncodeToString([]byte(pem)))
+	os.Setenv("B64_INVALID", "not base64!")
+	defer os.Unsetenv("B64_TLS_CERT")
+	defer os.Unsetenv("B64_INVALID")
+
+	yamlData := []byte(`
+tls:
+  cert: ${base64:B64_TLS_CERT}
+  key: ${base64:B64_MISSING:none}
+  invalid: ${base64:B64_INVALID}
+  auth: ${base64enc:user:pass}
+`)
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	assert(t, p.Get("tls.cert"), pem, "tls.cert")
+	assert(t, p.Get("tls.key"), "none", "tls.key")
+	assert(t, p.Get("tls.auth"), "dXNlcjpwYXNz", "tls.auth")
+	if _, err := p.GetError("tls.invalid"); err == nil {
+		t.Error("expected error decoding invalid base64")
+	}
+
+	snap := p.SnapshotEnv()
+	if _, ok := snap.Vars["B64_TLS_CERT"]; !ok {
+		t.Errorf("snapshot %v is missing B64_TLS_CERT", snap.Vars)
+	}
+}
+
+func TestYamlProfile_JSONScheme(t *testing.T) {
+	os.Setenv("JSON_CREDS", `{"username":"svc","port":5432,"tags":["a","b"]}`)
+	os.Setenv("JSON_VCAP", `{"p-mysql":[{"credentials":{"uri":"mysql://db"}}]}`)
+	os.Setenv("JSON_BROKEN", `{"username":`)
+	defer os.Unsetenv("JSON_CREDS")
+	defer os.Unsetenv("JSON_VCAP")
+	defer os.Unsetenv("JSON_BROKEN")
+
+	yamlData := []byte(`
+creds:
+  username: ${json:JSON_CREDS.username}
+  port: ${json:JSON_CREDS.port}
+  tags: ${json:JSON_CREDS.tags}
+  password: ${json:JSON_CREDS.password:guest}
+  uri: ${json:JSON_VCAP.p-mysql.0.credentials.uri}
+  unset: ${json:JSON_UNSET.username:nobody}
+  broken: ${json:JSON_BROKEN.username}
+`)
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	assert(t, p.Get("creds.username"), "svc", "creds.username")
+	assert(t, p.Get("creds.port"), "5432", "creds.port")
+	assert(t, p.Get("creds.tags"), `["a","b"]`, "creds.tags")
+	assert(t, p.Get("creds.password"), "guest", "creds.password")
+	assert(t, p.Get("creds.uri"), "mysql://db", "creds.uri")
+	assert(t, p.Get("creds.unset"), "nobody", "creds.unset")
+	if _, err := p.GetError("creds.broken"); err == nil {
+		t.Error("expected error parsing invalid JSON")
+	}
+}
+
+// mapResolver serves placeholders from a fixed map keyed by scheme:key
+type mapResolver map[string]string
+
+func (m mapResolver) Resolve(_ context.Context, scheme, key string) (string, error) {
+	if v, ok := m[scheme+":"+key]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrValueNotFound, key)
+}
+
+func TestYamlProfile_RegisterResolver(t *testing.T) {
+	values := mapResolver{"cfg:db/host": "db.internal", "file:/run/secret": "from-resolver"}
+	p := New(false, WithResolver("cfg", values))
+	p.RegisterResolver("file", values)
+	p.RegisterResolver("upper", ResolverFunc(func(_ context.Context, scheme, key string) (string, error) {
+		return strings.ToUpper(key), nil
+	}))
+
+	yamlData := []byte(`
+host: ${cfg:db/host}
+missing: ${cfg:db/user:admin}
+secret: ${file:/run/secret}
+shout: ${upper:hello}
+locked:
+  $resolvers: [env]
+  host: ${cfg:db/host}
+`)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"host", "db.internal"},
+		{"missing", "admin"},
+		{"secret", "from-resolver"},
+		{"shout", "HELLO"},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+	if _, err := p.GetError("locked.host"); !errors.Is(err, ErrResolverNotAllowed) {
+		t.Errorf("expected ErrResolverNotAllowed, got %v", err)
+	}
+	if names := p.SnapshotEnv().Vars; len(names) != 0 {
+		t.Errorf("custom schemes should not count as env vars, got %v", names)
+	}
+}
@@ -0,0 +1,137 @@
+package dollarYaml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrResolverNotAllowed = errors.New("resolver not allowed here")
+
+const (
+	// envPrefixKey declares an env var prefix for the placeholders in the
+	// map holding it and all maps below, e.g. "$envPrefix: WORKER_" makes
+	// ${PORT:8080} read WORKER_PORT. Nested declarations are appended to
+	// the prefix of the enclosing map.
+	envPrefixKey = "$envPrefix"
+
+	// resolversKey restricts the resolvers placeholders in a subtree may
+	// use, e.g. "$resolvers: [file]". Plain ${NAME} placeholders are
+	// named env. Nested declarations can only narrow the enclosing list.
+	resolversKey = "$resolvers"
+)
+
+// isDirective reports whether a map key is a directive to the loader
+// rather than configuration data
+func isDirective(key string) bool {
+	return key == envPrefixKey || key == resolversKey
+}
+
+// scope holds the resolution settings in effect at a path
+type scope struct {
+	envPrefix string
+	// resolvers lists the allowed resolvers, nil meaning all of them
+	resolvers []string
+}
+
+// allows reports whether the named resolver may be used in the scope
+func (s scope) allows(name string) bool {
+	if s.resolvers == nil {
+		return true
+	}
+	for _, r := range s.resolvers {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// narrow restricts the scope to the resolvers also listed in allowed
+func (s *scope) narrow(allowed []string) {
+	if s.resolvers == nil {
+		s.resolvers = append([]string{}, allowed...)
+		return
+	}
+	kept := s.resolvers[:0]
+	for _, r := range s.resolvers {
+		for _, a := range allowed {
+			if r == a {
+				kept = append(kept, r)
+				break
+			}
+		}
+	}
+	s.resolvers = kept
+}
+
+// checkResolver fails if the scope does not allow the named resolver
+func (s scope) checkResolver(name, path string) error {
+	if !s.allows(name) {
+		return fmt.Errorf("%w: %s at %s (allowed: %s)", ErrResolverNotAllowed, name, path, strings.Join(s.resolvers, ", "))
+	}
+	return nil
+}
+
+// scopeFor returns the resolution scope in effect at the absolute path,
+// combining directives along the path with WithPathResolvers options
+func (p *YamlProfile) scopeFor(path string) scope {
+	sc := scope{envPrefix: p.envPrefix}
+	if p.baseResolvers != nil {
+		sc.narrow(p.baseResolvers)
+	}
+	for prefix, allowed := range p.pathResolvers {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			sc.narrow(allowed)
+		}
+	}
+
+	var current interface{} = p.root()
+	for _, key := range relativeSegments(p.base, path) {
+		m, ok := current.(map[string]interface{})
+		if ok {
+			applyDirectives(m, &sc)
+			current = m[key]
+			continue
+		}
+		list, ok := current.([]interface{})
+		if !ok {
+			break
+		}
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(list) {
+			break
+		}
+		current = list[idx]
+	}
+	return sc
+}
+
+// applyDirectives updates sc with the directives declared in m
+func applyDirectives(m map[string]interface{}, sc *scope) {
+	if declared, ok := m[envPrefixKey].(string); ok {
+		sc.envPrefix += declared
+	}
+	switch declared := m[resolversKey].(type) {
+	case string:
+		sc.narrow([]string{declared})
+	case []interface{}:
+		allowed := make([]string, 0, len(declared))
+		for _, r := range declared {
+			allowed = append(allowed, fmt.Sprint(r))
+		}
+		sc.narrow(allowed)
+	}
+}
+
+// relativeSegments splits the part of path below base into its keys
+func relativeSegments(base, path string) []string {
+	if base != "" {
+		path = strings.TrimPrefix(strings.TrimPrefix(path, base), ".")
+	}
+	if path == "" {
+		return nil
+	}
+	return splitPath(path)
+}
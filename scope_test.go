@@ -0,0 +1,158 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestYamlProfile_EnvPrefixScope(t *testing.T) {
+	yamlData := []byte(`
+port: ${PORT:80}
+workers:
+  fast:
+    $envPrefix: FAST_
+    port: ${PORT:8080}
+    http:
+      $envPrefix: HTTP_
+      timeout: ${TIMEOUT:5}
+  slow:
+    $envPrefix: SLOW_
+    port: ${PORT:9090}
+pools:
+  - $envPrefix: POOL0_
+    size: ${SIZE:1}
+`)
+
+	env := map[string]string{
+		"PORT":              "1000",
+		"FAST_PORT":         "2000",
+		"FAST_HTTP_TIMEOUT": "30",
+		"POOL0_SIZE":        "4",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	assert(t, p.Get("port"), "1000", "port")
+	assert(t, p.Get("workers.fast.port"), "2000", "workers.fast.port")
+	assert(t, p.Get("workers.fast.http.timeout"), "30", "workers.fast.http.timeout")
+	assert(t, p.Get("workers.slow.port"), "9090", "workers.slow.port")
+
+	pools, err := p.SubSlice("pools")
+	if err != nil {
+		t.Fatalf("SubSlice failed: %v", err)
+	}
+	assert(t, pools[0].Get("size"), "4", "pools[0].size")
+
+	var config struct {
+		Workers map[string]map[string]interface{} `yaml:"workers"`
+	}
+	if err := p.UnmarshalTo(&config); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, config.Workers["fast"]["port"], 2000, "decoded fast port")
+	if _, ok := config.Workers["fast"][envPrefixKey]; ok {
+		t.Error("expected $envPrefix directive to be dropped from decoded config")
+	}
+
+	snap := p.SnapshotEnv()
+	for _, name := range []string{"PORT", "FAST_PORT", "FAST_HTTP_TIMEOUT", "POOL0_SIZE"} {
+		if _, ok := snap.Vars[name]; !ok {
+			t.Errorf("snapshot is missing %s", name)
+		}
+	}
+}
+
+func TestYamlProfile_PathResolvers(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "token")
+	if err := os.WriteFile(secret, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	os.Setenv("SCOPE_TOKEN", "env-token")
+	defer os.Unsetenv("SCOPE_TOKEN")
+
+	yamlData := []byte(`
+app:
+  token: ${SCOPE_TOKEN}
+  fromFile: ${file:` + secret + `}
+secrets:
+  $resolvers: [file]
+  token: ${file:` + secret + `}
+  leaked: ${SCOPE_TOKEN}
+  nested:
+    $resolvers: [file, env]
+    leaked: ${SCOPE_TOKEN}
+`)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		path    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "unrestricted subtree uses env",
+			path: "app.token",
+			want: "env-token",
+		},
+		{
+			name: "restricted subtree uses allowed resolver",
+			path: "secrets.token",
+			want: "file-token",
+		},
+		{
+			name:    "restricted subtree rejects env",
+			path:    "secrets.leaked",
+			wantErr: ErrResolverNotAllowed,
+		},
+		{
+			name:    "nested declaration cannot widen",
+			path:    "secrets.nested.leaked",
+			wantErr: ErrResolverNotAllowed,
+		},
+		{
+			name:    "option restricts subtree",
+			opts:    []Option{WithPathResolvers("app", "env")},
+			path:    "app.fromFile",
+			wantErr: ErrResolverNotAllowed,
+		},
+		{
+			name: "option leaves allowed resolver working",
+			opts: []Option{WithPathResolvers("app", "env")},
+			path: "app.token",
+			want: "env-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(false, tt.opts...)
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+			got, err := p.GetError(tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v but got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package dollarYaml
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotSealed  = errors.New("profile is not sealed")
+	ErrSealBroken = errors.New("config changed since it was sealed")
+)
+
+// SealError lists the leaves that differ from the sealed tree. It wraps
+// ErrSealBroken.
+type SealError struct {
+	Changed []string
+	Added   []string
+	Removed []string
+}
+
+func (e *SealError) Error() string {
+	var parts []string
+	if len(e.Changed) > 0 {
+		parts = append(parts, "changed: "+strings.Join(e.Changed, ", "))
+	}
+	if len(e.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(e.Added, ", "))
+	}
+	if len(e.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(e.Removed, ", "))
+	}
+	return fmt.Sprintf("%v (%s)", ErrSealBroken, strings.Join(parts, "; "))
+}
+
+func (e *SealError) Unwrap() error {
+	return ErrSealBroken
+}
+
+// Seal records a checksum of every leaf in the loaded tree so VerifySeal
+// can later detect code that wrote into the profile's maps or lists.
+// Placeholders are checksummed as written, so a changed env var is not a
+// broken seal. Read discards the seal.
+func (p *YamlProfile) Seal() {
+	seal := make(map[string][sha256.Size]byte)
+	checksumLeaves(p.base, p.root(), seal)
+	p.mu.Lock()
+	p.seal = seal
+	p.mu.Unlock()
+	p.debugf("Sealed %d values\n", len(seal))
+}
+
+// VerifySeal compares the tree against the checksums taken by Seal and
+// returns a *SealError naming each leaf that was changed, added or
+// removed since. Profiles derived from a sealed profile verify their own
+// subtree.
+func (p *YamlProfile) VerifySeal() error {
+	p.mu.RLock()
+	seal, data := p.seal, p.data
+	p.mu.RUnlock()
+	if seal == nil {
+		return ErrNotSealed
+	}
+	current := make(map[string][sha256.Size]byte)
+	checksumLeaves(p.base, data, current)
+
+	serr := &SealError{}
+	for path, sum := range current {
+		sealed, ok := seal[path]
+		switch {
+		case !ok:
+			serr.Added = append(serr.Added, path)
+		case sealed != sum:
+			serr.Changed = append(serr.Changed, path)
+		}
+	}
+	for path := range seal {
+		if !withinBase(p.base, path) {
+			continue
+		}
+		if _, ok := current[path]; !ok {
+			serr.Removed = append(serr.Removed, path)
+		}
+	}
+	if len(serr.Changed)+len(serr.Added)+len(serr.Removed) == 0 {
+		return nil
+	}
+	sort.Strings(serr.Changed)
+	sort.Strings(serr.Added)
+	sort.Strings(serr.Removed)
+	return serr
+}
+
+// withinBase reports whether the absolute path lies in the subtree at base
+func withinBase(base, path string) bool {
+	return base == "" || path == base || strings.HasPrefix(path, base+".")
+}
+
+// checksumLeaves records the checksum of each leaf under v by absolute
+// path. The type is part of the checksum so 1 and "1" differ; empty maps
+// and lists count as leaves so adding to them is noticed.
+func checksumLeaves(path string, v interface{}, sums map[string][sha256.Size]byte) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) > 0 {
+			for k, item := range val {
+				checksumLeaves(joinKey(path, k), item, sums)
+			}
+			return
+		}
+	case []interface{}:
+		if len(val) > 0 {
+			for i, item := range val {
+				checksumLeaves(joinPath(path, strconv.Itoa(i)), item, sums)
+			}
+			return
+		}
+	}
+	sums[path] = sha256.Sum256([]byte(fmt.Sprintf("%T\x00%v", v, v)))
+}
@@ -0,0 +1,80 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_Seal(t *testing.T) {
+	yamlData := []byte(`
+server:
+  host: ${SEAL_HOST:localhost}
+  port: 8080
+  tags: []
+workers:
+  - name: a
+  - name: b
+`)
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if err := p.VerifySeal(); !errors.Is(err, ErrNotSealed) {
+		t.Fatalf("expected ErrNotSealed but got %v", err)
+	}
+
+	p.Seal()
+	if err := p.VerifySeal(); err != nil {
+		t.Fatalf("unexpected error on untouched tree: %v", err)
+	}
+
+	// Env changes are not tampering
+	os.Setenv("SEAL_HOST", "example.com")
+	defer os.Unsetenv("SEAL_HOST")
+	if err := p.VerifySeal(); err != nil {
+		t.Errorf("unexpected error after env change: %v", err)
+	}
+
+	// A consumer writing through a derived profile's shared map
+	workers, err := p.SubSlice("workers")
+	if err != nil {
+		t.Fatalf("SubSlice failed: %v", err)
+	}
+	workers[1].data.(map[string]interface{})["name"] = "evil"
+	server := p.data.(map[string]interface{})["server"].(map[string]interface{})
+	server["port"] = "8080"
+	server["tags"] = []interface{}{"x"}
+	delete(server, "host")
+
+	err = p.VerifySeal()
+	var serr *SealError
+	if !errors.As(err, &serr) || !errors.Is(err, ErrSealBroken) {
+		t.Fatalf("expected *SealError but got %v", err)
+	}
+	want := &SealError{
+		Changed: []string{"server.port", "workers.1.name"},
+		Added:   []string{"server.tags.0"},
+		Removed: []string{"server.host", "server.tags"},
+	}
+	if !reflect.DeepEqual(serr, want) {
+		t.Errorf("got %+v, want %+v", serr, want)
+	}
+
+	// Derived profiles only check their own subtree
+	if err := workers[0].VerifySeal(); err != nil {
+		t.Errorf("unexpected error for untouched subtree: %v", err)
+	}
+	if err := workers[1].VerifySeal(); !errors.Is(err, ErrSealBroken) {
+		t.Errorf("expected ErrSealBroken for workers.1 but got %v", err)
+	}
+
+	// Read replaces the tree and discards the seal
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if err := p.VerifySeal(); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("expected ErrNotSealed after Read but got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package dollarYaml
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// AllKeys returns the path of every leaf in the tree, in key order with
+// list items in index order, each usable with Get. Placeholder keys appear
+// resolved, and directives such as $envPrefix are left out. Empty maps and
+// lists count as leaves.
+func (p *YamlProfile) AllKeys() []string {
+	var keys []string
+	p.walkKeys(context.Background(), p.base, "", p.root(), func(path string) {
+		keys = append(keys, path)
+	})
+	return keys
+}
+
+// walkKeys calls fn with the path of each leaf below v, the node at the
+// absolute raw path at and the lookup path path
+func (p *YamlProfile) walkKeys(ctx context.Context, at, path string, v interface{}, fn func(path string)) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 && path != "" {
+			fn(path)
+			return
+		}
+		names := p.settingKeys(ctx, at, val)
+		for _, k := range sortedKeys(names) {
+			p.walkKeys(ctx, joinKey(at, k), joinKey(path, names[k]), val[k], fn)
+		}
+	case []interface{}:
+		if len(val) == 0 && path != "" {
+			fn(path)
+			return
+		}
+		for i, item := range val {
+			idx := strconv.Itoa(i)
+			p.walkKeys(ctx, joinPath(at, idx), joinPath(path, idx), item, fn)
+		}
+	default:
+		fn(path)
+	}
+}
+
+// AllSettings returns the whole tree with its placeholders resolved and
+// typed as UnmarshalTo into a map[string]interface{} would, for
+// diagnostics, diffing and exporting. Unlike UnmarshalTo it does not stop
+// at the first failure: values and keys that fail to resolve keep their
+// placeholder text, and Unresolved lists them. A document whose root is
+// a list gives nil.
+func (p *YamlProfile) AllSettings() map[string]interface{} {
+	m, ok := p.root().(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return p.settingsOf(context.Background(), p.base, m).(map[string]interface{})
+}
+
+var untypedType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// settingsOf resolves v, the node at path, for AllSettings
+func (p *YamlProfile) settingsOf(ctx context.Context, path string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		names := p.settingKeys(ctx, path, val)
+		out := make(map[string]interface{}, len(names))
+		for k, name := range names {
+			out[name] = p.settingsOf(ctx, joinKey(path, k), val[k])
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = p.settingsOf(ctx, joinPath(path, strconv.Itoa(i)), item)
+		}
+		return out
+	}
+	processed, err := p.processValue(ctx, path, v)
+	if err != nil {
+		p.debugf("Keeping %s unresolved: %v\n", path, err)
+		return v
+	}
+	return p.coerceFor(processed, untypedType)
+}
+
+// settingKeys maps the keys of m, the map at path, to their resolved
+// names, keeping the placeholder text of keys that fail to resolve or
+// collide
+func (p *YamlProfile) settingKeys(ctx context.Context, path string, m map[string]interface{}) map[string]string {
+	names, err := p.resolveKeys(ctx, path, m)
+	if err == nil {
+		return names
+	}
+	p.debugf("Keeping keys of %s unresolved: %v\n", path, err)
+	names = make(map[string]string, len(m))
+	for k := range m {
+		if !isDirective(k) {
+			names[k] = k
+		}
+	}
+	return names
+}
+
+// sortedKeys returns the raw keys of names ordered by resolved name
+func sortedKeys(names map[string]string) []string {
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return names[keys[i]] < names[keys[j]]
+	})
+	return keys
+}
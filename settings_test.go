@@ -0,0 +1,95 @@
+package dollarYaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_AllKeys(t *testing.T) {
+	t.Setenv("KEYS_REGION", "eu")
+
+	yamlData := []byte(`
+server:
+  port: 8080
+  host: ${KEYS_HOST:localhost}
+  $envPrefix: APP_
+regions:
+  ${KEYS_REGION:us}: {endpoint: e}
+tags: [a, b]
+empty: {}
+none: []
+name: ~
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	want := []string{
+		"empty",
+		"name",
+		"none",
+		"regions.eu.endpoint",
+		"server.host",
+		"server.port",
+		"tags.0",
+		"tags.1",
+	}
+	got := p.AllKeys()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for _, key := range got {
+		if !p.Has(key) {
+			t.Errorf("AllKeys returned %s, which Has does not find", key)
+		}
+	}
+
+	root := NewProfile()
+	if err := root.Read([]byte("[{a: 1}, b]")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	if got := root.AllKeys(); !reflect.DeepEqual(got, []string{"0.a", "1"}) {
+		t.Errorf("root list keys = %v", got)
+	}
+}
+
+func TestYamlProfile_AllSettings(t *testing.T) {
+	t.Setenv("KEYS_PORT", "9090")
+	t.Setenv("KEYS_REGION", "eu")
+
+	yamlData := []byte(`
+server:
+  port: ${KEYS_PORT:8080}
+  host: ${KEYS_HOST:localhost}
+  secret: ${KEYS_SECRET}
+  $envPrefix: ""
+regions:
+  ${KEYS_REGION:us}: {zip: "${KEYS_ZIP:08540|string}"}
+tags: [a, "${KEYS_TAG:b}"]
+`)
+	p := NewProfile(WithStrict(true))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"server": map[string]interface{}{
+			"port":   9090,
+			"host":   "localhost",
+			"secret": "${KEYS_SECRET}",
+		},
+		"regions": map[string]interface{}{
+			"eu": map[string]interface{}{"zip": "08540"},
+		},
+		"tags": []interface{}{"a", "b"},
+	}
+	got := p.AllSettings()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// The result is a copy
+	got["server"].(map[string]interface{})["port"] = 1
+	assert(t, p.Get("server.port"), "9090", "server.port")
+}
@@ -0,0 +1,92 @@
+// Package spec holds the executable behavioural specification of
+// dollarYaml: how placeholders resolve, how sources merge and how resolved
+// values are typed. Each file in cases/ is a list of table-driven cases
+// that the package tests run against the library, comparing the decoded
+// result of every case with the golden file next to it. The cases double
+// as precise documentation for tooling built on top of dollarYaml.
+//
+// Regenerate the golden files after an intended behaviour change with
+//
+//	go test ./spec -update
+package spec
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed cases/*.yaml
+var files embed.FS
+
+// Case is one executable statement about the library's behaviour
+type Case struct {
+	// Name identifies the case within its file
+	Name string `yaml:"name"`
+	// Doc states the behaviour in prose
+	Doc string `yaml:"doc"`
+	// Env holds the environment variables set while the case runs
+	Env map[string]string `yaml:"env"`
+	// Config is the document to load. Sources, when set, are merged in
+	// order instead, later ones overriding earlier ones.
+	Config  string   `yaml:"config"`
+	Sources []string `yaml:"sources"`
+	// Options configures the profile
+	Options Options `yaml:"options"`
+	// Get maps paths to the string Get must return for them
+	Get map[string]string `yaml:"get"`
+	// Errors maps paths to the name of the sentinel error GetError must
+	// wrap for them, such as ErrUnresolved
+	Errors map[string]string `yaml:"errors"`
+	// DecodeError names the sentinel error UnmarshalTo must wrap. When it
+	// is empty the decoded tree is compared with the golden file.
+	DecodeError string `yaml:"decodeError"`
+}
+
+// Options mirrors the dollarYaml options the cases exercise
+type Options struct {
+	Strict       bool  `yaml:"strict"`
+	PercentVars  bool  `yaml:"percentVars"`
+	TypeCoercion *bool `yaml:"typeCoercion"`
+	// ListMerge is replace, append or index, naming a ListMergeStrategy
+	ListMerge string `yaml:"listMerge"`
+}
+
+// Files returns the names of the case files in sorted order, without
+// their .yaml extension
+func Files() []string {
+	entries, _ := files.ReadDir("cases")
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".golden.yaml") {
+			names = append(names, strings.TrimSuffix(name, ".yaml"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Cases returns the cases in the named file
+func Cases(file string) ([]Case, error) {
+	data, err := files.ReadFile(path.Join("cases", file+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := yaml.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range cases {
+		if c.Name == "" || seen[c.Name] {
+			return nil, fmt.Errorf("%s: case names must be unique and non-empty, got %q", file, c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return cases, nil
+}
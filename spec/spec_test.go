@@ -0,0 +1,158 @@
+package spec_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kmlixh/dollarYaml"
+	"github.com/kmlixh/dollarYaml/spec"
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+var sentinels = map[string]error{
+	"ErrValueNotFound":     dollarYaml.ErrValueNotFound,
+	"ErrLevelMismatch":     dollarYaml.ErrLevelMismatch,
+	"ErrUnresolved":        dollarYaml.ErrUnresolved,
+	"ErrCircularReference": dollarYaml.ErrCircularReference,
+	"ErrDuplicateKey":      dollarYaml.ErrDuplicateKey,
+	"ErrFilter":            dollarYaml.ErrFilter,
+	"ErrEnvNotAllowed":     dollarYaml.ErrEnvNotAllowed,
+}
+
+var listMerge = map[string]dollarYaml.ListMergeStrategy{
+	"replace": dollarYaml.ListReplace,
+	"append":  dollarYaml.ListAppend,
+	"index":   dollarYaml.ListMergeByIndex,
+}
+
+// golden is one case's entry in a golden file
+type golden struct {
+	Name   string      `yaml:"name"`
+	Result interface{} `yaml:"result"`
+}
+
+func TestSpec(t *testing.T) {
+	files := spec.Files()
+	if len(files) == 0 {
+		t.Fatal("no case files embedded")
+	}
+	for _, file := range files {
+		file := file
+		t.Run(file, func(t *testing.T) {
+			cases, err := spec.Cases(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var results []golden
+			for _, c := range cases {
+				var result interface{}
+				t.Run(c.Name, func(t *testing.T) {
+					result = run(t, c)
+				})
+				if c.DecodeError == "" {
+					results = append(results, golden{Name: c.Name, Result: result})
+				}
+			}
+			compareGolden(t, file, results)
+		})
+	}
+}
+
+// run checks the expectations of c and returns its decoded tree
+func run(t *testing.T, c spec.Case) interface{} {
+	for k, v := range c.Env {
+		t.Setenv(k, v)
+	}
+	p, err := load(c)
+	if err != nil {
+		t.Fatalf("loading: %v", err)
+	}
+	for path, want := range c.Get {
+		got, err := p.GetError(path)
+		if err != nil {
+			t.Errorf("Get(%q) failed: %v", path, err)
+		} else if got != want {
+			t.Errorf("Get(%q) = %q, want %q", path, got, want)
+		}
+	}
+	for path, name := range c.Errors {
+		_, err := p.GetError(path)
+		if !errors.Is(err, sentinel(t, name)) {
+			t.Errorf("Get(%q) error = %v, want %s", path, err, name)
+		}
+	}
+
+	var tree map[string]interface{}
+	err = p.UnmarshalTo(&tree)
+	if c.DecodeError != "" {
+		if !errors.Is(err, sentinel(t, c.DecodeError)) {
+			t.Errorf("UnmarshalTo error = %v, want %s", err, c.DecodeError)
+		}
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	return tree
+}
+
+func load(c spec.Case) (*dollarYaml.YamlProfile, error) {
+	opts := []dollarYaml.Option{
+		dollarYaml.WithStrict(c.Options.Strict),
+		dollarYaml.WithPercentVars(c.Options.PercentVars),
+	}
+	if c.Options.TypeCoercion != nil {
+		opts = append(opts, dollarYaml.WithTypeCoercion(*c.Options.TypeCoercion))
+	}
+	if strategy, ok := listMerge[c.Options.ListMerge]; ok {
+		opts = append(opts, dollarYaml.WithListMergeStrategy(strategy))
+	} else if c.Options.ListMerge != "" {
+		return nil, fmt.Errorf("unknown list merge strategy %q", c.Options.ListMerge)
+	}
+	if len(c.Sources) == 0 {
+		p := dollarYaml.NewProfile(opts...)
+		return p, p.Read([]byte(c.Config))
+	}
+	b := dollarYaml.NewBuilder(opts...)
+	for i, src := range c.Sources {
+		b.Add(dollarYaml.BytesSource(fmt.Sprintf("source%d", i), []byte(src), "yaml"))
+	}
+	return b.Build(context.Background())
+}
+
+func sentinel(t *testing.T, name string) error {
+	err, ok := sentinels[name]
+	if !ok {
+		t.Fatalf("unknown sentinel error %s", name)
+	}
+	return err
+}
+
+func compareGolden(t *testing.T, file string, results []golden) {
+	got, err := yaml.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("cases", file+".golden.yaml")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./spec -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match, got:\n%s", path, got)
+	}
+}
@@ -0,0 +1,77 @@
+package dollarYaml
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Sub returns a profile over the map at path, such as the database section,
+// for handing a component just its part of the config. It shares p's
+// options and resolvers, so placeholders resolve as they would through p,
+// including $envPrefix directives above path. Sub returns nil when path is
+// missing or does not hold a map.
+func (p *YamlProfile) Sub(path string) *YamlProfile {
+	value, rawPath, err := p.lookup(path)
+	if err != nil {
+		p.debugf("Sub %s: %v\n", path, err)
+		return nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		p.debugf("Sub %s: not a map\n", path)
+		return nil
+	}
+	return p.derive(joinPath(p.base, rawPath), m)
+}
+
+// SubReader is Sub for code holding a ConfigReader, returning a nil
+// ConfigReader when Sub returns nil
+func (p *YamlProfile) SubReader(path string) ConfigReader {
+	if sub := p.Sub(path); sub != nil {
+		return sub
+	}
+	return nil
+}
+
+// SubSlice returns a profile for each element of the list at path. Every
+// element must be a mapping; the returned profiles share p's options so
+// placeholders inside them resolve the same way they would through p.
+// An empty path selects the root of a document that is a list.
+func (p *YamlProfile) SubSlice(path string) ([]*YamlProfile, error) {
+	var value interface{} = p.root()
+	if path != "" {
+		var err error
+		if value, path, err = p.lookup(path); err != nil {
+			return nil, err
+		}
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a list", ErrLevelMismatch, path)
+	}
+
+	profiles := make([]*YamlProfile, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %s[%d] is not a map", ErrLevelMismatch, path, i)
+		}
+		profiles[i] = p.derive(joinPath(joinPath(p.base, path), strconv.Itoa(i)), m)
+	}
+	return profiles, nil
+}
+
+// derive returns a new profile over data, the subtree found at the
+// absolute path base, that shares p's configuration
+func (p *YamlProfile) derive(base string, data map[string]interface{}) *YamlProfile {
+	sc := p.scopeFor(base)
+	p.mu.RLock()
+	child := *p
+	p.mu.RUnlock()
+	child.envPrefix = sc.envPrefix
+	child.baseResolvers = sc.resolvers
+	child.base = base
+	child.data = data
+	child.raw = nil
+	return &child
+}
@@ -0,0 +1,109 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestYamlProfile_SubSlice(t *testing.T) {
+	yamlData := []byte(`
+exporters:
+  - name: otlp
+    endpoint: ${OTLP_ENDPOINT:localhost:4317}
+    options:
+      insecure: true
+  - name: prometheus
+    endpoint: ${PROM_ENDPOINT::9090}
+tags:
+  - a
+  - b
+`)
+
+	os.Setenv("OTLP_ENDPOINT", "collector:4317")
+	defer os.Unsetenv("OTLP_ENDPOINT")
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	exporters, err := p.SubSlice("exporters")
+	if err != nil {
+		t.Fatalf("SubSlice failed: %v", err)
+	}
+	assert(t, len(exporters), 2, "exporters length")
+	assert(t, exporters[0].Get("name"), "otlp", "exporter 0 name")
+	assert(t, exporters[0].Get("endpoint"), "collector:4317", "exporter 0 endpoint")
+	assert(t, exporters[0].Get("options.insecure"), "true", "exporter 0 insecure")
+	assert(t, exporters[1].Get("endpoint"), ":9090", "exporter 1 endpoint")
+
+	var exporter struct {
+		Name     string `yaml:"name"`
+		Endpoint string `yaml:"endpoint"`
+	}
+	if err := exporters[1].UnmarshalTo(&exporter); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, exporter.Name, "prometheus", "exporter 1 decoded name")
+
+	if _, err := p.SubSlice("tags"); !errors.Is(err, ErrLevelMismatch) {
+		t.Errorf("expected ErrLevelMismatch for list of scalars but got %v", err)
+	}
+	if _, err := p.SubSlice("missing"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound but got %v", err)
+	}
+}
+
+func TestYamlProfile_Sub(t *testing.T) {
+	t.Setenv("SUB_DB_HOST", "db.local")
+	t.Setenv("SUB_APP_PORT", "6543")
+
+	yamlData := []byte(`
+database:
+  $envPrefix: SUB_
+  master:
+    host: ${DB_HOST:localhost}
+    port: ${APP_PORT:5432}
+  replicas: [r1, r2]
+name: app
+`)
+	p := NewProfile(WithStrict(true))
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	db := p.Sub("database")
+	if db == nil {
+		t.Fatal("Sub returned nil")
+	}
+	assert(t, db.Get("master.host"), "db.local", "master.host")
+	assert(t, db.Get("replicas.1"), "r2", "replicas.1")
+	if _, err := db.GetError("name"); !errors.Is(err, ErrValueNotFound) {
+		t.Errorf("expected ErrValueNotFound outside the subtree but got %v", err)
+	}
+
+	master := db.Sub("master")
+	var server struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	if err := master.UnmarshalTo(&server); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, server.Host, "db.local", "decoded host")
+	assert(t, server.Port, 6543, "decoded port")
+
+	if p.Sub("name") != nil {
+		t.Error("expected nil for a scalar")
+	}
+	if p.Sub("missing") != nil {
+		t.Error("expected nil for a missing path")
+	}
+
+	var reader ConfigReader = p
+	assert(t, reader.SubReader("database").Get("master.port"), "6543", "SubReader")
+	if reader.SubReader("missing") != nil {
+		t.Error("expected a nil ConfigReader for a missing path")
+	}
+}
@@ -0,0 +1,162 @@
+package dollarYaml
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PathError reports a lookup path that leads to no value. It names the
+// path as requested, the deepest prefix of it that exists and, when the
+// path looks like a typo, the closest existing path. It wraps
+// ErrValueNotFound or ErrLevelMismatch.
+type PathError struct {
+	// Path is the path as requested
+	Path string
+	// Key is the segment of Path that could not be followed
+	Key string
+	// Found is the deepest prefix of Path that exists, empty when not even
+	// the first segment does
+	Found string
+	// Suggestion is the existing path closest to Path, if any is close.
+	// Finding it walks the whole tree, so it is only filled in by the
+	// getters returning errors, such as GetError, and not by helpers such
+	// as Has or GetOrDefault that discard them.
+	Suggestion string
+	// Err describes why Key could not be followed
+	Err error
+}
+
+func (e *PathError) Error() string {
+	msg := e.Err.Error()
+	if e.Path != e.Key {
+		msg += " in " + e.Path
+	}
+	var details []string
+	if e.Found != "" {
+		details = append(details, e.Found+" exists")
+	}
+	if e.Suggestion != "" {
+		details = append(details, "did you mean "+e.Suggestion+"?")
+	}
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, "; ") + ")"
+	}
+	return msg
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// pathError builds the error for key, the segment of path following the
+// segments found, failing with err
+func (p *YamlProfile) pathError(path string, found []string, key string, err error) error {
+	e := &PathError{Path: path, Key: key, Err: err}
+	for _, seg := range found {
+		e.Found = joinKey(e.Found, seg)
+	}
+	switch err {
+	case ErrValueNotFound:
+		e.Err = fmt.Errorf("%w: %s", err, key)
+	case ErrLevelMismatch:
+		e.Err = fmt.Errorf("%w: %s has no key %s", err, e.Found, key)
+		if e.Found == "" {
+			e.Err = fmt.Errorf("%w: the root has no key %s", err, key)
+		}
+	}
+	return e
+}
+
+// suggest fills in the Suggestion of err if it is a PathError for a
+// missing value
+func (p *YamlProfile) suggest(err error) error {
+	var pathErr *PathError
+	if errors.As(err, &pathErr) && errors.Is(pathErr.Err, ErrValueNotFound) && pathErr.Suggestion == "" {
+		pathErr.Suggestion = suggestPath(pathErr.Path, allKeys(p.root()))
+	}
+	return err
+}
+
+// allKeys returns every flattened dot-path of the tree in sorted order,
+// including the paths of intermediate maps. Lists are not descended into.
+func allKeys(data interface{}) []string {
+	var keys []string
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if isDirective(k) {
+				continue
+			}
+			key := joinKey(prefix, k)
+			keys = append(keys, key)
+			if nested, ok := v.(map[string]interface{}); ok {
+				walk(key, nested)
+			}
+		}
+	}
+	if m, ok := data.(map[string]interface{}); ok {
+		walk("", m)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// suggestPath returns the known key closest to path by edit distance,
+// or an empty string if nothing is close enough to be a likely typo
+func suggestPath(path string, keys []string) string {
+	best := ""
+	bestDist := -1
+	maxDist := len(path) / 4
+	if maxDist < 2 {
+		maxDist = 2
+	}
+	for _, key := range keys {
+		// Skip keys whose length alone rules them out
+		diff := len(key) - len(path)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > maxDist {
+			continue
+		}
+		d := levenshtein(strings.ToLower(path), strings.ToLower(key))
+		if d <= maxDist && (bestDist < 0 || d < bestDist) {
+			best, bestDist = key, d
+		}
+	}
+	return best
+}
+
+// levenshtein computes the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
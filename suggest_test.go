@@ -0,0 +1,141 @@
+package dollarYaml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestYamlProfile_PathGuards(t *testing.T) {
+	yamlData := []byte(`
+server:
+  http:
+    port: 8080
+    host: localhost
+`)
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		path        string
+		errType     error
+		wantSuggest string
+	}{
+		{
+			name:        "typo in leaf suggests nearest key",
+			path:        "server.http.prot",
+			errType:     ErrValueNotFound,
+			wantSuggest: "server.http.port",
+		},
+		{
+			name:        "typo in intermediate key suggests nearest key",
+			path:        "sever.http.host",
+			errType:     ErrValueNotFound,
+			wantSuggest: "server.http.host",
+		},
+		{
+			name:    "unrelated key has no suggestion",
+			path:    "database",
+			errType: ErrValueNotFound,
+		},
+		{
+			name:    "path deeper than limit",
+			opts:    []Option{WithMaxPathDepth(2)},
+			path:    "server.http.port",
+			errType: ErrPathTooDeep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(false, tt.opts...)
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+
+			_, err := p.GetError(tt.path)
+			if !errors.Is(err, tt.errType) {
+				t.Fatalf("expected error type %v but got %v", tt.errType, err)
+			}
+			hasSuggestion := strings.Contains(err.Error(), "did you mean")
+			if tt.wantSuggest == "" && hasSuggestion {
+				t.Errorf("unexpected suggestion in %q", err)
+			}
+			if tt.wantSuggest != "" && !strings.Contains(err.Error(), tt.wantSuggest+"?") {
+				t.Errorf("error %q does not suggest %q", err, tt.wantSuggest)
+			}
+		})
+	}
+
+	t.Run("default limit rejects very deep paths", func(t *testing.T) {
+		p := New(false)
+		deep := strings.Repeat("a.", DefaultMaxPathDepth) + "a"
+		if _, err := p.GetError(deep); !errors.Is(err, ErrPathTooDeep) {
+			t.Errorf("expected ErrPathTooDeep but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_PathError(t *testing.T) {
+	p := NewProfile()
+	if err := p.Read([]byte(`
+database:
+  host: db.local
+  port: 5432
+servers: [{name: a}]
+`)); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		wantErr error
+		found   string
+		suggest string
+		msg     string
+	}{
+		{"database.hots", ErrValueNotFound, "database", "database.host",
+			"value not found: hots in database.hots (database exists; did you mean database.host?)"},
+		{"databse", ErrValueNotFound, "", "database",
+			"value not found: databse (did you mean database?)"},
+		{"database.port.number", ErrLevelMismatch, "database.port", "",
+			"level does not match: database.port has no key number in database.port.number (database.port exists)"},
+		{"servers.3.name", ErrValueNotFound, "servers", "",
+			"value not found: index 3 out of range for 1 items in servers.3.name (servers exists)"},
+		{"servers.name", ErrLevelMismatch, "servers", "",
+			"level does not match: servers has no key name in servers.name (servers exists)"},
+	}
+	for _, tt := range tests {
+		_, err := p.GetError(tt.path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("GetError(%q): expected %v but got %v", tt.path, tt.wantErr, err)
+		}
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) {
+			t.Fatalf("GetError(%q) returned %T, want *PathError", tt.path, err)
+		}
+		assert(t, pathErr.Path, tt.path, "path")
+		assert(t, pathErr.Found, tt.found, tt.path+" found")
+		assert(t, pathErr.Suggestion, tt.suggest, tt.path+" suggestion")
+		assert(t, err.Error(), tt.msg, tt.path+" message")
+	}
+}
+
+func TestYamlProfile_PathErrorSuggestsOnlyWhenReturned(t *testing.T) {
+	p := NewProfile()
+	if err := p.Read([]byte("database:\n  host: db.local\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	_, _, err := p.lookup("database.hots")
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) {
+		t.Fatalf("lookup returned %T, want *PathError", err)
+	}
+	assert(t, pathErr.Suggestion, "", "lookup suggestion")
+
+	_, err = p.GetRaw("database.hots")
+	if !errors.As(err, &pathErr) {
+		t.Fatalf("GetRaw returned %T, want *PathError", err)
+	}
+	assert(t, pathErr.Suggestion, "database.host", "GetRaw suggestion")
+}
@@ -0,0 +1,112 @@
+package dollarYaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// secretSchemes are the resolvers whose values count as secrets in Summary
+var secretSchemes = map[string]bool{
+	"file":       true,
+	"vault":      true,
+	"aws-sm":     true,
+	"aws-ssm":    true,
+	"gcp-sm":     true,
+	"azure-kv":   true,
+	"k8s-secret": true,
+}
+
+// SummaryOptions configures Summary
+type SummaryOptions struct {
+	// Name labels the summary, typically the service name
+	Name string
+	// ListOverrides adds the names of the env vars overriding defaults.
+	// Values are never included.
+	ListOverrides bool
+}
+
+// Summary returns a short multi-line description of the loaded config for
+// logging at startup: where it came from, its hash, and how its
+// placeholders resolve. Placeholders are classified without being
+// resolved, so no remote resolver is called and no secret is read.
+// Overrides are env placeholders whose variable is set, defaults those
+// falling back to their default, and unset those with neither.
+func (p *YamlProfile) Summary(opts SummaryOptions) string {
+	var overrides []string
+	var keys, defaults, unset, secrets, resolvers int
+
+	walkLeaves(p.root(), func(interface{}) { keys++ })
+	p.walkPlaceholders(func(path, expr, prefix string) {
+		base, _ := p.splitFilters(expr)
+		name, _, hasDefault := parsePlaceholder(base)
+		if sch, ok := p.scheme(name); ok && (hasDefault || sch.bare) {
+			if secretSchemes[name] {
+				secrets++
+			} else {
+				resolvers++
+			}
+			return
+		}
+		switch val, _ := p.lookupEnv(prefix + name); {
+		case val != "":
+			overrides = append(overrides, prefix+name)
+		case hasDefault:
+			defaults++
+		default:
+			unset++
+		}
+	})
+
+	p.mu.RLock()
+	sources := "inline"
+	if len(p.sources) > 0 {
+		sources = strings.Join(p.sources, ", ")
+	}
+	loaded := "never"
+	if !p.loadedAt.IsZero() {
+		loaded = p.loadedAt.UTC().Format(time.RFC3339)
+	}
+	p.mu.RUnlock()
+
+	var b strings.Builder
+	if opts.Name != "" {
+		fmt.Fprintf(&b, "config %s\n", opts.Name)
+	} else {
+		b.WriteString("config\n")
+	}
+	fmt.Fprintf(&b, "  sources:   %s\n", sources)
+	fmt.Fprintf(&b, "  hash:      %s\n", p.ConfigHash()[:12])
+	fmt.Fprintf(&b, "  loaded:    %s\n", loaded)
+	fmt.Fprintf(&b, "  keys:      %d\n", keys)
+	if opts.ListOverrides && len(overrides) > 0 {
+		sort.Strings(overrides)
+		fmt.Fprintf(&b, "  overrides: %d (%s)\n", len(overrides), strings.Join(overrides, ", "))
+	} else {
+		fmt.Fprintf(&b, "  overrides: %d\n", len(overrides))
+	}
+	fmt.Fprintf(&b, "  defaults:  %d\n", defaults)
+	fmt.Fprintf(&b, "  unset:     %d\n", unset)
+	fmt.Fprintf(&b, "  secrets:   %d\n", secrets)
+	fmt.Fprintf(&b, "  resolvers: %d\n", resolvers)
+	return b.String()
+}
+
+// walkLeaves calls fn for every value in the tree that is not a map or list
+func walkLeaves(v interface{}, fn func(interface{})) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if !isDirective(k) {
+				walkLeaves(item, fn)
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			walkLeaves(item, fn)
+		}
+	default:
+		fn(v)
+	}
+}
@@ -0,0 +1,66 @@
+package dollarYaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYamlProfile_Summary(t *testing.T) {
+	t.Setenv("SUMMARY_PORT", "9090")
+	t.Setenv("WORKER_THREADS", "8")
+
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	data := []byte(`
+server:
+  port: ${SUMMARY_PORT:8080}
+  host: ${SUMMARY_HOST:localhost}
+  name: ${SUMMARY_NAME}
+  id: ${uuid}
+worker:
+  $envPrefix: WORKER_
+  threads: ${THREADS:4|int}
+db:
+  password: ${file:/run/secrets/db}
+  token: ${vault:secret/data/app#token}
+`)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	p := New(false)
+	if err := p.ReadFromPath(path); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+
+	summary := p.Summary(SummaryOptions{Name: "billing", ListOverrides: true})
+	for _, want := range []string{
+		"config billing\n",
+		"sources:   " + path + "\n",
+		"hash:      " + p.ConfigHash()[:12] + "\n",
+		"keys:      7\n",
+		"overrides: 2 (SUMMARY_PORT, WORKER_THREADS)\n",
+		"defaults:  1\n",
+		"unset:     1\n",
+		"secrets:   2\n",
+		"resolvers: 1\n",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+
+	t.Run("inline without names", func(t *testing.T) {
+		p := New(false)
+		if err := p.Read(data); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		summary := p.Summary(SummaryOptions{})
+		if !strings.HasPrefix(summary, "config\n  sources:   inline\n") {
+			t.Errorf("unexpected summary:\n%s", summary)
+		}
+		if !strings.Contains(summary, "overrides: 2\n") || strings.Contains(summary, "9090") {
+			t.Errorf("unexpected overrides line:\n%s", summary)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package dollarYaml
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tag returns the custom YAML tag attached to the node at path, such as
+// "!vault" or a tag expanded from a %TAG directive. Standard tags like !!str
+// are not reported.
+func (p *YamlProfile) Tag(path string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	tag, ok := p.tags[joinPath(p.base, normalizePath(path))]
+	return tag, ok
+}
+
+// Tags returns every custom tag in the document keyed by dot-path
+func (p *YamlProfile) Tags() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	tags := make(map[string]string)
+	for path, tag := range p.tags {
+		if p.base == "" || strings.HasPrefix(path, p.base+".") {
+			tags[strings.TrimPrefix(strings.TrimPrefix(path, p.base), ".")] = tag
+		}
+	}
+	return tags
+}
+
+// TaggedPaths returns the paths carrying the given custom tag in sorted order
+func (p *YamlProfile) TaggedPaths(tag string) []string {
+	var paths []string
+	for path, t := range p.Tags() {
+		if t == tag {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// collectTags records custom tags found under node and strips them so the
+// tree decodes like untagged YAML. Tagged scalars are kept as strings,
+// since their meaning belongs to whichever consumer defined the tag.
+func collectTags(node *yaml.Node, path string, tags map[string]string) {
+	if isCustomTag(node.Tag) {
+		tags[path] = node.Tag
+		if node.Kind == yaml.ScalarNode {
+			node.Tag = "!!str"
+		} else {
+			node.Tag = ""
+		}
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collectTags(child, path, tags)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			collectTags(node.Content[i+1], joinKey(path, node.Content[i].Value), tags)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			collectTags(child, joinPath(path, strconv.Itoa(i)), tags)
+		}
+	}
+}
+
+// isCustomTag reports whether tag is set and outside the YAML core schema
+func isCustomTag(tag string) bool {
+	return tag != "" && !strings.HasPrefix(tag, "!!") && !strings.HasPrefix(tag, "tag:yaml.org,2002:")
+}
@@ -0,0 +1,63 @@
+package dollarYaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_CustomTags(t *testing.T) {
+	yamlData := []byte(`%TAG !app! tag:example.com,2024:app/
+---
+db:
+  password: !vault secret/data/db#password
+  port: !app!port 5432
+  replicas: !app!list
+    - !host replica-1
+    - plain
+  options: !ref {name: default}
+  timeout: !!int 30
+`)
+
+	p := New(false)
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	assert(t, p.Get("db.password"), "secret/data/db#password", "db.password")
+	assert(t, p.Get("db.port"), "5432", "db.port")
+	assert(t, p.Get("db.timeout"), "30", "db.timeout")
+
+	wantTags := map[string]string{
+		"db.password":   "!vault",
+		"db.port":       "tag:example.com,2024:app/port",
+		"db.replicas":   "tag:example.com,2024:app/list",
+		"db.replicas.0": "!host",
+		"db.options":    "!ref",
+	}
+	if got := p.Tags(); !reflect.DeepEqual(got, wantTags) {
+		t.Errorf("Tags = %v, want %v", got, wantTags)
+	}
+	if tag, ok := p.Tag("db.password"); !ok || tag != "!vault" {
+		t.Errorf("Tag(db.password) = %q, %v", tag, ok)
+	}
+	if _, ok := p.Tag("db.timeout"); ok {
+		t.Error("expected standard tags to be ignored")
+	}
+	if got := p.TaggedPaths("!vault"); !reflect.DeepEqual(got, []string{"db.password"}) {
+		t.Errorf("TaggedPaths = %v", got)
+	}
+
+	var config struct {
+		DB struct {
+			Port     string            `yaml:"port"`
+			Replicas []string          `yaml:"replicas"`
+			Options  map[string]string `yaml:"options"`
+		} `yaml:"db"`
+	}
+	if err := p.UnmarshalTo(&config); err != nil {
+		t.Fatalf("UnmarshalTo failed: %v", err)
+	}
+	assert(t, config.DB.Port, "5432", "decoded port")
+	assert(t, config.DB.Replicas[0], "replica-1", "decoded replica")
+	assert(t, config.DB.Options["name"], "default", "decoded options")
+}
@@ -0,0 +1,858 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrTOML = errors.New("invalid TOML")
+
+func init() {
+	RegisterCodec(tomlCodec{}, ".toml", "application/toml")
+}
+
+// ReadTOML loads TOML data. Tables become maps and arrays lists, so paths,
+// placeholders and decoding work as they do for YAML. Offset datetimes,
+// local datetimes and local dates decode to time.Time, in UTC when they
+// have no offset; local times stay strings.
+func (p *YamlProfile) ReadTOML(data []byte) error {
+	return p.ReadAs(data, ".toml")
+}
+
+// tomlCodec is the built-in TOML codec, covering TOML 1.0
+type tomlCodec struct{}
+
+func (tomlCodec) Unmarshal(data []byte, out *map[string]interface{}) error {
+	if !utf8.Valid(data) {
+		return fmt.Errorf("%w: not UTF-8", ErrTOML)
+	}
+	t := &tomlParser{
+		src:     string(data),
+		line:    1,
+		root:    map[string]interface{}{},
+		headers: map[string]bool{},
+		arrays:  map[string]bool{},
+		inline:  map[uintptr]bool{},
+	}
+	t.table = t.root
+	if err := t.parse(); err != nil {
+		return err
+	}
+	*out = t.root
+	return nil
+}
+
+func (tomlCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeTOMLTable(&buf, nil, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tomlParser decodes a TOML document into a tree
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+
+	root  map[string]interface{}
+	table map[string]interface{}
+	// headers holds the tables defined by a [header], and arrays the
+	// arrays defined by [[header]], keyed by tomlKey
+	headers map[string]bool
+	arrays  map[string]bool
+	// inline holds the tables written as inline tables, which are complete
+	// as written and cannot be extended by headers or dotted keys
+	inline map[uintptr]bool
+}
+
+func (t *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrTOML, t.line, fmt.Sprintf(format, args...))
+}
+
+// tomlKey joins the segments of a dotted key for the bookkeeping maps
+func tomlKey(keys []string) string {
+	return strings.Join(keys, "\x00")
+}
+
+func (t *tomlParser) parse() error {
+	for {
+		t.skipSpace(true)
+		if t.pos >= len(t.src) {
+			return nil
+		}
+		var err error
+		switch {
+		case strings.HasPrefix(t.src[t.pos:], "[["):
+			t.pos += 2
+			err = t.parseHeader(true)
+		case t.src[t.pos] == '[':
+			t.pos++
+			err = t.parseHeader(false)
+		default:
+			err = t.parseKeyValue(t.table)
+		}
+		if err != nil {
+			return err
+		}
+		if err := t.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipSpace skips blanks and comments, and line breaks too when newlines
+// is set
+func (t *tomlParser) skipSpace(newlines bool) {
+	for t.pos < len(t.src) {
+		switch c := t.src[t.pos]; {
+		case c == ' ' || c == '\t':
+			t.pos++
+		case c == '#':
+			for t.pos < len(t.src) && t.src[t.pos] != '\n' {
+				t.pos++
+			}
+		case newlines && c == '\n':
+			t.pos++
+			t.line++
+		case newlines && c == '\r' && strings.HasPrefix(t.src[t.pos:], "\r\n"):
+			t.pos += 2
+			t.line++
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine consumes the rest of a line after a statement
+func (t *tomlParser) endOfLine() error {
+	t.skipSpace(false)
+	if t.pos >= len(t.src) {
+		return nil
+	}
+	switch {
+	case t.src[t.pos] == '\n':
+	case strings.HasPrefix(t.src[t.pos:], "\r\n"):
+	default:
+		return t.errorf("unexpected %q after value", t.src[t.pos])
+	}
+	return nil
+}
+
+// parseHeader parses a [table] or [[array]] header after its brackets
+func (t *tomlParser) parseHeader(array bool) error {
+	t.skipSpace(false)
+	keys, err := t.parseKey()
+	if err != nil {
+		return err
+	}
+	t.skipSpace(false)
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(t.src[t.pos:], closing) {
+		return t.errorf("expected %s closing the header", closing)
+	}
+	t.pos += len(closing)
+
+	parent, err := t.descend(t.root, keys[:len(keys)-1], true)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	path := tomlKey(keys)
+	if array {
+		list, exists := parent[last].([]interface{})
+		if _, ok := parent[last]; ok && (!exists || !t.arrays[path]) {
+			return t.errorf("%s is not an array of tables", strings.Join(keys, "."))
+		}
+		table := map[string]interface{}{}
+		parent[last] = append(list, table)
+		t.arrays[path] = true
+		// Headers below the previous item no longer apply
+		for k := range t.headers {
+			if strings.HasPrefix(k, path+"\x00") {
+				delete(t.headers, k)
+			}
+		}
+		t.table = table
+		return nil
+	}
+
+	if t.headers[path] {
+		return t.errorf("table %s defined twice", strings.Join(keys, "."))
+	}
+	switch existing := parent[last].(type) {
+	case nil:
+		if _, ok := parent[last]; ok {
+			return t.errorf("%s is already a value", strings.Join(keys, "."))
+		}
+		table := map[string]interface{}{}
+		parent[last] = table
+		t.table = table
+	case map[string]interface{}:
+		if t.isInline(existing) {
+			return t.errorf("%s is an inline table and cannot be extended", strings.Join(keys, "."))
+		}
+		t.table = existing
+	default:
+		return t.errorf("%s is already a value", strings.Join(keys, "."))
+	}
+	t.headers[path] = true
+	return nil
+}
+
+// descend walks keys from m, creating missing tables. Headers, walking
+// from the root, enter the last item of arrays of tables; dotted keys
+// cannot enter arrays.
+func (t *tomlParser) descend(m map[string]interface{}, keys []string, header bool) (map[string]interface{}, error) {
+	var seen []string
+	for _, k := range keys {
+		seen = append(seen, k)
+		switch next := m[k].(type) {
+		case nil:
+			if _, ok := m[k]; ok {
+				return nil, t.errorf("%s is already a value", strings.Join(seen, "."))
+			}
+			table := map[string]interface{}{}
+			m[k] = table
+			m = table
+		case map[string]interface{}:
+			if t.isInline(next) {
+				return nil, t.errorf("%s is an inline table and cannot be extended", strings.Join(seen, "."))
+			}
+			m = next
+		case []interface{}:
+			if !header || !t.arrays[tomlKey(seen)] || len(next) == 0 {
+				return nil, t.errorf("%s is already a value", strings.Join(seen, "."))
+			}
+			table, ok := next[len(next)-1].(map[string]interface{})
+			if !ok {
+				return nil, t.errorf("%s is already a value", strings.Join(seen, "."))
+			}
+			m = table
+		default:
+			return nil, t.errorf("%s is already a value", strings.Join(seen, "."))
+		}
+	}
+	return m, nil
+}
+
+// parseKeyValue parses key = value into m
+func (t *tomlParser) parseKeyValue(m map[string]interface{}) error {
+	keys, err := t.parseKey()
+	if err != nil {
+		return err
+	}
+	t.skipSpace(false)
+	if t.pos >= len(t.src) || t.src[t.pos] != '=' {
+		return t.errorf("expected = after key %s", strings.Join(keys, "."))
+	}
+	t.pos++
+	t.skipSpace(false)
+	value, err := t.parseValue()
+	if err != nil {
+		return err
+	}
+	parent, err := t.descend(m, keys[:len(keys)-1], false)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := parent[last]; ok {
+		return t.errorf("key %s defined twice", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey parses a bare, quoted or dotted key
+func (t *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		t.skipSpace(false)
+		if t.pos >= len(t.src) {
+			return nil, t.errorf("expected a key")
+		}
+		switch t.src[t.pos] {
+		case '"':
+			k, err := t.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		case '\'':
+			k, err := t.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		default:
+			start := t.pos
+			for t.pos < len(t.src) && isBareKeyChar(t.src[t.pos]) {
+				t.pos++
+			}
+			if start == t.pos {
+				return nil, t.errorf("invalid key character %q", t.src[t.pos])
+			}
+			keys = append(keys, t.src[start:t.pos])
+		}
+		t.skipSpace(false)
+		if t.pos >= len(t.src) || t.src[t.pos] != '.' {
+			return keys, nil
+		}
+		t.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (t *tomlParser) parseValue() (interface{}, error) {
+	if t.pos >= len(t.src) {
+		return nil, t.errorf("expected a value")
+	}
+	rest := t.src[t.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return t.parseMultilineString(`"""`)
+	case strings.HasPrefix(rest, "'''"):
+		return t.parseMultilineString("'''")
+	case rest[0] == '"':
+		return t.parseBasicString()
+	case rest[0] == '\'':
+		return t.parseLiteralString()
+	case rest[0] == '[':
+		return t.parseArray()
+	case rest[0] == '{':
+		return t.parseInlineTable()
+	}
+	return t.parseScalar()
+}
+
+// parseBasicString parses a "string" with escapes
+func (t *tomlParser) parseBasicString() (string, error) {
+	t.pos++
+	var b strings.Builder
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		switch {
+		case c == '"':
+			t.pos++
+			return b.String(), nil
+		case c == '\\':
+			if err := t.parseEscape(&b); err != nil {
+				return "", err
+			}
+		case c == '\n' || c == '\r':
+			return "", t.errorf("line break in a string")
+		default:
+			b.WriteByte(c)
+			t.pos++
+		}
+	}
+	return "", t.errorf("unterminated string")
+}
+
+// parseEscape decodes the escape sequence at t.pos into b
+func (t *tomlParser) parseEscape(b *strings.Builder) error {
+	if t.pos+1 >= len(t.src) {
+		return t.errorf("unterminated escape")
+	}
+	c := t.src[t.pos+1]
+	t.pos += 2
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if t.pos+n > len(t.src) {
+			return t.errorf("short unicode escape")
+		}
+		code, err := strconv.ParseUint(t.src[t.pos:t.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return t.errorf("invalid unicode escape \\%c%s", c, t.src[t.pos:t.pos+n])
+		}
+		b.WriteRune(rune(code))
+		t.pos += n
+	default:
+		return t.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// parseLiteralString parses a 'string' taken as written
+func (t *tomlParser) parseLiteralString() (string, error) {
+	end := strings.IndexAny(t.src[t.pos+1:], "'\n")
+	if end < 0 || t.src[t.pos+1+end] != '\'' {
+		return "", t.errorf("unterminated string")
+	}
+	s := t.src[t.pos+1 : t.pos+1+end]
+	t.pos += end + 2
+	return s, nil
+}
+
+// parseMultilineString parses a """basic""" or ”'literal”' string
+// spanning lines. A line break right after the opening quotes is dropped,
+// and in basic strings a backslash ending a line trims the whitespace
+// that follows it.
+func (t *tomlParser) parseMultilineString(quotes string) (string, error) {
+	t.pos += 3
+	if strings.HasPrefix(t.src[t.pos:], "\r\n") {
+		t.pos += 2
+		t.line++
+	} else if strings.HasPrefix(t.src[t.pos:], "\n") {
+		t.pos++
+		t.line++
+	}
+	literal := quotes == "'''"
+	var b strings.Builder
+	for t.pos < len(t.src) {
+		if strings.HasPrefix(t.src[t.pos:], quotes) {
+			// Up to two quotes may directly precede the closing ones
+			for i := 0; i < 2 && strings.HasPrefix(t.src[t.pos+1:], quotes); i++ {
+				b.WriteByte(quotes[0])
+				t.pos++
+			}
+			t.pos += 3
+			return b.String(), nil
+		}
+		c := t.src[t.pos]
+		switch {
+		case c == '\\' && !literal:
+			j := t.pos + 1
+			for j < len(t.src) && (t.src[j] == ' ' || t.src[j] == '\t') {
+				j++
+			}
+			if j < len(t.src) && (t.src[j] == '\n' || t.src[j] == '\r') {
+				t.pos = j
+				for t.pos < len(t.src) && strings.ContainsRune(" \t\r\n", rune(t.src[t.pos])) {
+					if t.src[t.pos] == '\n' {
+						t.line++
+					}
+					t.pos++
+				}
+				continue
+			}
+			if err := t.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				t.line++
+			}
+			b.WriteByte(c)
+			t.pos++
+		}
+	}
+	return "", t.errorf("unterminated string")
+}
+
+// parseArray parses [values], which may span lines and end with a comma
+func (t *tomlParser) parseArray() (interface{}, error) {
+	t.pos++
+	list := []interface{}{}
+	for {
+		t.skipSpace(true)
+		if t.pos >= len(t.src) {
+			return nil, t.errorf("unterminated array")
+		}
+		if t.src[t.pos] == ']' {
+			t.pos++
+			return list, nil
+		}
+		value, err := t.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		t.skipSpace(true)
+		if t.pos < len(t.src) && t.src[t.pos] == ',' {
+			t.pos++
+		} else if t.pos < len(t.src) && t.src[t.pos] != ']' {
+			return nil, t.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// parseInlineTable parses {key = value, ...} on a single line
+func (t *tomlParser) parseInlineTable() (interface{}, error) {
+	t.pos++
+	table := map[string]interface{}{}
+	t.skipSpace(false)
+	if t.pos < len(t.src) && t.src[t.pos] == '}' {
+		t.pos++
+		t.markInline(table)
+		return table, nil
+	}
+	for {
+		if err := t.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		t.skipSpace(false)
+		if t.pos >= len(t.src) {
+			return nil, t.errorf("unterminated inline table")
+		}
+		switch t.src[t.pos] {
+		case ',':
+			t.pos++
+			t.skipSpace(false)
+		case '}':
+			t.pos++
+			t.markInline(table)
+			return table, nil
+		default:
+			return nil, t.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// markInline records m and the tables nested in it as inline tables
+func (t *tomlParser) markInline(m map[string]interface{}) {
+	t.inline[reflect.ValueOf(m).Pointer()] = true
+	for _, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			t.markInline(nested)
+		}
+	}
+}
+
+// isInline reports whether m was written as an inline table
+func (t *tomlParser) isInline(m map[string]interface{}) bool {
+	return t.inline[reflect.ValueOf(m).Pointer()]
+}
+
+// parseScalar parses a boolean, number or date/time
+func (t *tomlParser) parseScalar() (interface{}, error) {
+	start := t.pos
+	for t.pos < len(t.src) && isScalarChar(t.src[t.pos]) {
+		t.pos++
+	}
+	// A space may separate the date and time of a datetime
+	if t.pos-start == 10 && t.pos+3 < len(t.src) && t.src[t.pos] == ' ' &&
+		isDigit(t.src[t.pos+1]) && isDigit(t.src[t.pos+2]) && t.src[t.pos+3] == ':' {
+		t.pos++
+		for t.pos < len(t.src) && isScalarChar(t.src[t.pos]) {
+			t.pos++
+		}
+	}
+	token := t.src[start:t.pos]
+	switch token {
+	case "":
+		return nil, t.errorf("expected a value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if v, ok := parseTOMLDateTime(token); ok {
+		return v, nil
+	}
+	if v, ok := parseTOMLNumber(token); ok {
+		return v, nil
+	}
+	return nil, t.errorf("invalid value %q", token)
+}
+
+func isScalarChar(c byte) bool {
+	return isBareKeyChar(c) || c == '+' || c == '.' || c == ':'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parseTOMLNumber parses an integer, as int, or a float
+func parseTOMLNumber(token string) (interface{}, bool) {
+	if !validUnderscores(token) {
+		return nil, false
+	}
+	s := strings.ReplaceAll(token, "_", "")
+	if len(s) > 2 && s[0] == '0' && strings.ContainsRune("xob", rune(s[1])) {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[s[1]]
+		if v, err := strconv.ParseInt(s[2:], base, 0); err == nil && s[2] != '+' && s[2] != '-' {
+			return int(v), true
+		}
+		return nil, false
+	}
+	digits := strings.TrimLeft(s, "+-")
+	if digits == "" || !isDigit(digits[0]) || len(s)-len(digits) > 1 {
+		return nil, false
+	}
+	if len(digits) > 1 && digits[0] == '0' && isDigit(digits[1]) {
+		// Leading zeros are not allowed
+		return nil, false
+	}
+	if !strings.ContainsAny(s, ".eE") {
+		v, err := strconv.ParseInt(s, 10, 0)
+		return int(v), err == nil
+	}
+	// A dot must have digits on both sides
+	if i := strings.IndexByte(digits, '.'); i >= 0 && (i == 0 || i+1 >= len(digits) || !isDigit(digits[i-1]) || !isDigit(digits[i+1])) {
+		return nil, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// validUnderscores reports whether every underscore in token sits
+// between two digits
+func validUnderscores(token string) bool {
+	for i := 0; i < len(token); i++ {
+		if token[i] != '_' {
+			continue
+		}
+		if i == 0 || i+1 >= len(token) || !isHexDigit(token[i-1]) || !isHexDigit(token[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// tomlDateLayouts are the datetime forms of TOML, offset ones first
+var tomlDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTOMLDateTime parses a datetime or date as time.Time, and keeps a
+// local time as its text
+func parseTOMLDateTime(token string) (interface{}, bool) {
+	if len(token) < 8 || !isDigit(token[0]) || !isDigit(token[1]) {
+		return nil, false
+	}
+	if token[2] == ':' {
+		if _, err := time.Parse("15:04:05.999999999", token); err == nil {
+			return token, true
+		}
+		return nil, false
+	}
+	upper := strings.ToUpper(token)
+	for _, layout := range tomlDateLayouts {
+		if v, err := time.Parse(layout, upper); err == nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// encodeTOMLTable writes the table m at path: its plain keys first, then
+// its subtables and arrays of tables, each sorted by key. Null values,
+// which TOML cannot express, are left out.
+func encodeTOMLTable(buf *bytes.Buffer, path []string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tables, arrays []string
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			tables = append(tables, k)
+			continue
+		case []interface{}:
+			if isTableArray(v) {
+				arrays = append(arrays, k)
+				continue
+			}
+		}
+		text, err := tomlValue(m[k])
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(append(path, k), "."), err)
+		}
+		buf.WriteString(tomlKeyText(k) + " = " + text + "\n")
+	}
+
+	for _, k := range tables {
+		sub := append(append([]string{}, path...), k)
+		buf.WriteString("\n[" + tomlPathText(sub) + "]\n")
+		if err := encodeTOMLTable(buf, sub, m[k].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	for _, k := range arrays {
+		sub := append(append([]string{}, path...), k)
+		for _, item := range m[k].([]interface{}) {
+			buf.WriteString("\n[[" + tomlPathText(sub) + "]]\n")
+			if err := encodeTOMLTable(buf, sub, item.(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTableArray reports whether list is a non-empty list of maps, written
+// as an array of tables
+func isTableArray(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return len(list) > 0
+}
+
+// tomlValue renders v as an inline TOML value
+func tomlValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", errors.New("TOML has no null value")
+	case string:
+		return tomlString(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float32:
+		return tomlFloat(float64(val)), nil
+	case float64:
+		return tomlFloat(val), nil
+	case time.Time:
+		return val.Format(time.RFC3339Nano), nil
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			text, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			if val[k] != nil {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			text, err := tomlValue(val[k])
+			if err != nil {
+				return "", err
+			}
+			items[i] = tomlKeyText(k) + " = " + text
+		}
+		return "{" + strings.Join(items, ", ") + "}", nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return "", fmt.Errorf("%d overflows a TOML integer", rv.Uint())
+		}
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return tomlString(fmt.Sprint(v)), nil
+}
+
+// tomlString renders s as a basic string
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlFloat renders f with the decimal point or exponent TOML requires
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEn") {
+		s += ".0"
+	}
+	return s
+}
+
+// tomlKeyText renders k bare when it can be, quoted otherwise
+func tomlKeyText(k string) string {
+	for i := 0; i < len(k); i++ {
+		if !isBareKeyChar(k[i]) {
+			return tomlString(k)
+		}
+	}
+	return k
+}
+
+func tomlPathText(keys []string) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = tomlKeyText(k)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,234 @@
+package dollarYaml
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_ReadTOML(t *testing.T) {
+	t.Setenv("TOML_DB_HOST", "db.local")
+
+	data := []byte(`
+# service config
+title = "app"
+owner.name = 'Tom'
+"quoted key" = 1
+
+[database]
+host = "${TOML_DB_HOST:localhost}"
+ports = [ 8000,
+  8001, # trailing comma
+]
+limits = { cpu = 2, memory = 4_096 }
+enabled = true
+ratio = 0.5
+big = 0xFF_FF
+started = 1979-05-27T07:32:00-08:00
+day = 1979-05-27
+at = 07:32:00
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]
+
+[[products]]
+name = "Nail"
+[[products.colors]]
+name = "gray"
+
+[text]
+basic = "tab\there \u00e9"
+lines = """
+first \
+  second"""
+raw = '''C:\dir\'''
+`)
+	p := NewProfile()
+	if err := p.ReadTOML(data); err != nil {
+		t.Fatalf("ReadTOML failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"title", "app"},
+		{"owner.name", "Tom"},
+		{"[quoted key]", "1"},
+		{"database.host", "db.local"},
+		{"database.ports.1", "8001"},
+		{"database.limits.memory", "4096"},
+		{"database.enabled", "true"},
+		{"database.ratio", "0.5"},
+		{"database.big", "65535"},
+		{"database.at", "07:32:00"},
+		{"servers.alpha.ip", "10.0.0.1"},
+		{"products.0.sku", "738594937"},
+		{"products.2.name", "Nail"},
+		{"products.2.colors.0.name", "gray"},
+		{"text.basic", "tab\there é"},
+		{"text.lines", "first second"},
+		{"text.raw", `C:\dir\`},
+	}
+	for _, tt := range tests {
+		got, err := p.GetError(tt.path)
+		if err != nil {
+			t.Fatalf("GetError(%q) failed: %v", tt.path, err)
+		}
+		assert(t, got, tt.want, tt.path)
+	}
+	n, _ := p.Len("products")
+	assert(t, n, 3, "products")
+
+	started, err := p.GetTime("database.started")
+	if err != nil {
+		t.Fatalf("GetTime failed: %v", err)
+	}
+	assert(t, started.Equal(time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC)), true, "offset datetime")
+	day, err := p.GetTime("database.day")
+	if err != nil {
+		t.Fatalf("GetTime failed: %v", err)
+	}
+	assert(t, day.Equal(time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC)), true, "local date")
+
+	path := filepath.Join(t.TempDir(), "app.toml")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	fromFile := NewProfile()
+	if err := fromFile.ReadFromPath(path); err != nil {
+		t.Fatalf("ReadFromPath failed: %v", err)
+	}
+	assert(t, fromFile.Get("database.host"), "db.local", "host from .toml file")
+}
+
+func TestTOMLCodec_Values(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want interface{}
+	}{
+		{"v = +17", 17},
+		{"v = -0x0", nil},
+		{"v = 0o755", 493},
+		{"v = 0b1101", 13},
+		{"v = 1e06", 1e6},
+		{"v = -2E-2", -0.02},
+		{"v = 6.626e-34", 6.626e-34},
+		{"v = 1_000.5", 1000.5},
+		{"v = -inf", math.Inf(-1)},
+		{"v = 1979-05-27 07:32:00Z", time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)},
+		{"v = 1979-05-27T00:32:00.999999", time.Date(1979, 5, 27, 0, 32, 0, 999999000, time.UTC)},
+		{`v = "\"quoted\" \\ \U0001F600"`, "\"quoted\" \\ \U0001F600"},
+		{`v = """two "" quotes"""""`, `two "" quotes""`},
+		{"v = []", []interface{}{}},
+		{"v = [[1, 2], ['a']]", []interface{}{[]interface{}{1, 2}, []interface{}{"a"}}},
+		{"v = {}", map[string]interface{}{}},
+		{"v = { a.b = 1 }", map[string]interface{}{"a": map[string]interface{}{"b": 1}}},
+	}
+	for _, tt := range tests {
+		var out map[string]interface{}
+		err := tomlCodec{}.Unmarshal([]byte(tt.doc), &out)
+		if tt.want == nil {
+			if !errors.Is(err, ErrTOML) {
+				t.Errorf("%s: expected ErrTOML but got %v", tt.doc, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.doc, err)
+			continue
+		}
+		if got := out["v"]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestTOMLCodec_Errors(t *testing.T) {
+	docs := []string{
+		"a = 1\na = 2",
+		"[t]\n[t]",
+		"a = 1\n[a]",
+		"a = [1]\n[[a]]",
+		"a = 1 b = 2",
+		"a = 'unterminated",
+		`a = "bad \q escape"`,
+		"a = 01",
+		"a = 1__0",
+		"a = _1",
+		"a = .5",
+		"a = infinity",
+		"a = {b = 1,}",
+		"a = {x = 1}\n[a]\ny = 2",
+		"a = {b = {x = 1}}\n[a.b]\ny = 2",
+		"a = {}\n[a.b]",
+		"a = {x = 1}\na.y = 2",
+		"a = {b.c = 1}\n[a.b]",
+		"[a",
+		"= 1",
+	}
+	for _, doc := range docs {
+		var out map[string]interface{}
+		if err := (tomlCodec{}).Unmarshal([]byte(doc), &out); !errors.Is(err, ErrTOML) {
+			t.Errorf("%q: expected ErrTOML but got %v", doc, err)
+		}
+	}
+}
+
+func TestTOMLCodec_Marshal(t *testing.T) {
+	tree := map[string]interface{}{
+		"title": "app \"x\"\n",
+		"ratio": 2.0,
+		"none":  nil,
+		"database": map[string]interface{}{
+			"port":   5432,
+			"tags":   []interface{}{"a", 1, map[string]interface{}{"k": true}},
+			"my key": "v",
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "b", "meta": map[string]interface{}{"zone": 1}},
+		},
+	}
+	got, err := tomlCodec{}.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `ratio = 2.0
+title = "app \"x\"\n"
+
+[database]
+"my key" = "v"
+port = 5432
+tags = ["a", 1, {k = true}]
+
+[[servers]]
+name = "a"
+
+[[servers]]
+name = "b"
+
+[servers.meta]
+zone = 1
+`
+	assert(t, string(got), want, "Marshal")
+
+	var back map[string]interface{}
+	if err := (tomlCodec{}).Unmarshal(got, &back); err != nil {
+		t.Fatalf("failed to read marshaled TOML: %v", err)
+	}
+	delete(tree, "none")
+	if !reflect.DeepEqual(back, tree) {
+		t.Errorf("round trip = %#v, want %#v", back, tree)
+	}
+}
@@ -0,0 +1,162 @@
+package dollarYaml
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"strings"
+	"time"
+)
+
+// URLOption configures ReadFromURL
+type URLOption func(*urlConfig)
+
+type urlConfig struct {
+	timeout   time.Duration
+	header    http.Header
+	tlsConfig *tls.Config
+	client    *http.Client
+}
+
+// URLTimeout bounds the whole request, body included; defaults to
+// DefaultSourceTimeout
+func URLTimeout(timeout time.Duration) URLOption {
+	return func(c *urlConfig) {
+		c.timeout = timeout
+	}
+}
+
+// URLHeader adds a request header, such as an Authorization token
+func URLHeader(key, value string) URLOption {
+	return func(c *urlConfig) {
+		if c.header == nil {
+			c.header = http.Header{}
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// URLTLSConfig sets the TLS configuration, for private CAs or client
+// certificates. It is ignored when URLClient is set.
+func URLTLSConfig(cfg *tls.Config) URLOption {
+	return func(c *urlConfig) {
+		c.tlsConfig = cfg
+	}
+}
+
+// URLClient overrides the HTTP client used for the request
+func URLClient(client *http.Client) URLOption {
+	return func(c *urlConfig) {
+		c.client = client
+	}
+}
+
+// remoteState holds the validators of the config last loaded with
+// ReadFromURL, sent back to make the next request for it conditional
+type remoteState struct {
+	url          string
+	etag         string
+	lastModified string
+}
+
+// ReadFromURL fetches the config at an http or https URL and loads it.
+// The codec is chosen by the Content-Type of the response, then by the
+// extension of the URL path, falling back to YAML. The Last-Modified
+// time of the response becomes the SourceTime.
+//
+// Reading the same URL again sends the ETag and Last-Modified of the
+// previous response as If-None-Match and If-Modified-Since; when the
+// server answers 304 Not Modified the loaded config is kept, without
+// notifying OnChange hooks, and only LoadedAt is updated.
+func (p *YamlProfile) ReadFromURL(ctx context.Context, rawURL string, opts ...URLOption) error {
+	cfg := urlConfig{timeout: DefaultSourceTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parsing config URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported config URL scheme %q", u.Scheme)
+	}
+	name := u.Redacted()
+
+	client := cfg.client
+	if client == nil {
+		client = http.DefaultClient
+		if cfg.tlsConfig != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = cfg.tlsConfig
+			client = &http.Client{Transport: transport}
+		}
+	}
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", name, err)
+	}
+	for key, values := range cfg.header {
+		req.Header[key] = values
+	}
+	p.mu.RLock()
+	previous := p.remote
+	p.mu.RUnlock()
+	conditional := previous.url == u.String()
+	if conditional {
+		if previous.etag != "" {
+			req.Header.Set("If-None-Match", previous.etag)
+		}
+		if previous.lastModified != "" {
+			req.Header.Set("If-Modified-Since", previous.lastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		p.mu.Lock()
+		p.loadedAt = time.Now()
+		p.mu.Unlock()
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("fetching %s: %s: %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", name, err)
+	}
+
+	format := pathpkg.Ext(u.Path)
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		if _, ok := CodecFor(mediaType); ok {
+			format = mediaType
+		}
+	}
+	remote := remoteState{
+		url:          u.String(),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	modified, _ := http.ParseTime(remote.lastModified)
+	return p.readFile(data, format, func() {
+		p.sources = []string{name}
+		p.remote = remote
+		p.sourceTime = modified
+	})
+}
@@ -0,0 +1,127 @@
+package dollarYaml
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestYamlProfile_ReadFromURL(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var requests, notModified int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app.yaml", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write([]byte("database:\n  port: 5432\n"))
+	})
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"database": {"port": 6543}}`))
+	})
+	mux.HandleFunc("/app.toml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("[database]\nport = 7654\n"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	ctx := context.Background()
+
+	t.Run("conditional", func(t *testing.T) {
+		p := NewProfile()
+		auth := URLHeader("Authorization", "Bearer secret")
+		if err := p.ReadFromURL(ctx, server.URL+"/app.yaml", auth); err != nil {
+			t.Fatalf("ReadFromURL failed: %v", err)
+		}
+		assert(t, p.Get("database.port"), "5432", "port")
+		assert(t, p.SourceTime().Equal(modified), true, "SourceTime")
+		assert(t, p.sources[0], server.URL+"/app.yaml", "source")
+
+		var changes int
+		p.OnChange(func(ChangeEvent) { changes++ })
+		loaded := p.LoadedAt()
+		time.Sleep(time.Millisecond)
+		if err := p.ReadFromURL(ctx, server.URL+"/app.yaml", auth); err != nil {
+			t.Fatalf("conditional ReadFromURL failed: %v", err)
+		}
+		assert(t, atomic.LoadInt32(&notModified), int32(1), "304 responses")
+		assert(t, p.Get("database.port"), "5432", "port after 304")
+		assert(t, changes, 0, "OnChange calls")
+		assert(t, p.LoadedAt().After(loaded), true, "LoadedAt updated")
+
+		// Other loads forget the validators
+		if err := p.Read([]byte("other: true")); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if err := p.ReadFromURL(ctx, server.URL+"/app.yaml", auth); err != nil {
+			t.Fatalf("ReadFromURL failed: %v", err)
+		}
+		assert(t, atomic.LoadInt32(&notModified), int32(1), "304 responses")
+		assert(t, p.Get("database.port"), "5432", "port after reload")
+
+		err := NewProfile().ReadFromURL(ctx, server.URL+"/app.yaml")
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("expected a 401 error but got %v", err)
+		}
+	})
+
+	t.Run("format", func(t *testing.T) {
+		p := NewProfile()
+		if err := p.ReadFromURL(ctx, server.URL+"/config"); err != nil {
+			t.Fatalf("ReadFromURL failed: %v", err)
+		}
+		assert(t, p.Get("database.port"), "6543", "content type")
+		if err := p.ReadFromURL(ctx, server.URL+"/app.toml"); err != nil {
+			t.Fatalf("ReadFromURL failed: %v", err)
+		}
+		assert(t, p.Get("database.port"), "7654", "extension")
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		tlsServer := httptest.NewTLSServer(mux)
+		defer tlsServer.Close()
+		if err := NewProfile().ReadFromURL(ctx, tlsServer.URL+"/config"); err == nil {
+			t.Error("expected an untrusted certificate to fail")
+		}
+		pool := x509.NewCertPool()
+		pool.AddCert(tlsServer.Certificate())
+		p := NewProfile()
+		if err := p.ReadFromURL(ctx, tlsServer.URL+"/config", URLTLSConfig(&tls.Config{RootCAs: pool})); err != nil {
+			t.Fatalf("ReadFromURL failed: %v", err)
+		}
+		assert(t, p.Get("database.port"), "6543", "port over TLS")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer slow.Close()
+		if err := NewProfile().ReadFromURL(ctx, slow.URL, URLTimeout(20*time.Millisecond)); err == nil {
+			t.Error("expected the request to time out")
+		}
+	})
+
+	if err := NewProfile().ReadFromURL(ctx, "file:///etc/app.yaml"); err == nil {
+		t.Error("expected an unsupported scheme to fail")
+	}
+}
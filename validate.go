@@ -0,0 +1,122 @@
+package dollarYaml
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Validator is implemented by config structs that check their own values;
+// ValidateDir calls Validate after each file decodes successfully
+type Validator interface {
+	Validate() error
+}
+
+// ValidateOptions configures ValidateDir
+type ValidateOptions struct {
+	// Options are applied to the profile created for every file, e.g.
+	// WithStrict(true) to fail on placeholders that cannot be resolved
+	Options []Option
+	// Env returns the environment to resolve path against in place of the
+	// process environment; a nil map keeps the process environment
+	Env func(path string) map[string]string
+}
+
+// FileResult is the outcome of validating a single file
+type FileResult struct {
+	Path string
+	Err  error
+}
+
+// ValidationReport lists the result for every file checked by ValidateDir
+type ValidationReport struct {
+	Files []FileResult
+}
+
+// OK reports whether every file passed
+func (r *ValidationReport) OK() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the results of the files that did not pass
+func (r *ValidationReport) Failed() []FileResult {
+	var failed []FileResult
+	for _, f := range r.Files {
+		if f.Err != nil {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// String formats the report with one line per file
+func (r *ValidationReport) String() string {
+	var b strings.Builder
+	for _, f := range r.Files {
+		if f.Err != nil {
+			fmt.Fprintf(&b, "FAIL %s: %v\n", f.Path, f.Err)
+		} else {
+			fmt.Fprintf(&b, "ok   %s\n", f.Path)
+		}
+	}
+	fmt.Fprintf(&b, "%d files, %d failed\n", len(r.Files), len(r.Failed()))
+	return b.String()
+}
+
+// ValidateDir loads every .yaml and .yml file under dir and decodes each
+// into a fresh value of target's type, calling Validate when the type
+// implements Validator. target is only used as a prototype and is left
+// untouched. Per-file failures are collected in the report; the returned
+// error is reserved for problems walking dir itself.
+func ValidateDir(dir string, target interface{}, opts ValidateOptions) (*ValidationReport, error) {
+	typ := reflect.TypeOf(target)
+	if typ == nil {
+		return nil, fmt.Errorf("ValidateDir: nil target")
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	report := &ValidationReport{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			report.Files = append(report.Files, FileResult{Path: path, Err: validateFile(path, typ, opts)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// validateFile loads path and decodes it into a new value of typ
+func validateFile(path string, typ reflect.Type, opts ValidateOptions) error {
+	p := NewProfile(opts.Options...)
+	if opts.Env != nil {
+		p.env = opts.Env(path)
+	}
+	if err := p.ReadFromPath(path); err != nil {
+		return err
+	}
+	value := reflect.New(typ)
+	if err := p.UnmarshalTo(value.Interface()); err != nil {
+		return err
+	}
+	if v, ok := value.Interface().(Validator); ok {
+		return v.Validate()
+	}
+	if v, ok := value.Elem().Interface().(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
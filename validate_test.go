@@ -0,0 +1,85 @@
+package dollarYaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type validatedConfig struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+func (c *validatedConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+func TestValidateDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml":          "name: a\nport: 80\n",
+		"b.yml":           "name: b\nport: 0\n",
+		"nested/c.yaml":   "name: c\nport: ${C_PORT}\n",
+		"nested/d.yaml":   "name: [unclosed\n",
+		"nested/skip.txt": "not yaml",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	report, err := ValidateDir(dir, validatedConfig{}, ValidateOptions{
+		Options: []Option{WithStrict(true)},
+		Env: func(path string) map[string]string {
+			if strings.HasSuffix(path, "c.yaml") {
+				return map[string]string{"C_PORT": "9000"}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("ValidateDir failed: %v", err)
+	}
+
+	if len(report.Files) != 4 {
+		t.Fatalf("expected 4 files, got %d:\n%s", len(report.Files), report)
+	}
+	failed := map[string]bool{}
+	for _, f := range report.Failed() {
+		failed[filepath.Base(f.Path)] = true
+	}
+	if !failed["b.yml"] || !failed["d.yaml"] || len(failed) != 2 {
+		t.Errorf("unexpected failures:\n%s", report)
+	}
+	if report.OK() {
+		t.Error("expected report not to be OK")
+	}
+	if !strings.Contains(report.String(), "4 files, 2 failed") {
+		t.Errorf("unexpected summary:\n%s", report)
+	}
+}
+
+func TestValidateDir_Unresolved(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("port: ${VALIDATE_UNSET_PORT}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	report, err := ValidateDir(dir, &validatedConfig{}, ValidateOptions{Options: []Option{WithStrict(true)}})
+	if err != nil {
+		t.Fatalf("ValidateDir failed: %v", err)
+	}
+	if failed := report.Failed(); len(failed) != 1 || !errors.Is(failed[0].Err, ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved, got:\n%s", report)
+	}
+}
@@ -0,0 +1,247 @@
+package dollarYaml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultConfig configures the ${vault:path#field} resolver. Exactly one
+// authentication method is used, in order of preference: Token, AppRole
+// (RoleID and SecretID) or Kubernetes (KubernetesRole).
+type VaultConfig struct {
+	// Address of the Vault server; defaults to VAULT_ADDR
+	Address string
+	// Token authenticates directly; defaults to VAULT_TOKEN
+	Token string
+	// Namespace is sent as X-Vault-Namespace; defaults to VAULT_NAMESPACE
+	Namespace string
+
+	// RoleID and SecretID log in through the AppRole auth method
+	RoleID       string
+	SecretID     string
+	AppRoleMount string
+
+	// KubernetesRole logs in through the Kubernetes auth method with the
+	// service account token read from KubernetesTokenPath
+	KubernetesRole      string
+	KubernetesTokenPath string
+	KubernetesMount     string
+
+	// Timeout bounds each request to Vault; defaults to 10 seconds
+	Timeout time.Duration
+	// CacheTTL is how long a fetched secret is reused; defaults to 5
+	// minutes, and a negative value disables caching
+	CacheTTL time.Duration
+	// HTTPClient overrides the client used to reach Vault
+	HTTPClient *http.Client
+}
+
+// vaultResolver fetches secrets from Vault KV v1 or v2 mounts
+type vaultResolver struct {
+	cfg VaultConfig
+
+	cache *ttlCache
+
+	// mu guards token and is held while logging in
+	mu    sync.Mutex
+	token string
+}
+
+func newVaultResolver(cfg VaultConfig) *vaultResolver {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" && cfg.RoleID == "" && cfg.KubernetesRole == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if cfg.AppRoleMount == "" {
+		cfg.AppRoleMount = "approle"
+	}
+	if cfg.KubernetesMount == "" {
+		cfg.KubernetesMount = "kubernetes"
+	}
+	if cfg.KubernetesTokenPath == "" {
+		cfg.KubernetesTokenPath = defaultKubernetesTokenPath
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &vaultResolver{
+		cfg:   cfg,
+		token: cfg.Token,
+		cache: newTTLCache(cfg.CacheTTL),
+	}
+}
+
+// resolve returns one field of a secret from a key of the form path#field
+func (v *vaultResolver) resolve(ctx context.Context, key string) (string, error) {
+	path, field, ok := strings.Cut(key, "#")
+	if !ok || field == "" {
+		return "", fmt.Errorf("vault key %q must be path#field", key)
+	}
+
+	path = strings.Trim(path, "/")
+	cached, err := v.cache.get(ctx, path, func() (interface{}, error) {
+		return v.secret(ctx, path)
+	})
+	if err != nil {
+		return "", err
+	}
+	data := cached.(map[string]interface{})
+	value, ok := data[field]
+	if !ok || value == nil {
+		return "", fmt.Errorf("%w: field %s in %s", ErrValueNotFound, field, path)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// secret fetches the key/value data stored at path
+func (v *vaultResolver) secret(ctx context.Context, path string) (map[string]interface{}, error) {
+	token, err := v.authToken(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	status, err := v.do(ctx, http.MethodGet, "/v1/"+path, token, nil, &body)
+	if status == http.StatusForbidden && v.canLogin() {
+		// The token has likely expired, so log in again once
+		if token, err = v.authToken(ctx, token); err != nil {
+			return nil, err
+		}
+		status, err = v.do(ctx, http.MethodGet, "/v1/"+path, token, nil, &body)
+	}
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: vault secret %s", ErrValueNotFound, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := body.Data
+	// KV v2 nests the secret under data.data next to its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	return data, nil
+}
+
+// authToken returns the client token, logging in when none is held or the
+// one held is expired, the token a request was just refused with. Only
+// the login runs under v.mu, so lookups of different secrets run
+// concurrently, and a token another lookup already renewed is reused.
+func (v *vaultResolver) authToken(ctx context.Context, expired string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token != "" && v.token != expired {
+		return v.token, nil
+	}
+	v.token = ""
+	if err := v.login(ctx); err != nil {
+		return "", err
+	}
+	return v.token, nil
+}
+
+// canLogin reports whether tokens are obtained through AppRole or
+// Kubernetes auth rather than configured directly
+func (v *vaultResolver) canLogin() bool {
+	return v.cfg.Token == "" && (v.cfg.RoleID != "" || v.cfg.KubernetesRole != "")
+}
+
+// login obtains a client token through AppRole or Kubernetes auth
+func (v *vaultResolver) login(ctx context.Context) error {
+	var mount string
+	var payload map[string]string
+	switch {
+	case v.cfg.RoleID != "":
+		mount = v.cfg.AppRoleMount
+		payload = map[string]string{"role_id": v.cfg.RoleID, "secret_id": v.cfg.SecretID}
+	case v.cfg.KubernetesRole != "":
+		jwt, err := os.ReadFile(v.cfg.KubernetesTokenPath)
+		if err != nil {
+			return fmt.Errorf("reading service account token: %w", err)
+		}
+		mount = v.cfg.KubernetesMount
+		payload = map[string]string{"role": v.cfg.KubernetesRole, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return fmt.Errorf("vault: no token, AppRole or Kubernetes role configured")
+	}
+
+	var body struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if _, err := v.do(ctx, http.MethodPost, "/v1/auth/"+mount+"/login", "", payload, &body); err != nil {
+		return fmt.Errorf("vault login: %w", err)
+	}
+	if body.Auth.ClientToken == "" {
+		return fmt.Errorf("vault login: no client token in response")
+	}
+	v.token = body.Auth.ClientToken
+	return nil
+}
+
+// do sends a request to Vault, authenticated with token if not empty, and
+// decodes the JSON response into out
+func (v *vaultResolver) do(ctx context.Context, method, path, token string, payload, out interface{}) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.cfg.Timeout)
+	defer cancel()
+
+	var reqBody bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&reqBody).Encode(payload); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(v.cfg.Address, "/")+path, &reqBody)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("vault %s %s: %s", method, path, resp.Status)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
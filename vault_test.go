@@ -0,0 +1,210 @@
+package dollarYaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newVaultServer fakes a Vault server with a KV v2 mount at secret/, a KV
+// v1 mount at kv/ and the AppRole and Kubernetes auth methods
+func newVaultServer(t *testing.T, reads *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login", "/v1/auth/kubernetes/login":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["secret_id"] != "sid" && body["jwt"] != "sa-jwt" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": "issued"},
+			})
+			return
+		}
+
+		if token := r.Header.Get("X-Vault-Token"); token != "root" && token != "issued" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		atomic.AddInt32(reads, 1)
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     map[string]interface{}{"password": "v2-pass", "port": 5432},
+					"metadata": map[string]interface{}{"version": 3},
+				},
+			})
+		case "/v1/kv/app":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"password": "v1-pass"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestYamlProfile_VaultScheme(t *testing.T) {
+	var reads int32
+	server := newVaultServer(t, &reads)
+	defer server.Close()
+
+	jwtPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtPath, []byte("sa-jwt\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	yamlData := []byte(`
+db:
+  password: ${vault:secret/data/app#password}
+  port: ${vault:secret/data/app#port}
+  legacy: ${vault:kv/app#password}
+  user: ${vault:secret/data/app#user:admin}
+  missing: ${vault:secret/data/other#password:fallback}
+`)
+
+	auths := map[string]VaultConfig{
+		"token":      {Address: server.URL, Token: "root"},
+		"approle":    {Address: server.URL, RoleID: "rid", SecretID: "sid"},
+		"kubernetes": {Address: server.URL, KubernetesRole: "app", KubernetesTokenPath: jwtPath},
+	}
+	for name, cfg := range auths {
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&reads, 0)
+			p := New(false, WithVault(cfg))
+			if err := p.Read(yamlData); err != nil {
+				t.Fatalf("failed to read yaml data: %v", err)
+			}
+
+			assert(t, p.Get("db.password"), "v2-pass", "db.password")
+			assert(t, p.Get("db.port"), "5432", "db.port")
+			assert(t, p.Get("db.legacy"), "v1-pass", "db.legacy")
+			assert(t, p.Get("db.user"), "admin", "db.user")
+			assert(t, p.Get("db.missing"), "fallback", "db.missing")
+
+			// secret/data/app is fetched once and then served from cache
+			assert(t, atomic.LoadInt32(&reads), int32(3), "vault reads")
+		})
+	}
+
+	t.Run("failed login", func(t *testing.T) {
+		p := New(false, WithVault(VaultConfig{Address: server.URL, RoleID: "rid", SecretID: "wrong"}))
+		if err := p.Read(yamlData); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		if _, err := p.GetError("db.password"); err == nil {
+			t.Error("expected login error")
+		}
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		p := New(false)
+		if err := p.Read(yamlData); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		if _, err := p.GetError("db.password"); !errors.Is(err, ErrResolverNotConfigured) {
+			t.Errorf("expected ErrResolverNotConfigured but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_VaultTokenExpiry(t *testing.T) {
+	// Each login issues a new token, and expiring one makes Vault reject
+	// it with 403 as it does once a token's TTL runs out
+	var logins, current int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/approle/login" {
+			n := atomic.AddInt32(&logins, 1)
+			atomic.StoreInt32(&current, n)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": fmt.Sprintf("token-%d", n)},
+			})
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != fmt.Sprintf("token-%d", atomic.LoadInt32(&current)) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"password": "v1-pass"},
+		})
+	}))
+	defer server.Close()
+
+	p := New(false, WithVault(VaultConfig{Address: server.URL, RoleID: "rid", SecretID: "sid", CacheTTL: -1}))
+	if err := p.Read([]byte("password: ${vault:kv/app#password}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	assert(t, p.Get("password"), "v1-pass", "password")
+
+	atomic.StoreInt32(&current, 0)
+	value, err := p.GetError("password")
+	if err != nil {
+		t.Fatalf("expected a new login after the token expired: %v", err)
+	}
+	assert(t, value, "v1-pass", "password after expiry")
+	assert(t, atomic.LoadInt32(&logins), int32(2), "logins")
+
+	t.Run("token configured", func(t *testing.T) {
+		atomic.StoreInt32(&logins, 0)
+		p := New(false, WithVault(VaultConfig{Address: server.URL, Token: "fixed", CacheTTL: -1}))
+		if err := p.Read([]byte("password: ${vault:kv/app#password}\n")); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		if _, err := p.GetError("password"); err == nil {
+			t.Error("expected a rejected token to fail")
+		}
+		assert(t, atomic.LoadInt32(&logins), int32(0), "logins with a configured token")
+	})
+}
+
+func TestYamlProfile_VaultConcurrentLookups(t *testing.T) {
+	// Reading kv/a blocks until kv/b is requested, so the lookups only
+	// finish if they reach Vault at the same time
+	aRequested, bRequested := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/kv/a":
+			close(aRequested)
+			select {
+			case <-bRequested:
+			case <-time.After(5 * time.Second):
+				w.WriteHeader(http.StatusGatewayTimeout)
+				return
+			}
+		case "/v1/kv/b":
+			close(bRequested)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"password": "pass"},
+		})
+	}))
+	defer server.Close()
+
+	p := New(false, WithVault(VaultConfig{Address: server.URL, Token: "root"}))
+	if err := p.Read([]byte("a: ${vault:kv/a#password}\nb: ${vault:kv/b#password}\n")); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.GetError("a")
+		done <- err
+	}()
+	<-aRequested
+	if _, err := p.GetError("b"); err != nil {
+		t.Errorf("b: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("a: %v", err)
+	}
+}
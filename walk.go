@@ -0,0 +1,73 @@
+package dollarYaml
+
+import (
+	"context"
+	"sort"
+	"strconv"
+)
+
+// WalkFunc is called by Walk for each leaf with its path, usable with Get,
+// and its resolved value
+type WalkFunc func(path string, value interface{}) error
+
+// Walk calls fn for every leaf of the effective config, resolved as by
+// UnmarshalTo into a map[string]interface{}, in the order of AllKeys.
+// Empty maps and lists count as leaves. Walk fails without calling fn if
+// the config does not resolve, and stops at the first error fn returns.
+func (p *YamlProfile) Walk(fn WalkFunc) error {
+	tree, err := p.resolvedTree(context.Background())
+	if err != nil {
+		return err
+	}
+	return walkTree("", tree, fn)
+}
+
+func walkTree(path string, v interface{}, fn WalkFunc) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 && path != "" {
+			return fn(path, val)
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := walkTree(joinKey(path, k), val[k], fn); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if len(val) == 0 && path != "" {
+			return fn(path, val)
+		}
+		for i, item := range val {
+			if err := walkTree(joinPath(path, strconv.Itoa(i)), item, fn); err != nil {
+				return err
+			}
+		}
+	default:
+		return fn(path, val)
+	}
+	return nil
+}
+
+// Flatten returns the leaves of the effective config keyed by their path
+// and rendered as text, e.g. {"database.master.port": "5432"}, for stores
+// that only hold flat key/value pairs. Values read as Get returns them,
+// except that nulls are empty strings; empty maps and lists are left out.
+// Flatten fails if the config does not resolve.
+func (p *YamlProfile) Flatten() (map[string]string, error) {
+	flat := map[string]string{}
+	err := p.Walk(func(path string, value interface{}) error {
+		if isScalar(value) {
+			flat[path] = scalarText(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
@@ -0,0 +1,122 @@
+package dollarYaml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestYamlProfile_Walk(t *testing.T) {
+	t.Setenv("APP_WALK_PORT", "9090")
+	t.Setenv("WALK_REGION", "eu")
+
+	yamlData := []byte(`
+server:
+  port: ${WALK_PORT:8080}
+  host: ${WALK_HOST:localhost}
+  $envPrefix: APP_
+regions:
+  ${WALK_REGION:us}: {endpoint: e}
+tags: [a, "${WALK_TAG:b}"]
+empty: {}
+name: ~
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	var paths []string
+	values := map[string]interface{}{}
+	err := p.Walk(func(path string, value interface{}) error {
+		paths = append(paths, path)
+		values[path] = value
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if !reflect.DeepEqual(paths, p.AllKeys()) {
+		t.Errorf("walked %v, want the AllKeys order %v", paths, p.AllKeys())
+	}
+	assert(t, values["server.port"], 9090, "server.port")
+	assert(t, values["server.host"], "localhost", "server.host")
+	assert(t, values["regions.eu.endpoint"], "e", "regions.eu.endpoint")
+	assert(t, values["tags.1"], "b", "tags.1")
+	assert(t, values["name"], nil, "name")
+	if !reflect.DeepEqual(values["empty"], map[string]interface{}{}) {
+		t.Errorf("empty = %#v", values["empty"])
+	}
+
+	t.Run("stops at errors", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := p.Walk(func(path string, value interface{}) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) {
+			t.Errorf("expected the callback error but got %v", err)
+		}
+		assert(t, calls, 1, "calls after an error")
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		p := NewProfile(WithStrict(true))
+		if err := p.Read([]byte("a: ${WALK_UNSET}")); err != nil {
+			t.Fatalf("failed to read yaml data: %v", err)
+		}
+		err := p.Walk(func(string, interface{}) error {
+			t.Error("callback called for an unresolved config")
+			return nil
+		})
+		if !errors.Is(err, ErrUnresolved) {
+			t.Errorf("expected ErrUnresolved but got %v", err)
+		}
+	})
+}
+
+func TestYamlProfile_Flatten(t *testing.T) {
+	t.Setenv("WALK_PORT", "5433")
+
+	yamlData := []byte(`
+database:
+  master:
+    port: ${WALK_PORT:5432}
+    host: db.local
+  replicas: [r1, r2]
+  annotations:
+    example.com/team: core
+ratio: 0.5
+debug: true
+name: ~
+empty: {}
+`)
+	p := NewProfile()
+	if err := p.Read(yamlData); err != nil {
+		t.Fatalf("failed to read yaml data: %v", err)
+	}
+
+	got, err := p.Flatten()
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	want := map[string]string{
+		"database.master.port":                    "5433",
+		"database.master.host":                    "db.local",
+		"database.replicas.0":                     "r1",
+		"database.replicas.1":                     "r2",
+		"database.annotations.[example.com/team]": "core",
+		"ratio": "0.5",
+		"debug": "true",
+		"name":  "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Flatten() = %v, want %v", got, want)
+	}
+	for path, value := range got {
+		if path != "name" {
+			assert(t, p.Get(path), value, path)
+		}
+	}
+}